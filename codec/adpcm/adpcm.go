@@ -20,6 +20,7 @@ package adpcm
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -36,6 +37,14 @@ const (
 	compFact      = 4 // In general ADPCM compresses by a factor of 4.
 )
 
+// Errors returned by Decoder.Write for malformed ADPCM.
+var (
+	ErrShortChunk = errors.New("ADPCM chunk extends past end of data")
+	ErrChunkLen   = errors.New("invalid ADPCM chunk length")
+	ErrStepIndex  = errors.New("ADPCM step index out of range")
+	ErrPadFlag    = errors.New("invalid ADPCM padding flag")
+)
+
 // Table of index changes (see spec).
 var indexTable = []int16{
 	-1, -1, -1, -1, 2, 4, 6, 8,
@@ -282,15 +291,25 @@ func (d *Decoder) decodeSample(nibble byte) int16 {
 // Write takes a slice of bytes of arbitrary length representing adpcm and decodes it into pcm.
 // It writes its output to the Decoder's dst.
 // The number of bytes written out is returned along with any error that occured.
+// If b ends part way through a chunk, or a chunk header is malformed, the chunks
+// before it are decoded and an error is returned.
 func (d *Decoder) Write(b []byte) (int, error) {
 	// Iterate over each chunk and decode it.
 	var n int
 	var chunkLen int
-	for off := 0; off+headSize <= len(b); off += chunkLen {
+	for off := 0; off < len(b); off += chunkLen {
+		if off+headSize > len(b) {
+			return n, ErrShortChunk
+		}
+
 		// Read length of chunk and check if whole chunk exists.
 		chunkLen = int(binary.LittleEndian.Uint32(b[off : off+chunkLenSize]))
-		if off+chunkLen > len(b) {
-			break
+		err := checkHeader(b[off : off+headSize])
+		if err != nil {
+			return n, err
+		}
+		if chunkLen > len(b)-off {
+			return n, ErrShortChunk
 		}
 
 		// Initialize Decoder with header of b.
@@ -341,6 +360,23 @@ func (d *Decoder) Write(b []byte) (int, error) {
 	return n, nil
 }
 
+// checkHeader checks that the ADPCM chunk header h holds a valid chunk length,
+// step index and padding flag.
+func checkHeader(h []byte) error {
+	chunkLen := binary.LittleEndian.Uint32(h[0:chunkLenSize])
+	pad := h[chunkLenSize+byteDepth+1]
+	switch {
+	case h[chunkLenSize+byteDepth] >= byte(len(stepTable)):
+		return fmt.Errorf("%w: %d", ErrStepIndex, h[chunkLenSize+byteDepth])
+	case pad > 1:
+		return fmt.Errorf("%w: %d", ErrPadFlag, pad)
+	case chunkLen < headSize+uint32(pad):
+		return fmt.Errorf("%w: %d", ErrChunkLen, chunkLen)
+	default:
+		return nil
+	}
+}
+
 // capAdd16 adds two int16s together and caps at max/min int16 instead of overflowing
 func capAdd16(a, b int16) int16 {
 	c := int32(a) + int32(b)
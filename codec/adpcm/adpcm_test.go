@@ -22,6 +22,8 @@ package adpcm
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"io/ioutil"
 	"testing"
 )
@@ -81,3 +83,54 @@ func TestDecodeBlock(t *testing.T) {
 		t.Error("PCM generated does not match expected PCM")
 	}
 }
+
+// TestDecodeMalformed checks that the decoder returns errors, rather than
+// panicking, when given truncated or malformed ADPCM.
+func TestDecodeMalformed(t *testing.T) {
+	// Encode a short ramp to get a valid chunk, with and without padding.
+	pcm := make([]byte, 64)
+	for i := 0; i < len(pcm)/byteDepth; i++ {
+		binary.LittleEndian.PutUint16(pcm[i*byteDepth:], uint16(i*100))
+	}
+	var even, odd bytes.Buffer
+	_, err := NewEncoder(&even).Write(pcm)
+	if err != nil {
+		t.Fatalf("did not expect error encoding: %v", err)
+	}
+	_, err = NewEncoder(&odd).Write(pcm[:len(pcm)-byteDepth])
+	if err != nil {
+		t.Fatalf("did not expect error encoding: %v", err)
+	}
+	valid := even.Bytes()
+
+	// modify returns a copy of valid with the byte at i set to v.
+	modify := func(i int, v byte) []byte {
+		b := append([]byte(nil), valid...)
+		b[i] = v
+		return b
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{name: "valid", in: valid},
+		{name: "valid padded", in: odd.Bytes()},
+		{name: "valid two chunks", in: append(append([]byte(nil), valid...), valid...)},
+		{name: "short header", in: valid[:headSize-1], want: ErrShortChunk},
+		{name: "truncated chunk", in: valid[:len(valid)-1], want: ErrShortChunk},
+		{name: "trailing bytes", in: append(append([]byte(nil), valid...), valid[:3]...), want: ErrShortChunk},
+		{name: "zero chunk length", in: modify(0, 0), want: ErrChunkLen},
+		{name: "large chunk length", in: modify(3, 0xff), want: ErrShortChunk},
+		{name: "step index", in: modify(chunkLenSize+byteDepth, byte(len(stepTable))), want: ErrStepIndex},
+		{name: "padding flag", in: modify(chunkLenSize+byteDepth+1, 2), want: ErrPadFlag},
+		{name: "padded header only", in: []byte{headSize, 0, 0, 0, 0, 0, 0, 1}, want: ErrChunkLen},
+	}
+	for _, test := range tests {
+		_, err := NewDecoder(&bytes.Buffer{}).Write(test.in)
+		if !errors.Is(err, test.want) {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, test.want)
+		}
+	}
+}
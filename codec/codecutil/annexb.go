@@ -0,0 +1,57 @@
+/*
+NAME
+  annexb.go
+
+DESCRIPTION
+  annexb.go provides splitting of H.264 and H.265 Annex-B byte streams into
+  NAL units.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package codecutil
+
+// SplitNALUnits splits the Annex-B byte stream b into NAL units, delimited by
+// 3 or 4 byte start codes. The returned NAL units do not include start codes
+// or trailing zero bytes, and share the underlying array of b. Any bytes
+// before the first start code are ignored. The framing is common to H.264
+// and H.265.
+func SplitNALUnits(b []byte) [][]byte {
+	var (
+		nalus [][]byte
+		start = -1
+	)
+	for i := 0; i+2 < len(b); i++ {
+		if b[i] != 0x00 || b[i+1] != 0x00 || b[i+2] != 0x01 {
+			continue
+		}
+		if start >= 0 {
+			nalus = appendNAL(nalus, b[start:i])
+		}
+		i += 2
+		start = i + 1
+	}
+	if start >= 0 && start < len(b) {
+		nalus = appendNAL(nalus, b[start:])
+	}
+	return nalus
+}
+
+// appendNAL appends n to nalus, first removing trailing zero bytes, which
+// belong either to the following 4 byte start code or are trailing_zero_8bits.
+// Empty NAL units are discarded.
+func appendNAL(nalus [][]byte, n []byte) [][]byte {
+	for len(n) > 0 && n[len(n)-1] == 0x00 {
+		n = n[:len(n)-1]
+	}
+	if len(n) == 0 {
+		return nalus
+	}
+	return append(nalus, n)
+}
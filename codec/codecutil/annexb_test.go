@@ -0,0 +1,57 @@
+/*
+NAME
+  annexb_test.go
+
+DESCRIPTION
+  annexb_test.go provides testing for the Annex-B splitting in annexb.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package codecutil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitNALUnits(t *testing.T) {
+	want := [][]byte{
+		{0x67, 0x42, 0xc0, 0x1e},
+		{0x68, 0xce, 0x3c, 0x80},
+		{0x65, 0x88, 0x00, 0x00, 0x03, 0x01},
+		{0x41, 0x9a},
+	}
+
+	// Use both 3 and 4 byte start codes, with leading garbage and
+	// trailing_zero_8bits.
+	stream := []byte{0xff, 0xfe}
+	for i, n := range want {
+		if i%2 == 0 {
+			stream = append(stream, 0x00)
+		}
+		stream = append(stream, 0x00, 0x00, 0x01)
+		stream = append(stream, n...)
+	}
+	stream = append(stream, 0x00, 0x00)
+
+	got := SplitNALUnits(stream)
+	if len(got) != len(want) {
+		t.Fatalf("did not get expected number of NAL units.\nGot: %d\nWant: %d\n", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("did not get expected NAL unit %d.\nGot: %#v\nWant: %#v\n", i, got[i], want[i])
+		}
+	}
+
+	if got := SplitNALUnits([]byte{0x01, 0x02, 0x03}); got != nil {
+		t.Errorf("did not expect NAL units from data with no start code, got: %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+NAME
+  rbsp.go
+
+DESCRIPTION
+  rbsp.go provides conversion between the encapsulated byte sequence payload
+  (EBSP) of an H.264 or H.265 NAL unit and its raw byte sequence payload
+  (RBSP), i.e. the removal and insertion of emulation prevention bytes.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package codecutil
+
+// emulationPreventionByte is the emulation_prevention_three_byte inserted
+// after two zero bytes to prevent start code emulation.
+const emulationPreventionByte = 0x03
+
+// EBSPToRBSP returns the RBSP of the NAL unit payload ebsp by removing each
+// emulation_prevention_three_byte, i.e. each 0x03 following two zero bytes,
+// as described by section 7.4.1 of ITU-T H.264 and H.265. ebsp is not
+// modified.
+func EBSPToRBSP(ebsp []byte) []byte {
+	rbsp := make([]byte, 0, len(ebsp))
+	var zeros int
+	for _, b := range ebsp {
+		if zeros >= 2 && b == emulationPreventionByte {
+			zeros = 0
+			continue
+		}
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
+
+// RBSPToEBSP returns the EBSP of the RBSP rbsp by inserting an
+// emulation_prevention_three_byte wherever two zero bytes are followed by a
+// byte less than or equal to 0x03, and after a trailing cabac_zero_word, as
+// described by section 7.4.1 of ITU-T H.264 and H.265. rbsp is not modified.
+func RBSPToEBSP(rbsp []byte) []byte {
+	ebsp := make([]byte, 0, len(rbsp)+len(rbsp)/2)
+	var zeros int
+	for _, b := range rbsp {
+		if zeros == 2 && b <= emulationPreventionByte {
+			ebsp = append(ebsp, emulationPreventionByte)
+			zeros = 0
+		}
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		ebsp = append(ebsp, b)
+	}
+	if zeros == 2 {
+		ebsp = append(ebsp, emulationPreventionByte)
+	}
+	return ebsp
+}
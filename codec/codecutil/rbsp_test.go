@@ -0,0 +1,87 @@
+/*
+NAME
+  rbsp_test.go
+
+DESCRIPTION
+  rbsp_test.go provides testing for the EBSP/RBSP conversion in rbsp.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package codecutil
+
+import (
+	"bytes"
+	"testing"
+)
+
+var rbspTests = []struct {
+	name string
+	rbsp []byte
+	ebsp []byte
+}{
+	{
+		name: "no emulation",
+		rbsp: []byte{0x67, 0x42, 0x00, 0x1e, 0x8d},
+		ebsp: []byte{0x67, 0x42, 0x00, 0x1e, 0x8d},
+	},
+	{
+		name: "all low bytes",
+		rbsp: []byte{0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x01, 0x05, 0x00, 0x00, 0x03, 0x80},
+		ebsp: []byte{0x01, 0x00, 0x00, 0x03, 0x00, 0x02, 0x00, 0x00, 0x03, 0x01, 0x05, 0x00, 0x00, 0x03, 0x03, 0x80},
+	},
+	{
+		name: "no emulation for high byte",
+		rbsp: []byte{0x00, 0x00, 0x04, 0x00, 0x00, 0xff},
+		ebsp: []byte{0x00, 0x00, 0x04, 0x00, 0x00, 0xff},
+	},
+	{
+		name: "emulation at start",
+		rbsp: []byte{0x00, 0x00, 0x01, 0x80},
+		ebsp: []byte{0x00, 0x00, 0x03, 0x01, 0x80},
+	},
+	{
+		name: "consecutive zeros",
+		rbsp: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80},
+		ebsp: []byte{0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00, 0x00, 0x80},
+	},
+	{
+		name: "trailing cabac_zero_word",
+		rbsp: []byte{0x65, 0x88, 0x80, 0x00, 0x00},
+		ebsp: []byte{0x65, 0x88, 0x80, 0x00, 0x00, 0x03},
+	},
+	{
+		name: "empty",
+		rbsp: []byte{},
+		ebsp: []byte{},
+	},
+}
+
+func TestEBSPToRBSP(t *testing.T) {
+	for _, test := range rbspTests {
+		got := EBSPToRBSP(test.ebsp)
+		if !bytes.Equal(got, test.rbsp) {
+			t.Errorf("did not get expected result for test %q.\nGot: %#v\nWant: %#v\n", test.name, got, test.rbsp)
+		}
+	}
+}
+
+func TestRBSPToEBSP(t *testing.T) {
+	for _, test := range rbspTests {
+		got := RBSPToEBSP(test.rbsp)
+		if !bytes.Equal(got, test.ebsp) {
+			t.Errorf("did not get expected result for test %q.\nGot: %#v\nWant: %#v\n", test.name, got, test.ebsp)
+		}
+
+		// The EBSP must not contain a start code prefix.
+		if len(got) >= 3 && bytes.Contains(got, []byte{0x00, 0x00, 0x01}) {
+			t.Errorf("EBSP for test %q contains start code prefix: %#v", test.name, got)
+		}
+	}
+}
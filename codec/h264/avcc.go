@@ -0,0 +1,82 @@
+/*
+NAME
+  avcc.go
+
+DESCRIPTION
+  avcc.go provides functionality for converting AVC decoder configuration
+  records (avcC) to Annex-B byte stream format.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h264
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ausocean/av/codec/h264/h264dec"
+)
+
+// avccHeaderSize is the size of the fixed fields of an avcC record before
+// the SPS count.
+const avccHeaderSize = 5
+
+var errInvalidAVCC = errors.New("invalid avcC record")
+
+// AVCCExtradataToAnnexB returns the SPS and PPS NAL units of the
+// AVCDecoderConfigurationRecord b, as held by an MP4 avcC box or an RTMP/FLV
+// AVC sequence header, in Annex-B byte stream format with 4 byte start codes.
+// The SPS precede the PPS, as in b. Any trailing extension data for high
+// profiles is ignored.
+func AVCCExtradataToAnnexB(b []byte) ([]byte, error) {
+	if len(b) < avccHeaderSize+1 {
+		return nil, fmt.Errorf("%w: too short", errInvalidAVCC)
+	}
+	if b[0] != 1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", errInvalidAVCC, b[0])
+	}
+
+	var (
+		res []byte
+		off = avccHeaderSize
+	)
+	for _, p := range []struct {
+		mask byte
+		typ  byte
+	}{
+		{mask: 0x1f, typ: h264dec.NALTypeSPS},
+		{mask: 0xff, typ: h264dec.NALTypePPS},
+	} {
+		if off >= len(b) {
+			return nil, fmt.Errorf("%w: missing parameter set count", errInvalidAVCC)
+		}
+		n := int(b[off] & p.mask)
+		off++
+		for i := 0; i < n; i++ {
+			if len(b)-off < 2 {
+				return nil, fmt.Errorf("%w: truncated parameter set length", errInvalidAVCC)
+			}
+			l := int(binary.BigEndian.Uint16(b[off:]))
+			off += 2
+			if l == 0 || len(b)-off < l {
+				return nil, fmt.Errorf("%w: invalid parameter set length %d", errInvalidAVCC, l)
+			}
+			nal := b[off : off+l]
+			off += l
+			if nal[0]&0x1f != p.typ {
+				return nil, fmt.Errorf("%w: NAL unit type %d, want %d", errInvalidAVCC, nal[0]&0x1f, p.typ)
+			}
+			res = append(res, 0x00, 0x00, 0x00, 0x01)
+			res = append(res, nal...)
+		}
+	}
+	return res, nil
+}
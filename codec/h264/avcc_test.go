@@ -0,0 +1,97 @@
+/*
+NAME
+  avcc_test.go
+
+DESCRIPTION
+  avcc_test.go provides testing for functionality in avcc.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h264
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ausocean/av/codec/codecutil"
+	"github.com/ausocean/av/codec/h264/h264dec"
+)
+
+// avccBlob is an avcC record for baseline profile 320x240 video at 25 fps,
+// with 4 byte NAL unit lengths. The SPS includes an emulation prevention byte.
+var avccBlob = []byte{
+	0x01,       // configurationVersion.
+	0x42,       // AVCProfileIndication.
+	0xc0,       // profile_compatibility.
+	0x1e,       // AVCLevelIndication.
+	0xff,       // lengthSizeMinusOne = 3.
+	0xe1,       // numOfSequenceParameterSets = 1.
+	0x00, 0x12, // sequenceParameterSetLength.
+	0x67, 0x42, 0xc0, 0x1e, 0xda, 0x05, 0x07, 0xe8, 0x40, 0x00,
+	0x00, 0x03, 0x00, 0x40, 0x00, 0x00, 0x0c, 0xa1,
+	0x01,       // numOfPictureParameterSets.
+	0x00, 0x04, // pictureParameterSetLength.
+	0x68, 0xce, 0x3c, 0x80,
+}
+
+func TestAVCCExtradataToAnnexB(t *testing.T) {
+	got, err := AVCCExtradataToAnnexB(avccBlob)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	want := []byte{
+		0x00, 0x00, 0x00, 0x01,
+		0x67, 0x42, 0xc0, 0x1e, 0xda, 0x05, 0x07, 0xe8, 0x40, 0x00,
+		0x00, 0x03, 0x00, 0x40, 0x00, 0x00, 0x0c, 0xa1,
+		0x00, 0x00, 0x00, 0x01,
+		0x68, 0xce, 0x3c, 0x80,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("did not get expected Annex-B.\nGot: %x\nWant: %x\n", got, want)
+	}
+
+	// Check that the SPS survives intact by parsing it.
+	sps, err := h264dec.NewSPS(codecutil.EBSPToRBSP(got[5:22]), false)
+	if err != nil {
+		t.Fatalf("did not expect error parsing SPS: %v", err)
+	}
+	if sps.PicWidthInMBSMinus1 != 19 || sps.PicHeightInMapUnitsMinus1 != 14 {
+		t.Errorf("did not get expected SPS dimensions: %+v", sps)
+	}
+}
+
+func TestAVCCExtradataToAnnexBErrors(t *testing.T) {
+	// modify returns a copy of avccBlob with the byte at i set to v.
+	modify := func(i int, v byte) []byte {
+		b := append([]byte(nil), avccBlob...)
+		b[i] = v
+		return b
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{name: "empty", in: nil},
+		{name: "version", in: modify(0, 2)},
+		{name: "truncated SPS", in: avccBlob[:20]},
+		{name: "missing PPS count", in: avccBlob[:26]},
+		{name: "truncated PPS", in: avccBlob[:len(avccBlob)-1]},
+		{name: "SPS type", in: modify(8, 0x68)},
+		{name: "zero length", in: modify(7, 0x00)},
+	}
+	for _, test := range tests {
+		_, err := AVCCExtradataToAnnexB(test.in)
+		if !errors.Is(err, errInvalidAVCC) {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, errInvalidAVCC)
+		}
+	}
+}
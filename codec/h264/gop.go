@@ -0,0 +1,96 @@
+/*
+NAME
+  gop.go
+
+DESCRIPTION
+  gop.go provides functionality for analysing the GOP structure of an H.264
+  byte stream, i.e. the keyframe interval and the distribution of slice types.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h264
+
+import (
+	"fmt"
+
+	"github.com/ausocean/av/codec/codecutil"
+	"github.com/ausocean/av/codec/h264/h264dec"
+)
+
+// SliceStats holds the number and total size of slices of one type.
+type SliceStats struct {
+	Count int // Number of slices.
+	Bytes int // Total size of the slice NAL units, excluding start codes.
+}
+
+// AvgBytes returns the average size of the slices in bytes, or 0 if there
+// are none.
+func (s SliceStats) AvgBytes() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Bytes) / float64(s.Count)
+}
+
+// GOPStats holds statistics of the GOP structure of an H.264 stream.
+type GOPStats struct {
+	Pictures    int        // Number of coded pictures.
+	Keyframes   int        // Number of IDR pictures.
+	KeyInterval float64    // Mean number of pictures between IDR pictures, or 0 if fewer than two.
+	I, P, B     SliceStats // Slice statistics by type. SI and SP slices are counted as I and P.
+}
+
+// AnalyzeGOP returns statistics of the GOP structure of the H.264 byte stream
+// b. A new picture is taken to begin at each slice with first_mb_in_slice of
+// zero.
+func AnalyzeGOP(b []byte) (*GOPStats, error) {
+	var (
+		s       GOPStats
+		lastKey = -1 // Index of the last IDR picture.
+		gaps    int  // Sum of the intervals between IDR pictures.
+	)
+	for i, n := range codecutil.SplitNALUnits(b) {
+		typ := int(n[0] & 0x1f)
+		if typ != h264dec.NALTypeNonIDR && typ != h264dec.NALTypeIDR {
+			continue
+		}
+		firstMB, sliceType, err := h264dec.ParseSliceStart(n)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse slice in NAL unit %d: %w", i, err)
+		}
+
+		if firstMB == 0 {
+			s.Pictures++
+			if typ == h264dec.NALTypeIDR {
+				if lastKey != -1 {
+					gaps += s.Pictures - 1 - lastKey
+				}
+				lastKey = s.Pictures - 1
+				s.Keyframes++
+			}
+		}
+
+		var st *SliceStats
+		switch sliceType {
+		case "I", "SI":
+			st = &s.I
+		case "P", "SP":
+			st = &s.P
+		case "B":
+			st = &s.B
+		}
+		st.Count++
+		st.Bytes += len(n)
+	}
+	if s.Keyframes > 1 {
+		s.KeyInterval = float64(gaps) / float64(s.Keyframes-1)
+	}
+	return &s, nil
+}
@@ -0,0 +1,116 @@
+/*
+NAME
+  gop_test.go
+
+DESCRIPTION
+  gop_test.go provides testing for functionality in gop.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h264
+
+import (
+	"bytes"
+	"math/bits"
+	"strings"
+	"testing"
+)
+
+// Slice types as coded in slice_type, for all slices of the picture.
+const (
+	testSliceP = 5
+	testSliceB = 6
+	testSliceI = 7
+)
+
+// testSlice returns a slice NAL unit, with start code, of the given NAL
+// header, first_mb_in_slice and slice_type, padded to size bytes.
+func testSlice(hdr byte, firstMB, sliceType, size int) []byte {
+	// Exp-Golomb code the two fields, then add a stop bit.
+	var sb strings.Builder
+	for _, v := range []int{firstMB, sliceType} {
+		x := uint(v + 1)
+		sb.WriteString(strings.Repeat("0", bits.Len(x)-1))
+		for i := bits.Len(x) - 1; i >= 0; i-- {
+			sb.WriteByte('0' + byte(x>>uint(i)&1))
+		}
+	}
+	sb.WriteByte('1')
+	s := sb.String()
+
+	n := []byte{0x00, 0x00, 0x00, 0x01, hdr}
+	for i := 0; i < len(s); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			b <<= 1
+			if i+j < len(s) && s[i+j] == '1' {
+				b |= 1
+			}
+		}
+		n = append(n, b)
+	}
+	return append(n, bytes.Repeat([]byte{0xaa}, size-len(n)+4)...)
+}
+
+func TestAnalyzeGOP(t *testing.T) {
+	const (
+		nGOPs = 3
+		iSize = 1000
+		pSize = 300
+		bSize = 100
+	)
+	var (
+		aud = []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0}
+		sps = []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0xc0, 0x1e}
+	)
+
+	// Each GOP is, in decode order, I P B B P B B, with the IDR picture coded
+	// as two slices.
+	var stream []byte
+	for g := 0; g < nGOPs; g++ {
+		stream = append(stream, aud...)
+		stream = append(stream, sps...)
+		stream = append(stream, testSlice(0x65, 0, testSliceI, iSize)...)
+		stream = append(stream, testSlice(0x65, 40, testSliceI, iSize)...)
+		for _, st := range []int{testSliceP, testSliceB, testSliceB, testSliceP, testSliceB, testSliceB} {
+			stream = append(stream, aud...)
+			switch st {
+			case testSliceP:
+				stream = append(stream, testSlice(0x41, 0, st, pSize)...)
+			case testSliceB:
+				stream = append(stream, testSlice(0x01, 0, st, bSize)...)
+			}
+		}
+	}
+
+	got, err := AnalyzeGOP(stream)
+	if err != nil {
+		t.Fatalf("did not expect error analysing stream: %v", err)
+	}
+	want := &GOPStats{
+		Pictures:    7 * nGOPs,
+		Keyframes:   nGOPs,
+		KeyInterval: 7,
+		I:           SliceStats{Count: 2 * nGOPs, Bytes: 2 * nGOPs * iSize},
+		P:           SliceStats{Count: 2 * nGOPs, Bytes: 2 * nGOPs * pSize},
+		B:           SliceStats{Count: 4 * nGOPs, Bytes: 4 * nGOPs * bSize},
+	}
+	if *got != *want {
+		t.Errorf("did not get expected stats.\nGot: %+v\nWant: %+v\n", got, want)
+	}
+	if got.B.AvgBytes() != bSize {
+		t.Errorf("did not get expected average B slice size.\nGot: %v\nWant: %v\n", got.B.AvgBytes(), bSize)
+	}
+
+	_, err = AnalyzeGOP([]byte{0x00, 0x00, 0x01, 0x65, 0x00})
+	if err == nil {
+		t.Errorf("expected error for truncated slice")
+	}
+}
@@ -120,6 +120,15 @@ func (br *BitReader) PeekBits(n int) (uint64, error) {
 	return r, nil
 }
 
+// PeekBytes returns the next n bytes of the source that have not been
+// partially read, without advancing through the source. If the reader is byte
+// aligned these begin at the current position, otherwise they follow the
+// current byte. If fewer than n bytes are available, those available are
+// returned along with an error.
+func (br *BitReader) PeekBytes(n int) ([]byte, error) {
+	return br.r.Peek(n)
+}
+
 // ByteAligned returns true if the reader position is at the start of a byte,
 // and false otherwise.
 func (br *BitReader) ByteAligned() bool {
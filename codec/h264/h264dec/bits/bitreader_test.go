@@ -302,3 +302,43 @@ func TestByteAligned(t *testing.T) {
 		}
 	}
 }
+
+func TestPeekBytes(t *testing.T) {
+	in := []byte{0xa5, 0x3c, 0x0f, 0xf0}
+	tests := []struct {
+		read int    // Bits read before peeking.
+		n    int    // Bytes to peek.
+		want []byte // Expected peeked bytes.
+		err  bool   // Whether an error is expected.
+		next uint64 // The 4 bits following those read.
+	}{
+		{read: 0, n: 2, want: []byte{0xa5, 0x3c}, next: 0xa},
+		{read: 3, n: 2, want: []byte{0x3c, 0x0f}, next: 0x2},
+		{read: 8, n: 3, want: []byte{0x3c, 0x0f, 0xf0}, next: 0x3},
+		{read: 12, n: 4, want: []byte{0x0f, 0xf0}, err: true, next: 0xc},
+	}
+
+	for i, test := range tests {
+		br := NewBitReader(bytes.NewReader(in))
+		_, err := br.ReadBits(test.read)
+		if err != nil {
+			t.Fatalf("did not expect error reading bits for test %d: %v", i, err)
+		}
+		got, err := br.PeekBytes(test.n)
+		if (err != nil) != test.err {
+			t.Errorf("did not get expected error for test %d: %v", i, err)
+		}
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("did not get expected bytes for test %d.\nGot: %v\nWant: %v\n", i, got, test.want)
+		}
+
+		// Peeking should not advance the reader.
+		b, err := br.ReadBits(4)
+		if err != nil {
+			t.Fatalf("did not expect error reading bits after peek for test %d: %v", i, err)
+		}
+		if b != test.next {
+			t.Errorf("reader advanced by peek for test %d.\nGot: %#x\nWant: %#x\n", i, b, test.next)
+		}
+	}
+}
@@ -83,6 +83,21 @@ var (
 	}
 )
 
+// NALTypeOf returns the nal_unit_type of the NAL unit n, which must not
+// include a start code, or -1 if n is empty.
+func NALTypeOf(n []byte) int {
+	if len(n) == 0 {
+		return -1
+	}
+	return int(n[0] & 0x1f)
+}
+
+// IsVCL returns true if t is the type of a VCL NAL unit, i.e. a coded slice
+// or slice data partition of a primary picture.
+func IsVCL(t int) bool {
+	return t >= NALTypeNonIDR && t <= NALTypeIDR
+}
+
 func rbspBytes(frame []byte) []byte {
 	if len(frame) > 8 {
 		return frame[8:]
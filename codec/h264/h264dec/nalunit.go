@@ -11,10 +11,10 @@ package h264dec
 
 import (
 	"fmt"
-	"io"
 
 	"github.com/pkg/errors"
 
+	"github.com/ausocean/av/codec/codecutil"
 	"github.com/ausocean/av/codec/h264/h264dec/bits"
 )
 
@@ -259,28 +259,15 @@ func NewNALUnit(br *bits.BitReader) (*NALUnit, error) {
 		}
 	}
 
+	// Gather the remaining payload bytes, and then remove any emulation
+	// prevention bytes to give the RBSP.
+	var ebsp []byte
 	for moreRBSPData(br) {
-		next3Bytes, err := br.PeekBits(24)
-
-		// If PeekBits cannot get 3 bytes, but there still might be 2 bytes left in
-		// the source, we will get an io.ErrUnexpectedEOF; we wish to ignore this
-		// and continue. The call to moreRBSPData will determine when we have
-		// reached the end of the NAL unit.
-		if err != nil && errors.Cause(err) != io.ErrUnexpectedEOF {
-			return nil, errors.Wrap(err, "could not Peek next 3 bytes")
-		}
-
-		if next3Bytes == 0x000003 {
-			for j := 0; j < 2; j++ {
-				rbspByte := byte(r.readBits(8))
-				n.RBSP = append(n.RBSP, byte(rbspByte))
-			}
-
-			// Read Emulation prevention three byte.
-			n.EmulationPreventionThreeByte = byte(r.readBits(8))
-		} else {
-			n.RBSP = append(n.RBSP, byte(r.readBits(8)))
-		}
+		ebsp = append(ebsp, byte(r.readBits(8)))
+	}
+	n.RBSP = codecutil.EBSPToRBSP(ebsp)
+	if len(n.RBSP) != len(ebsp) {
+		n.EmulationPreventionThreeByte = 0x03
 	}
 
 	if r.err() != nil {
@@ -218,6 +218,39 @@ func TestNewNALUnit(t *testing.T) {
 				},
 			},
 		},
+		{
+			in: "0" + // f(1) forbidden_zero_bit = 0
+				"11" + // u(2) nal_ref_idc = 3
+				"0 0101" + // u(5) nal_unit_type = 5
+
+				// rbsp bytes with emulation prevention bytes at the start and end.
+				"0000 0000" +
+				"0000 0000" +
+				"0000 0011" + // emulation_prevention_three_byte
+				"0000 0001" +
+				"1000 1000" +
+				"0000 0000" +
+				"0000 0000" +
+				"0000 0011" + // emulation_prevention_three_byte
+				"0000 0000" +
+				"1000 0000", // trailing bits
+
+			want: &NALUnit{
+				ForbiddenZeroBit:             0,
+				RefIdc:                       3,
+				Type:                         5,
+				EmulationPreventionThreeByte: 0x03,
+				RBSP: []byte{
+					0x00,
+					0x00,
+					0x01,
+					0x88,
+					0x00,
+					0x00,
+					0x00,
+				},
+			},
+		},
 	}
 
 	for i, test := range tests {
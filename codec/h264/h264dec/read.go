@@ -1,6 +1,7 @@
 package h264dec
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -62,17 +63,30 @@ func (h *H264Reader) Start() {
 		nalUnit, _, _ := h.readNalUnit()
 		switch nalUnit.Type {
 		case NALTypeSPS:
-			// TODO: handle this error
-			sps, _ := NewSPS(nalUnit.RBSP, false)
-			h.VideoStreams = append(
-				h.VideoStreams,
-				&VideoStream{SPS: sps},
-			)
+			sps, err := NewSPS(nalUnit.RBSP, false)
+			if err != nil {
+				logger.Printf("error: could not parse SPS, skipping: %v\n", err)
+				continue
+			}
+			// A stream may carry several SPS, distinguished by ID, so only
+			// the first starts a new video stream.
+			if len(h.VideoStreams) == 0 {
+				h.VideoStreams = append(h.VideoStreams, &VideoStream{})
+			}
+			h.VideoStreams[len(h.VideoStreams)-1].AddSPS(sps)
 		case NALTypePPS:
+			if len(h.VideoStreams) == 0 {
+				logger.Printf("warning: PPS before any SPS, skipping\n")
+				continue
+			}
 			videoStream := h.VideoStreams[len(h.VideoStreams)-1]
-			// TODO: handle this error
-			// TODO: fix chromaFormat
-			videoStream.PPS, _ = NewPPS(nil, 0)
+			br := bits.NewBitReader(bytes.NewReader(nalUnit.RBSP))
+			pps, err := NewPPS(br, int(videoStream.SPS.ChromaFormatIDC))
+			if err != nil {
+				logger.Printf("error: could not parse PPS, skipping: %v\n", err)
+				continue
+			}
+			videoStream.AddPPS(pps)
 		case NALTypeIDR:
 			fallthrough
 		case NALTypeNonIDR:
@@ -178,63 +192,61 @@ func isEmpty3Byte(buf []byte) bool {
 	return true
 }
 
-func moreRBSPData(br *bits.BitReader) bool {
-	// If we get an error then we must at end of NAL unit or end of stream, so
-	// return false.
-	b, err := br.PeekBits(1)
-	if err != nil {
-		return false
-	}
+// maxRBSPPeek is the maximum number of bytes that moreRBSPData will look ahead
+// for the end of the RBSP.
+const maxRBSPPeek = 4096
 
-	// If b is not 1, then we don't have a stop bit and therefore there is more
-	// data so return true.
-	if b == 0 {
-		return true
+// moreRBSPData returns true if there is more data in the RBSP before the
+// rbsp_trailing_bits as per more_rbsp_data() in section 7.2, i.e. if there is
+// a bit equal to 1 after the current position. The last bit equal to 1 is the
+// rbsp_stop_one_bit, which may be followed only by zero bits, cabac_zero_words
+// and trailing zero bytes. The RBSP is taken to end at the end of the source,
+// or at the start code of the following NAL unit. As the NAL unit may be read
+// before the removal of emulation prevention bytes, these are ignored.
+func moreRBSPData(br *bits.BitReader) bool {
+	// If we get an error then we must be at the end of the NAL unit or end of
+	// stream, so there is no more data.
+	n := br.Off()
+	if n == 0 {
+		n = 8
 	}
-
-	// If we have a stop bit and trailing zeros then we're okay, otherwise return
-	// now, we haven't found the end.
-	b, err = br.PeekBits(8 - br.Off())
+	b, err := br.PeekBits(n)
 	if err != nil {
 		return false
 	}
-	rem := 0x01 << uint(7-br.Off())
-	if int(b) != rem {
-		return true
-	}
 
-	// If we try to read another bit but get EOF then we must be at the end of the
-	// NAL or stream.
-	_, err = br.PeekBits(9 - br.Off())
-	if err != nil {
-		return false
+	// Check for a 1 bit in the remainder of the current byte after the current
+	// bit.
+	if b&(1<<uint(n-1)-1) != 0 {
+		return true
 	}
 
-	// Do we have some trailing 0 bits, and then a 24-bit start code ? If so, it
-	// there must not be any more RBSP data left.
-	// If we get an error from the Peek, then there must not be another NAL, and
-	// there must be some more RBSP, because trailing bits do not extend past the
-	// byte in which the stop bit is found.
-	b, err = br.PeekBits(8 - br.Off() + 24)
-	if err != nil {
-		return true
+	// Otherwise look for a non-zero byte in the remainder of the RBSP. The
+	// bytes peeked begin at the current byte if we are byte aligned.
+	next, _ := br.PeekBytes(maxRBSPPeek)
+	if br.ByteAligned() {
+		next = next[1:]
 	}
-	rem = (0x01 << uint((7-br.Off())+24)) | 0x01
-	if int(b) == rem {
-		return false
+	var zeros int
+	if n == 8 && b == 0 {
+		zeros = 1
 	}
-
-	// Similar check to above, but this time checking for 32-bit start code.
-	b, err = br.PeekBits(8 - br.Off() + 32)
-	if err != nil {
+	for _, c := range next {
+		switch {
+		case c == 0x00:
+			zeros++
+			continue
+		case c == 0x01 && zeros >= 2:
+			// Start code of the next NAL unit.
+			return false
+		case c == 0x03 && zeros >= 2:
+			// Emulation prevention byte.
+			zeros = 0
+			continue
+		}
 		return true
 	}
-	rem = (0x01 << uint((7-br.Off())+32)) | 0x01
-	if int(b) == rem {
-		return false
-	}
-
-	return true
+	return false
 }
 
 type field struct {
@@ -40,7 +40,21 @@ func TestMoreRBSPData(t *testing.T) {
 			want: false,
 		},
 		{
-			in:   "10000000 00000000",
+			// Stop bit followed by a cabac_zero_word.
+			in:   "10000000 00000000 00000000",
+			want: false,
+		},
+		{
+			// Stop bit followed by an emulation prevented cabac_zero_word.
+			in:   "10000000 00000000 00000000 00000011",
+			want: false,
+		},
+		{
+			in:   "00000000 00000001",
+			want: true,
+		},
+		{
+			in:   "10000000 00000000 00000010",
 			want: true,
 		},
 	}
@@ -57,3 +71,44 @@ func TestMoreRBSPData(t *testing.T) {
 		}
 	}
 }
+
+// TestMoreRBSPDataTermination checks that a loop reading syntax elements while
+// moreRBSPData is true, as for slice data, terminates after the last element
+// for varying positions of the rbsp_stop_one_bit.
+func TestMoreRBSPDataTermination(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string // Elements coded as ue(v), then the rbsp_trailing_bits.
+		want int    // Number of elements.
+	}{
+		{name: "stop bit at start of byte", in: "1111 010 1" + " 10000000", want: 6},
+		{name: "stop bit at end of byte", in: "010 011 1" + "1", want: 3},
+		{name: "stop bit mid byte", in: "1 010" + "1000", want: 2},
+		{name: "element spanning bytes", in: "00111 00100 1" + "10000", want: 3},
+		{name: "zero bits before stop bit", in: "0001000 0001000" + "10", want: 2},
+		{name: "zero byte within element", in: "000000001 00000000" + "1000000", want: 1},
+		{name: "cabac_zero_words", in: "1 1 1" + "10000" + " 00000000 00000000 00000011 00000000 00000000 00000011", want: 3},
+		{name: "next NAL unit", in: "010 1" + "1000" + " 00000000 00000000 00000001 01100111 01000010", want: 2},
+	}
+
+	for _, test := range tests {
+		b, err := binToSlice(test.in)
+		if err != nil {
+			t.Fatalf("unexpected binToSlice error for test %q: %v", test.name, err)
+		}
+
+		br := bits.NewBitReader(bytes.NewReader(b))
+		r := newFieldReader(br)
+		var got int
+		for moreRBSPData(br) {
+			r.readUe()
+			if r.err() != nil {
+				t.Fatalf("unexpected error reading element %d for test %q: %v", got, test.name, r.err())
+			}
+			got++
+		}
+		if got != test.want {
+			t.Errorf("did not get expected number of elements for test %q.\nGot: %d\nWant: %d\n", test.name, got, test.want)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/ausocean/av/codec/codecutil"
 	"github.com/ausocean/av/codec/h264/h264dec/bits"
 	"github.com/pkg/errors"
 )
@@ -43,8 +44,15 @@ type picture struct {
 }
 
 type VideoStream struct {
+	// SPS and PPS are the active parameter sets, i.e. those referenced by the
+	// slice currently being parsed.
 	*SPS
 	*PPS
+
+	// SPSs and PPSs hold the parameter sets of the stream by ID.
+	SPSs map[int]*SPS
+	PPSs map[int]*PPS
+
 	Slices []*SliceContext
 
 	ChromaArrayType                  int
@@ -59,6 +67,57 @@ type VideoStream struct {
 	bottomFieldOrderCnt              int
 }
 
+// Errors used by activateParamSets.
+var (
+	errUnknownPPS = errors.New("slice references unknown PPS")
+	errUnknownSPS = errors.New("PPS references unknown SPS")
+)
+
+// AddSPS adds sps to the stream's sequence parameter sets, replacing any with
+// the same ID, and makes it the active SPS.
+func (vid *VideoStream) AddSPS(sps *SPS) {
+	if vid.SPSs == nil {
+		vid.SPSs = make(map[int]*SPS)
+	}
+	vid.SPSs[int(sps.SPSID)] = sps
+	vid.SPS = sps
+}
+
+// AddPPS adds pps to the stream's picture parameter sets, replacing any with
+// the same ID, and makes it the active PPS.
+func (vid *VideoStream) AddPPS(pps *PPS) {
+	if vid.PPSs == nil {
+		vid.PPSs = make(map[int]*PPS)
+	}
+	vid.PPSs[pps.ID] = pps
+	vid.PPS = pps
+}
+
+// activateParamSets makes the PPS with the given ID, and the SPS it
+// references, the active parameter sets of the stream, as required when
+// parsing a slice (see section 7.4.1.2.1). If no parameter sets have been
+// added using AddSPS or AddPPS, the current active parameter sets are kept.
+func (vid *VideoStream) activateParamSets(ppsID int) error {
+	if len(vid.PPSs) == 0 {
+		return nil
+	}
+	pps, ok := vid.PPSs[ppsID]
+	if !ok {
+		return fmt.Errorf("%w: %d", errUnknownPPS, ppsID)
+	}
+	vid.PPS = pps
+
+	if len(vid.SPSs) == 0 {
+		return nil
+	}
+	sps, ok := vid.SPSs[pps.SPSID]
+	if !ok {
+		return fmt.Errorf("%w: %d", errUnknownSPS, pps.SPSID)
+	}
+	vid.SPS = sps
+	return nil
+}
+
 type SliceContext struct {
 	*SPS
 	*PPS
@@ -1220,30 +1279,63 @@ func (c *SliceContext) Update(header *SliceHeader, data *SliceData) {
 	c.Slice = &Slice{SliceHeader: header, SliceData: data}
 }
 func NewSliceContext(vid *VideoStream, nalUnit *NALUnit, rbsp []byte, showPacket bool) (*SliceContext, error) {
-	var err error
-	sps := vid.SPS
-	pps := vid.PPS
 	logger.Printf("debug: %s RBSP %d bytes %d bits == \n", NALUnitType[int(nalUnit.Type)], len(rbsp), len(rbsp)*8)
 	logger.Printf("debug: \t%#v\n", rbsp[0:8])
+	br := bits.NewBitReader(bytes.NewReader(rbsp))
+	header, err := newSliceHeader(vid, nalUnit, br)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse slice header")
+	}
+
+	sliceContext := &SliceContext{
+		SPS:     vid.SPS,
+		PPS:     vid.PPS,
+		NALUnit: nalUnit,
+		Slice: &Slice{
+			SliceHeader: header,
+		},
+	}
+	sliceContext.Slice.SliceData, err = NewSliceData(vid.ChromaArrayType, vid, sliceContext, br)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create slice data")
+	}
+
+	return sliceContext, nil
+}
+
+// newSliceHeader parses a slice header from br as per section 7.3.3. The
+// parameter sets referenced by the slice header become the active parameter
+// sets of vid.
+func newSliceHeader(vid *VideoStream, nalUnit *NALUnit, br *bits.BitReader) (*SliceHeader, error) {
+	var err error
 	var idrPic bool
 	if nalUnit.Type == 5 {
 		idrPic = true
 	}
 	header := SliceHeader{}
-	if sps.SeparateColorPlaneFlag {
-		vid.ChromaArrayType = 0
-	} else {
-		vid.ChromaArrayType = int(sps.ChromaFormatIDC)
-	}
-	br := bits.NewBitReader(bytes.NewReader(rbsp))
 	r := newFieldReader(br)
 
 	header.FirstMbInSlice = int(r.readUe())
 	header.SliceType = int(r.readUe())
 
 	sliceType := sliceTypeMap[header.SliceType]
-	logger.Printf("debug: %s (%s) slice of %d bytes\n", NALUnitType[int(nalUnit.Type)], sliceType, len(rbsp))
+	logger.Printf("debug: %s (%s) slice\n", NALUnitType[int(nalUnit.Type)], sliceType)
 	header.PPSID = int(r.readUe())
+
+	// The remainder of the slice is parsed using the parameter sets referenced
+	// by the PPS ID.
+	err = vid.activateParamSets(header.PPSID)
+	if err != nil {
+		return nil, err
+	}
+	sps := vid.SPS
+	pps := vid.PPS
+	if sps.SeparateColorPlaneFlag {
+		vid.ChromaArrayType = 0
+	} else {
+		vid.ChromaArrayType = int(sps.ChromaFormatIDC)
+	}
+
 	if sps.SeparateColorPlaneFlag {
 		b, err := br.ReadBits(2)
 		if err != nil {
@@ -1379,16 +1471,41 @@ func NewSliceContext(vid *VideoStream, nalUnit *NALUnit, rbsp []byte, showPacket
 		header.SliceGroupChangeCycle = int(b)
 	}
 
-	sliceContext := &SliceContext{
-		NALUnit: nalUnit,
-		Slice: &Slice{
-			SliceHeader: &header,
-		},
+	return &header, nil
+}
+
+// ParseSliceStart parses only the start of the slice header of the slice NAL
+// unit n, which must not include a start code, returning first_mb_in_slice
+// and the name of the slice type ("P", "B", "I", "SP" or "SI"). This is
+// enough to find picture boundaries and slice types without the SPS and PPS.
+func ParseSliceStart(n []byte) (firstMB int, sliceType string, err error) {
+	if len(n) < 2 {
+		return 0, "", errors.New("NAL unit too short for slice header")
 	}
-	sliceContext.Slice.SliceData, err = NewSliceData(vid.ChromaArrayType, nil, sliceContext, br)
-	if err != nil {
-		return nil, errors.Wrap(err, "could not create slice data")
+	if t := n[0] & 0x1f; t != NALTypeNonIDR && t != NALTypeIDR {
+		return 0, "", fmt.Errorf("NAL unit type %d is not a slice", t)
 	}
 
-	return sliceContext, nil
+	// The first two fields of the header fit in a few bytes, so limit the
+	// emulation prevention removal to the start of the NAL unit.
+	const maxStart = 16
+	end := len(n)
+	if end > maxStart+1 {
+		end = maxStart + 1
+	}
+	br := bits.NewBitReader(bytes.NewReader(codecutil.EBSPToRBSP(n[1:end])))
+
+	mb, err := readUe(br)
+	if err != nil {
+		return 0, "", errors.Wrap(err, "could not parse first_mb_in_slice")
+	}
+	st, err := readUe(br)
+	if err != nil {
+		return 0, "", errors.Wrap(err, "could not parse slice_type")
+	}
+	name, ok := sliceTypeMap[int(st)]
+	if !ok {
+		return 0, "", fmt.Errorf("invalid slice type %d", st)
+	}
+	return int(mb), name, nil
 }
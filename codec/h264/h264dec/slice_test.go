@@ -20,6 +20,7 @@ package h264dec
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -325,3 +326,83 @@ func TestDecRefPicMarking(t *testing.T) {
 		}
 	}
 }
+
+// TestNewSliceHeaderParamSets checks that a slice header is parsed using the
+// SPS and PPS it references when a video stream holds several of each.
+func TestNewSliceHeaderParamSets(t *testing.T) {
+	sps0 := &SPS{SPSID: 0, ChromaFormatIDC: chroma420, FrameMBSOnlyFlag: true, PicOrderCountType: 2}
+	sps1 := &SPS{SPSID: 1, ChromaFormatIDC: chroma444, PicOrderCountType: 0, Log2MaxPicOrderCntLSBMin4: 4}
+	pps0 := &PPS{ID: 0, SPSID: 0}
+	pps1 := &PPS{ID: 1, SPSID: 1}
+
+	vid := &VideoStream{}
+	vid.AddSPS(sps0)
+	vid.AddSPS(sps1)
+	vid.AddPPS(pps0)
+	vid.AddPPS(pps1)
+
+	tests := []struct {
+		name    string
+		in      string // Slice header bits.
+		want    SliceHeader
+		wantSPS *SPS
+		wantPPS *PPS
+		err     error
+	}{
+		{
+			name: "second SPS",
+			in: "1" + // ue(v) first_mb_in_slice = 0
+				"0001000" + // ue(v) slice_type = 7 (I)
+				"010" + // ue(v) pic_parameter_set_id = 1
+				"1" + // u(1) field_pic_flag = 1, as frame_mbs_only_flag = 0 for SPS 1
+				"0" + // u(1) bottom_field_flag = 0
+				"10101010" + // u(8) pic_order_cnt_lsb = 170, as log2_max_pic_order_cnt_lsb_minus4 = 4
+				"1", // se(v) slice_qp_delta = 0
+			want:    SliceHeader{SliceType: 7, PPSID: 1, FieldPic: true, PicOrderCntLsb: 170},
+			wantSPS: sps1,
+			wantPPS: pps1,
+		},
+		{
+			name: "first SPS",
+			in: "1" + // ue(v) first_mb_in_slice = 0
+				"0001000" + // ue(v) slice_type = 7 (I)
+				"1" + // ue(v) pic_parameter_set_id = 0
+				"1", // se(v) slice_qp_delta = 0
+			want:    SliceHeader{SliceType: 7},
+			wantSPS: sps0,
+			wantPPS: pps0,
+		},
+		{
+			name: "unknown PPS",
+			in: "1" + // ue(v) first_mb_in_slice = 0
+				"0001000" + // ue(v) slice_type = 7 (I)
+				"011", // ue(v) pic_parameter_set_id = 2
+			err: errUnknownPPS,
+		},
+	}
+
+	for _, test := range tests {
+		b, err := binToSlice(test.in)
+		if err != nil {
+			t.Fatalf("unexpected error converting bits for test %q: %v", test.name, err)
+		}
+
+		got, err := newSliceHeader(vid, &NALUnit{Type: NALTypeNonIDR}, bits.NewBitReader(bytes.NewReader(b)))
+		if !errors.Is(err, test.err) {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		// RefPicListModification is not of interest here.
+		got.RefPicListModification = nil
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("did not get expected slice header for test %q.\nGot: %+v\nWant: %+v\n", test.name, *got, test.want)
+		}
+		if vid.SPS != test.wantSPS || vid.PPS != test.wantPPS {
+			t.Errorf("did not get expected active parameter sets for test %q", test.name)
+		}
+	}
+}
@@ -235,12 +235,13 @@ func NewSPS(rbsp []byte, showPacket bool) (*SPS, error) {
 	r.readBits(2) // 2 reserved bits.
 	sps.LevelIDC = uint8(r.readBits(8))
 	sps.SPSID = r.readUe()
-	sps.ChromaFormatIDC = r.readUe()
 
 	// This should be done only for certain ProfileIDC:
 	isProfileIDC := []int{100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135}
 	// SpecialProfileCase1
+	sps.ChromaFormatIDC = chroma420 // Inferred when not present.
 	if isInList(isProfileIDC, int(sps.Profile)) {
+		sps.ChromaFormatIDC = r.readUe()
 		if sps.ChromaFormatIDC == chroma444 {
 			// TODO: should probably deal with error here.
 			sps.SeparateColorPlaneFlag = r.readBits(1) == 1
@@ -324,12 +325,30 @@ func NewSPS(rbsp []byte, showPacket bool) (*SPS, error) {
 	sps.VUIParametersPresentFlag = r.readBits(1) == 1
 
 	if sps.VUIParametersPresentFlag {
-
+		var err error
+		sps.VUIParameters, err = NewVUIParameters(br)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse VUIParameters")
+		}
 	} // End VuiParameters Annex E.1.1
 
 	return &sps, nil
 }
 
+// FrameRate returns the frame rate given by the timing information in the VUI
+// parameters of the SPS, or false if this is not present. Each frame is taken
+// to span two clock ticks, as is the case for frame coded video (see E.2.1).
+func (s *SPS) FrameRate() (float64, bool) {
+	if !s.VUIParametersPresentFlag || s.VUIParameters == nil {
+		return 0, false
+	}
+	vui := s.VUIParameters
+	if !vui.TimingInfoPresentFlag || vui.NumUnitsInTick == 0 {
+		return 0, false
+	}
+	return float64(vui.TimeScale) / float64(2*vui.NumUnitsInTick), true
+}
+
 // SPS describes a sequence parameter set as defined by section E.1.1 in the
 // Specifications.
 // Semantics for fields are define in section E.2.1. Comments on fields are
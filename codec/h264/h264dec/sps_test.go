@@ -0,0 +1,72 @@
+/*
+DESCRIPTION
+  sps_test.go provides testing for parsing functionality found in sps.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h264dec
+
+import "testing"
+
+func TestNewSPSFrameRate(t *testing.T) {
+	// A baseline profile SPS for 320x240 video with VUI timing information
+	// giving 25 frames per second.
+	in := "01000010" + // u(8) profile_idc = 66
+		"11000000" + // u(8) constraint_set0_flag = 1, constraint_set1_flag = 1, reserved
+		"00011110" + // u(8) level_idc = 30
+		"1" + // ue(v) seq_parameter_set_id = 0
+		"1" + // ue(v) log2_max_frame_num_minus4 = 0
+		"011" + // ue(v) pic_order_cnt_type = 2
+		"010" + // ue(v) max_num_ref_frames = 1
+		"0" + // u(1) gaps_in_frame_num_value_allowed_flag = 0
+		"000010100" + // ue(v) pic_width_in_mbs_minus1 = 19
+		"0001111" + // ue(v) pic_height_in_map_units_minus1 = 14
+		"1" + // u(1) frame_mbs_only_flag = 1
+		"1" + // u(1) direct_8x8_inference_flag = 1
+		"0" + // u(1) frame_cropping_flag = 0
+		"1" + // u(1) vui_parameters_present_flag = 1
+		"0" + // u(1) aspect_ratio_info_present_flag = 0
+		"0" + // u(1) overscan_info_present_flag = 0
+		"0" + // u(1) video_signal_type_present_flag = 0
+		"0" + // u(1) chroma_loc_info_present_flag = 0
+		"1" + // u(1) timing_info_present_flag = 1
+		"00000000000000000000000000000001" + // u(32) num_units_in_tick = 1
+		"00000000000000000000000000110010" + // u(32) time_scale = 50
+		"1" + // u(1) fixed_frame_rate_flag = 1
+		"0" + // u(1) nal_hrd_parameters_present_flag = 0
+		"0" + // u(1) vcl_hrd_parameters_present_flag = 0
+		"0" + // u(1) pic_struct_present_flag = 0
+		"0" + // u(1) bitstream_restriction_flag = 0
+		"1" // rbsp_stop_one_bit, which is byte aligned.
+
+	b, err := binToSlice(in)
+	if err != nil {
+		t.Fatalf("unexpected error converting bits: %v", err)
+	}
+	sps, err := NewSPS(b, false)
+	if err != nil {
+		t.Fatalf("did not expect error parsing SPS: %v", err)
+	}
+
+	if sps.ChromaFormatIDC != chroma420 || sps.PicWidthInMBSMinus1 != 19 || sps.PicHeightInMapUnitsMinus1 != 14 {
+		t.Errorf("did not get expected SPS fields: %+v", sps)
+	}
+
+	const wantRate = 25
+	got, ok := sps.FrameRate()
+	if !ok || got != wantRate {
+		t.Errorf("did not get expected frame rate.\nGot: %v, %v\nWant: %v, true\n", got, ok, wantRate)
+	}
+
+	_, ok = (&SPS{}).FrameRate()
+	if ok {
+		t.Error("did not expect frame rate for SPS without VUI parameters")
+	}
+}
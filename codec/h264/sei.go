@@ -0,0 +1,149 @@
+/*
+NAME
+  sei.go
+
+DESCRIPTION
+  sei.go provides functionality for creating, inserting and parsing H.264
+  supplemental enhancement information (SEI) NAL units.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h264
+
+import (
+	"errors"
+
+	"github.com/ausocean/av/codec/codecutil"
+	"github.com/ausocean/av/codec/h264/h264dec"
+)
+
+// SEIUserDataUnregistered is the SEI payload type of user_data_unregistered
+// messages, as described by section D.1.6 of ITU-T H.264.
+const SEIUserDataUnregistered = 5
+
+// uuidSize is the size of the uuid_iso_iec_11578 at the start of a
+// user_data_unregistered SEI payload.
+const uuidSize = 16
+
+var (
+	errNotSEI       = errors.New("not an SEI NAL unit")
+	errTruncatedSEI = errors.New("SEI message extends past end of NAL unit")
+)
+
+// SEIMessage is a single message from an SEI NAL unit.
+type SEIMessage struct {
+	Type    int
+	Payload []byte
+}
+
+// UserData returns the UUID and user data of a user_data_unregistered
+// message. ok is false if m is not such a message.
+func (m SEIMessage) UserData() (uuid [uuidSize]byte, data []byte, ok bool) {
+	if m.Type != SEIUserDataUnregistered || len(m.Payload) < uuidSize {
+		return uuid, nil, false
+	}
+	copy(uuid[:], m.Payload)
+	return uuid, m.Payload[uuidSize:], true
+}
+
+// UserDataSEI returns an SEI NAL unit in byte stream format, i.e. with a start
+// code, holding a single user_data_unregistered message with the given UUID
+// and data.
+func UserDataSEI(uuid [uuidSize]byte, data []byte) []byte {
+	payload := append(uuid[:], data...)
+
+	var rbsp []byte
+	for n := SEIUserDataUnregistered; ; n -= 0xff {
+		if n < 0xff {
+			rbsp = append(rbsp, byte(n))
+			break
+		}
+		rbsp = append(rbsp, 0xff)
+	}
+	for n := len(payload); ; n -= 0xff {
+		if n < 0xff {
+			rbsp = append(rbsp, byte(n))
+			break
+		}
+		rbsp = append(rbsp, 0xff)
+	}
+	rbsp = append(rbsp, payload...)
+	rbsp = append(rbsp, 0x80) // rbsp_trailing_bits.
+
+	nal := []byte{0x00, 0x00, 0x00, 0x01, h264dec.NALTypeSEI}
+	return append(nal, codecutil.RBSPToEBSP(rbsp)...)
+}
+
+// ParseSEI returns the messages in the SEI NAL unit n, which must not include
+// a start code.
+func ParseSEI(n []byte) ([]SEIMessage, error) {
+	if len(n) == 0 || n[0]&0x1f != h264dec.NALTypeSEI {
+		return nil, errNotSEI
+	}
+	rbsp := codecutil.EBSPToRBSP(n[1:])
+
+	// readValue reads a value coded as a sequence of 0xff bytes followed by a
+	// final byte, as used for the SEI payload type and size.
+	var off int
+	readValue := func() (int, bool) {
+		var v int
+		for off < len(rbsp) {
+			b := rbsp[off]
+			off++
+			v += int(b)
+			if b != 0xff {
+				return v, true
+			}
+		}
+		return 0, false
+	}
+
+	var msgs []SEIMessage
+	// Messages continue until the rbsp_trailing_bits.
+	for off < len(rbsp) && rbsp[off] != 0x80 {
+		typ, ok := readValue()
+		if !ok {
+			return msgs, errTruncatedSEI
+		}
+		size, ok := readValue()
+		if !ok || off+size > len(rbsp) {
+			return msgs, errTruncatedSEI
+		}
+		msgs = append(msgs, SEIMessage{Type: typ, Payload: rbsp[off : off+size]})
+		off += size
+	}
+	return msgs, nil
+}
+
+// InsertBeforeVCL returns the access unit au, in byte stream format, with the
+// NAL unit nal inserted before its first slice. If au has no slice, nal is
+// appended. au is not modified.
+func InsertBeforeVCL(au, nal []byte) []byte {
+	idx := len(au)
+	for i := 0; i+3 < len(au); i++ {
+		if au[i] != 0x00 || au[i+1] != 0x00 || au[i+2] != 0x01 {
+			continue
+		}
+		typ := au[i+3] & 0x1f
+		if typ < h264dec.NALTypeNonIDR || typ > h264dec.NALTypeIDR {
+			continue
+		}
+		idx = i
+		if i > 0 && au[i-1] == 0x00 {
+			idx--
+		}
+		break
+	}
+
+	res := make([]byte, 0, len(au)+len(nal))
+	res = append(res, au[:idx]...)
+	res = append(res, nal...)
+	return append(res, au[idx:]...)
+}
@@ -0,0 +1,104 @@
+/*
+NAME
+  sei_test.go
+
+DESCRIPTION
+  sei_test.go provides testing for functionality in sei.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h264
+
+import (
+	"bytes"
+	"testing"
+)
+
+var testUUID = [16]byte{0xa1, 0xb2, 0xc3, 0xd4, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+
+func TestUserDataSEI(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "text", data: []byte("ts=1700000000;site=rig1")},
+		{name: "emulation", data: []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x03}},
+		{name: "long", data: bytes.Repeat([]byte{0x5a}, 600)},
+	}
+	for _, test := range tests {
+		nal := UserDataSEI(testUUID, test.data)
+		if !bytes.HasPrefix(nal, []byte{0x00, 0x00, 0x00, 0x01, 0x06}) {
+			t.Fatalf("did not get expected start code and header for test %q: %x", test.name, nal[:5])
+		}
+		if bytes.Contains(nal[4:], []byte{0x00, 0x00, 0x01}) {
+			t.Errorf("SEI for test %q contains start code emulation", test.name)
+		}
+
+		msgs, err := ParseSEI(nal[4:])
+		if err != nil {
+			t.Fatalf("did not expect error parsing SEI for test %q: %v", test.name, err)
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("did not get expected number of messages for test %q.\nGot: %d\nWant: 1\n", test.name, len(msgs))
+		}
+		uuid, data, ok := msgs[0].UserData()
+		if !ok {
+			t.Fatalf("did not get user data message for test %q: type %d", test.name, msgs[0].Type)
+		}
+		if uuid != testUUID {
+			t.Errorf("did not get expected UUID for test %q.\nGot: %x\nWant: %x\n", test.name, uuid, testUUID)
+		}
+		if !bytes.Equal(data, test.data) {
+			t.Errorf("did not get expected data for test %q.\nGot: %x\nWant: %x\n", test.name, data, test.data)
+		}
+	}
+
+	_, err := ParseSEI([]byte{0x65, 0x88})
+	if err != errNotSEI {
+		t.Errorf("did not get expected error for non-SEI.\nGot: %v\nWant: %v\n", err, errNotSEI)
+	}
+	_, err = ParseSEI([]byte{0x06, 0x05, 0x20, 0x00})
+	if err != errTruncatedSEI {
+		t.Errorf("did not get expected error for truncated SEI.\nGot: %v\nWant: %v\n", err, errTruncatedSEI)
+	}
+}
+
+func TestInsertBeforeVCL(t *testing.T) {
+	var (
+		aud = []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0}
+		sps = []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42}
+		pps = []byte{0x00, 0x00, 0x01, 0x68, 0xce}
+		idr = []byte{0x00, 0x00, 0x01, 0x65, 0x88}
+		sei = []byte{0x00, 0x00, 0x00, 0x01, 0x06, 0x05}
+	)
+	cat := func(b ...[]byte) []byte { return bytes.Join(b, nil) }
+
+	tests := []struct {
+		name string
+		au   []byte
+		want []byte
+	}{
+		{name: "idr only", au: idr, want: cat(sei, idr)},
+		{name: "parameter sets", au: cat(aud, sps, pps, idr), want: cat(aud, sps, pps, sei, idr)},
+		{name: "four byte start code", au: cat(aud, []byte{0x00}, idr), want: cat(aud, sei, []byte{0x00}, idr)},
+		{name: "no slice", au: cat(aud, sps), want: cat(aud, sps, sei)},
+	}
+	for _, test := range tests {
+		au := append([]byte(nil), test.au...)
+		got := InsertBeforeVCL(au, sei)
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("did not get expected result for test %q.\nGot: %x\nWant: %x\n", test.name, got, test.want)
+		}
+		if !bytes.Equal(au, test.au) {
+			t.Errorf("access unit modified for test %q", test.name)
+		}
+	}
+}
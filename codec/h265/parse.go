@@ -0,0 +1,118 @@
+/*
+NAME
+  parse.go
+
+DESCRIPTION
+  parse.go provides H.265 NAL unit parsing, including the splitting of Annex-B
+  byte streams into NAL units and the parsing of NAL unit headers.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h265
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ausocean/av/codec/codecutil"
+)
+
+// NAL unit types (from ITU-T H.265 Table 7-1).
+const (
+	NALTypeTrailN      = 0
+	NALTypeTrailR      = 1
+	NALTypeBLAWLP      = 16
+	NALTypeBLAWRADL    = 17
+	NALTypeBLANLP      = 18
+	NALTypeIDRWRADL    = 19
+	NALTypeIDRNLP      = 20
+	NALTypeCRA         = 21
+	NALTypeVPS         = 32
+	NALTypeSPS         = 33
+	NALTypePPS         = 34
+	NALTypeAUD         = 35
+	NALTypeEOS         = 36
+	NALTypeEOB         = 37
+	NALTypeFD          = 38
+	NALTypePrefixSEI   = 39
+	NALTypeSuffixSEI   = 40
+	nalTypeIRAPLowest  = NALTypeBLAWLP
+	nalTypeIRAPHighest = 23 // Includes reserved IRAP types 22 and 23.
+)
+
+// nalHeaderSize is the size of the H.265 NAL unit header in bytes.
+const nalHeaderSize = 2
+
+// Errors returned by ParseNALHeader.
+var (
+	ErrShortNAL        = errors.New("NAL unit too short for header")
+	ErrForbiddenBit    = errors.New("NAL unit forbidden_zero_bit is set")
+	ErrZeroTemporalID1 = errors.New("NAL unit nuh_temporal_id_plus1 is zero")
+)
+
+// NALHeader holds the fields of an H.265 NAL unit header.
+type NALHeader struct {
+	Type       uint8 // nal_unit_type.
+	LayerID    uint8 // nuh_layer_id.
+	TemporalID uint8 // TemporalId, i.e. nuh_temporal_id_plus1 - 1.
+}
+
+// ParseNALHeader parses the 2 byte header at the start of the NAL unit n. n
+// must not include a start code.
+func ParseNALHeader(n []byte) (NALHeader, error) {
+	if len(n) < nalHeaderSize {
+		return NALHeader{}, ErrShortNAL
+	}
+	if n[0]&0x80 != 0 {
+		return NALHeader{}, ErrForbiddenBit
+	}
+	tid1 := n[1] & 0x07
+	if tid1 == 0 {
+		return NALHeader{}, ErrZeroTemporalID1
+	}
+	return NALHeader{
+		Type:       (n[0] >> 1) & 0x3f,
+		LayerID:    (n[0]&0x01)<<5 | n[1]>>3,
+		TemporalID: tid1 - 1,
+	}, nil
+}
+
+// IsIRAP returns true if the NAL unit is an intra random access point, i.e.
+// a BLA, IDR or CRA picture.
+func (h NALHeader) IsIRAP() bool {
+	return h.Type >= nalTypeIRAPLowest && h.Type <= nalTypeIRAPHighest
+}
+
+// IsIDR returns true if the NAL unit is an IDR picture.
+func (h NALHeader) IsIDR() bool {
+	return h.Type == NALTypeIDRWRADL || h.Type == NALTypeIDRNLP
+}
+
+// IsVCL returns true if the NAL unit is a VCL NAL unit, i.e. a coded slice
+// segment, including reserved VCL types.
+func (h NALHeader) IsVCL() bool {
+	return h.Type < NALTypeVPS
+}
+
+// IsParameterSet returns true if the NAL unit is a VPS, SPS or PPS.
+func (h NALHeader) IsParameterSet() bool {
+	return h.Type == NALTypeVPS || h.Type == NALTypeSPS || h.Type == NALTypePPS
+}
+
+// String implements fmt.Stringer.
+func (h NALHeader) String() string {
+	return fmt.Sprintf("type: %d, layer: %d, tid: %d", h.Type, h.LayerID, h.TemporalID)
+}
+
+// SplitNALUnits splits the Annex-B byte stream b into NAL units, as
+// described by codecutil.SplitNALUnits.
+func SplitNALUnits(b []byte) [][]byte {
+	return codecutil.SplitNALUnits(b)
+}
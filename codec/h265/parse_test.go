@@ -0,0 +1,125 @@
+/*
+NAME
+  parse_test.go
+
+DESCRIPTION
+  parse_test.go provides testing for the NAL unit parsing in parse.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package h265
+
+import (
+	"bytes"
+	"testing"
+)
+
+// NAL units taken from the start of an HEVC stream captured from a camera.
+var (
+	vps = []byte{
+		0x40, 0x01, 0x0c, 0x01, 0xff, 0xff, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00,
+		0x90, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00, 0x5d, 0x95, 0x98, 0x09,
+	}
+	sps = []byte{
+		0x42, 0x01, 0x01, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x03, 0x00, 0x5d, 0xa0, 0x02, 0x80, 0x80, 0x2d, 0x16,
+		0x59, 0x59, 0xa4, 0x93, 0x2b, 0xc0, 0x5a, 0x70, 0x80, 0x00, 0x01, 0xf4,
+		0x80, 0x00, 0x3a, 0x98, 0x04,
+	}
+	pps   = []byte{0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62, 0x40}
+	sei   = []byte{0x4e, 0x01, 0x05, 0x1a, 0x47, 0x56, 0x4a, 0xdc, 0x5c, 0x4c, 0x80}
+	idr   = []byte{0x26, 0x01, 0xaf, 0x06, 0xb8, 0x63, 0xef, 0x3a, 0x7f, 0x3e, 0x6b, 0x10}
+	trail = []byte{0x02, 0x01, 0xd0, 0x09, 0x7e, 0x10, 0xc2, 0x27, 0x61, 0x2e}
+)
+
+// annexB joins the given NAL units using 4 byte start codes for parameter sets
+// and 3 byte start codes otherwise.
+func annexB(nalus ...[]byte) []byte {
+	var buf bytes.Buffer
+	for _, n := range nalus {
+		h, _ := ParseNALHeader(n)
+		if h.IsParameterSet() {
+			buf.WriteByte(0x00)
+		}
+		buf.Write([]byte{0x00, 0x00, 0x01})
+		buf.Write(n)
+	}
+	return buf.Bytes()
+}
+
+func TestSplitNALUnits(t *testing.T) {
+	want := [][]byte{vps, sps, pps, sei, idr, trail}
+	stream := annexB(want...)
+
+	// Add trailing_zero_8bits to the end of the stream.
+	stream = append(stream, 0x00, 0x00)
+
+	got := SplitNALUnits(stream)
+	if len(got) != len(want) {
+		t.Fatalf("did not get expected number of NAL units.\nGot: %d\nWant: %d\n", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("did not get expected NAL unit %d.\nGot: %#v\nWant: %#v\n", i, got[i], want[i])
+		}
+	}
+
+	if got := SplitNALUnits([]byte{0x01, 0x02, 0x03}); got != nil {
+		t.Errorf("did not expect NAL units from data with no start code, got: %v", got)
+	}
+}
+
+func TestParseNALHeader(t *testing.T) {
+	tests := []struct {
+		nalu  []byte
+		want  NALHeader
+		irap  bool
+		idr   bool
+		param bool
+	}{
+		{nalu: vps, want: NALHeader{Type: NALTypeVPS}, param: true},
+		{nalu: sps, want: NALHeader{Type: NALTypeSPS}, param: true},
+		{nalu: pps, want: NALHeader{Type: NALTypePPS}, param: true},
+		{nalu: sei, want: NALHeader{Type: NALTypePrefixSEI}},
+		{nalu: idr, want: NALHeader{Type: NALTypeIDRWRADL}, irap: true, idr: true},
+		{nalu: trail, want: NALHeader{Type: NALTypeTrailR}},
+		{nalu: []byte{0x2a, 0x01}, want: NALHeader{Type: NALTypeCRA}, irap: true},
+		{nalu: []byte{0x03, 0x0b}, want: NALHeader{Type: NALTypeTrailR, LayerID: 33, TemporalID: 2}},
+	}
+
+	for i, test := range tests {
+		got, err := ParseNALHeader(test.nalu)
+		if err != nil {
+			t.Errorf("did not expect error for test %d: %v", i, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("did not get expected header for test %d.\nGot: %v\nWant: %v\n", i, got, test.want)
+		}
+		if got.IsIRAP() != test.irap || got.IsIDR() != test.idr || got.IsParameterSet() != test.param {
+			t.Errorf("did not get expected classification for test %d: irap: %t, idr: %t, param: %t", i, got.IsIRAP(), got.IsIDR(), got.IsParameterSet())
+		}
+	}
+
+	errTests := []struct {
+		nalu []byte
+		want error
+	}{
+		{nalu: []byte{0x40}, want: ErrShortNAL},
+		{nalu: []byte{0xc0, 0x01}, want: ErrForbiddenBit},
+		{nalu: []byte{0x40, 0x00}, want: ErrZeroTemporalID1},
+	}
+	for i, test := range errTests {
+		_, err := ParseNALHeader(test.nalu)
+		if err != test.want {
+			t.Errorf("did not get expected error for test %d.\nGot: %v\nWant: %v\n", i, err, test.want)
+		}
+	}
+}
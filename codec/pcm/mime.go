@@ -0,0 +1,120 @@
+/*
+NAME
+  mime.go
+
+DESCRIPTION
+  mime.go provides functions for building and parsing the media type strings
+  used to describe PCM audio when it is sent, e.g.
+  audio/x-wav;codec=pcm;rate=48000;channels=1;bits=16.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package pcm
+
+import (
+	"fmt"
+	"mime"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// The media type and codec used for PCM audio.
+const (
+	MIMEMediaType = "audio/x-wav"
+	MIMECodec     = "pcm"
+)
+
+// Errors returned by MIMEType and ParseMIMEType.
+var (
+	ErrInvalidMIMEType = errors.New("invalid PCM media type")
+	ErrInvalidRate     = errors.New("invalid or no sample rate")
+	ErrInvalidChannels = errors.New("invalid or no number of channels")
+	ErrInvalidBits     = errors.New("invalid or unsupported bit depth")
+)
+
+// MIMEType returns the media type string describing PCM audio of format f, in
+// the form audio/x-wav;codec=pcm;rate=<rate>;channels=<channels>;bits=<bits>.
+func MIMEType(f BufferFormat) (string, error) {
+	bits, err := bitDepth(f.SFormat)
+	if err != nil {
+		return "", err
+	}
+	if f.Rate == 0 {
+		return "", ErrInvalidRate
+	}
+	if f.Channels == 0 {
+		return "", ErrInvalidChannels
+	}
+	return fmt.Sprintf("%s;codec=%s;rate=%d;channels=%d;bits=%d", MIMEMediaType, MIMECodec, f.Rate, f.Channels, bits), nil
+}
+
+// ParseMIMEType parses a media type string of the form given by MIMEType and
+// returns the BufferFormat it describes.
+func ParseMIMEType(s string) (BufferFormat, error) {
+	mt, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		return BufferFormat{}, errors.Wrap(ErrInvalidMIMEType, err.Error())
+	}
+	if mt != MIMEMediaType {
+		return BufferFormat{}, errors.Wrapf(ErrInvalidMIMEType, "unexpected media type %s", mt)
+	}
+	if params["codec"] != MIMECodec {
+		return BufferFormat{}, errors.Wrapf(ErrInvalidMIMEType, "unexpected codec %s", params["codec"])
+	}
+
+	rate, err := strconv.ParseUint(params["rate"], 10, 0)
+	if err != nil || rate == 0 {
+		return BufferFormat{}, ErrInvalidRate
+	}
+	channels, err := strconv.ParseUint(params["channels"], 10, 0)
+	if err != nil || channels == 0 {
+		return BufferFormat{}, ErrInvalidChannels
+	}
+	bits, err := strconv.Atoi(params["bits"])
+	if err != nil {
+		return BufferFormat{}, ErrInvalidBits
+	}
+	sf, err := sampleFormat(bits)
+	if err != nil {
+		return BufferFormat{}, err
+	}
+
+	return BufferFormat{SFormat: sf, Rate: uint(rate), Channels: uint(channels)}, nil
+}
+
+// bitDepth returns the number of bits per sample of the SampleFormat f. As
+// with WAV, 8 bit samples are unsigned, so S8 is not supported.
+func bitDepth(f SampleFormat) (int, error) {
+	switch f {
+	case S16_LE:
+		return 16, nil
+	case S32_LE:
+		return 32, nil
+	case U8:
+		return 8, nil
+	default:
+		return 0, ErrInvalidBits
+	}
+}
+
+// sampleFormat returns the SampleFormat with the given bits per sample.
+func sampleFormat(bits int) (SampleFormat, error) {
+	switch bits {
+	case 16:
+		return S16_LE, nil
+	case 32:
+		return S32_LE, nil
+	case 8:
+		return U8, nil
+	default:
+		return Unknown, ErrInvalidBits
+	}
+}
@@ -0,0 +1,108 @@
+/*
+NAME
+  mime_test.go
+
+DESCRIPTION
+  mime_test.go provides testing for functionality in mime.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package pcm
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMIMETypeRoundTrip(t *testing.T) {
+	tests := []struct {
+		format BufferFormat
+		want   string
+	}{
+		{
+			format: BufferFormat{SFormat: S16_LE, Rate: 48000, Channels: 1},
+			want:   "audio/x-wav;codec=pcm;rate=48000;channels=1;bits=16",
+		},
+		{
+			format: BufferFormat{SFormat: S32_LE, Rate: 8000, Channels: 2},
+			want:   "audio/x-wav;codec=pcm;rate=8000;channels=2;bits=32",
+		},
+		{
+			format: BufferFormat{SFormat: U8, Rate: 8000, Channels: 1},
+			want:   "audio/x-wav;codec=pcm;rate=8000;channels=1;bits=8",
+		},
+	}
+	for i, test := range tests {
+		got, err := MIMEType(test.format)
+		if err != nil {
+			t.Fatalf("did not expect error building media type for test %d: %v", i, err)
+		}
+		if got != test.want {
+			t.Errorf("did not get expected media type for test %d.\nGot: %s\nWant: %s\n", i, got, test.want)
+		}
+
+		f, err := ParseMIMEType(got)
+		if err != nil {
+			t.Fatalf("did not expect error parsing media type for test %d: %v", i, err)
+		}
+		if f != test.format {
+			t.Errorf("did not get expected format for test %d.\nGot: %v\nWant: %v\n", i, f, test.format)
+		}
+	}
+
+	// Whitespace and parameter order should not matter when parsing.
+	f, err := ParseMIMEType("audio/x-wav; bits=16; channels=1; rate=48000; codec=pcm")
+	if err != nil {
+		t.Fatalf("did not expect error parsing reordered media type: %v", err)
+	}
+	want := BufferFormat{SFormat: S16_LE, Rate: 48000, Channels: 1}
+	if f != want {
+		t.Errorf("did not get expected format for reordered media type.\nGot: %v\nWant: %v\n", f, want)
+	}
+}
+
+func TestMIMETypeErrors(t *testing.T) {
+	buildTests := []struct {
+		format BufferFormat
+		want   error
+	}{
+		{format: BufferFormat{SFormat: Unknown, Rate: 48000, Channels: 1}, want: ErrInvalidBits},
+		{format: BufferFormat{SFormat: S8, Rate: 48000, Channels: 1}, want: ErrInvalidBits},
+		{format: BufferFormat{SFormat: S16_LE, Rate: 0, Channels: 1}, want: ErrInvalidRate},
+		{format: BufferFormat{SFormat: S16_LE, Rate: 48000, Channels: 0}, want: ErrInvalidChannels},
+	}
+	for i, test := range buildTests {
+		_, err := MIMEType(test.format)
+		if err != test.want {
+			t.Errorf("did not get expected error building media type for test %d.\nGot: %v\nWant: %v\n", i, err, test.want)
+		}
+	}
+
+	parseTests := []struct {
+		in   string
+		want error
+	}{
+		{in: "", want: ErrInvalidMIMEType},
+		{in: "video/mp2t;codec=pcm;rate=48000;channels=1;bits=16", want: ErrInvalidMIMEType},
+		{in: "audio/x-wav;codec=adpcm;rate=48000;channels=1;bits=16", want: ErrInvalidMIMEType},
+		{in: "audio/x-wav;codec=pcm;channels=1;bits=16", want: ErrInvalidRate},
+		{in: "audio/x-wav;codec=pcm;rate=-1;channels=1;bits=16", want: ErrInvalidRate},
+		{in: "audio/x-wav;codec=pcm;rate=48000;channels=0;bits=16", want: ErrInvalidChannels},
+		{in: "audio/x-wav;codec=pcm;rate=48000;channels=1;bits=24", want: ErrInvalidBits},
+		{in: "audio/x-wav;codec=pcm;rate=48000;channels=1", want: ErrInvalidBits},
+	}
+	for _, test := range parseTests {
+		_, err := ParseMIMEType(test.in)
+		if errors.Cause(err) != test.want {
+			t.Errorf("did not get expected error parsing %q.\nGot: %v\nWant: %v\n", test.in, err, test.want)
+		}
+	}
+}
@@ -40,6 +40,10 @@ const (
 const (
 	S16_LE SampleFormat = iota
 	S32_LE
+	U8
+	S8
+	S16_BE
+	S32_BE
 	// There are many more:
 	// https://linux.die.net/man/1/arecord
 	// https://trac.ffmpeg.org/wiki/audio%20types
@@ -87,6 +91,8 @@ func Resample(c Buffer, rate uint) (Buffer, error) {
 		sampleLen = int(4 * c.Format.Channels)
 	case S16_LE:
 		sampleLen = int(2 * c.Format.Channels)
+	case U8, S8:
+		sampleLen = int(c.Format.Channels)
 	default:
 		return Buffer{}, fmt.Errorf("Unhandled ALSA format: %v", c.Format.SFormat)
 	}
@@ -116,6 +122,10 @@ func Resample(c Buffer, rate uint) (Buffer, error) {
 				sum += int(int32(binary.LittleEndian.Uint32(c.Data[(i*ratioFrom*sampleLen)+(j*sampleLen) : (i*ratioFrom*sampleLen)+((j+1)*sampleLen)])))
 			case S16_LE:
 				sum += int(int16(binary.LittleEndian.Uint16(c.Data[(i*ratioFrom*sampleLen)+(j*sampleLen) : (i*ratioFrom*sampleLen)+((j+1)*sampleLen)])))
+			case U8:
+				sum += int(c.Data[(i*ratioFrom*sampleLen)+(j*sampleLen)])
+			case S8:
+				sum += int(int8(c.Data[(i*ratioFrom*sampleLen)+(j*sampleLen)]))
 			}
 		}
 		avg := sum / ratioFrom
@@ -124,6 +134,8 @@ func Resample(c Buffer, rate uint) (Buffer, error) {
 			binary.LittleEndian.PutUint32(bAvg, uint32(avg))
 		case S16_LE:
 			binary.LittleEndian.PutUint16(bAvg, uint16(avg))
+		case U8, S8:
+			bAvg[0] = byte(avg)
 		}
 		resampled = append(resampled, bAvg...)
 	}
@@ -155,6 +167,8 @@ func StereoToMono(c Buffer) (Buffer, error) {
 		stereoSampleBytes = 8
 	case S16_LE:
 		stereoSampleBytes = 4
+	case U8, S8:
+		stereoSampleBytes = 2
 	default:
 		return Buffer{}, fmt.Errorf("Unhandled sample format %v", c.Format.SFormat)
 	}
@@ -183,6 +197,117 @@ func StereoToMono(c Buffer) (Buffer, error) {
 	}, nil
 }
 
+// ExtractChannel returns a mono Buffer containing only channel ch (starting
+// at 0) of the interleaved audio in c. Any trailing partial frame is ignored.
+func ExtractChannel(c Buffer, ch uint) (Buffer, error) {
+	if ch >= c.Format.Channels {
+		return Buffer{}, fmt.Errorf("channel %d out of range for audio with %d channels", ch, c.Format.Channels)
+	}
+	size := sampleSize(c.Format.SFormat)
+	if size == 0 {
+		return Buffer{}, fmt.Errorf("Unhandled sample format %v", c.Format.SFormat)
+	}
+
+	frame := size * int(c.Format.Channels)
+	n := len(c.Data) / frame
+	mono := make([]byte, n*size)
+	for i := 0; i < n; i++ {
+		off := i*frame + int(ch)*size
+		copy(mono[i*size:], c.Data[off:off+size])
+	}
+
+	return Buffer{
+		Format: BufferFormat{
+			Channels: 1,
+			SFormat:  c.Format.SFormat,
+			Rate:     c.Format.Rate,
+		},
+		Data: mono,
+	}, nil
+}
+
+// InterleaveMono returns a stereo Buffer with the mono audio of left and right
+// interleaved as its first and second channels. This is the inverse of
+// ExtractChannel. left and right must have the same format and length.
+func InterleaveMono(left, right Buffer) (Buffer, error) {
+	if left.Format.Channels != 1 || right.Format.Channels != 1 {
+		return Buffer{}, fmt.Errorf("audio must be mono, have %d and %d channels", left.Format.Channels, right.Format.Channels)
+	}
+	if left.Format != right.Format {
+		return Buffer{}, fmt.Errorf("audio formats do not match: %+v and %+v", left.Format, right.Format)
+	}
+	if len(left.Data) != len(right.Data) {
+		return Buffer{}, fmt.Errorf("audio lengths do not match: %d and %d bytes", len(left.Data), len(right.Data))
+	}
+	size := sampleSize(left.Format.SFormat)
+	if size == 0 {
+		return Buffer{}, fmt.Errorf("Unhandled sample format %v", left.Format.SFormat)
+	}
+	if len(left.Data)%size != 0 {
+		return Buffer{}, fmt.Errorf("audio length %d is not a whole number of samples", len(left.Data))
+	}
+
+	stereo := make([]byte, 2*len(left.Data))
+	for i := 0; i < len(left.Data); i += size {
+		copy(stereo[2*i:], left.Data[i:i+size])
+		copy(stereo[2*i+size:], right.Data[i:i+size])
+	}
+
+	return Buffer{
+		Format: BufferFormat{
+			Channels: 2,
+			SFormat:  left.Format.SFormat,
+			Rate:     left.Format.Rate,
+		},
+		Data: stereo,
+	}, nil
+}
+
+// ByteSwap returns the audio of Buffer c with the byte order of each sample
+// reversed, converting little endian audio to big endian and vice versa, e.g.
+// S16_BE to S16_LE. Other functions in this package expect little endian
+// audio, so big endian input should be converted with ByteSwap first. 8 bit
+// audio has no byte order and is returned unchanged.
+func ByteSwap(c Buffer) (Buffer, error) {
+	var (
+		size int
+		to   SampleFormat
+	)
+	switch c.Format.SFormat {
+	case U8, S8:
+		return c, nil
+	case S16_LE:
+		size, to = 2, S16_BE
+	case S16_BE:
+		size, to = 2, S16_LE
+	case S32_LE:
+		size, to = 4, S32_BE
+	case S32_BE:
+		size, to = 4, S32_LE
+	default:
+		return Buffer{}, fmt.Errorf("Unhandled sample format %v", c.Format.SFormat)
+	}
+	if len(c.Data)%size != 0 {
+		return Buffer{}, fmt.Errorf("audio length %d is not a whole number of samples", len(c.Data))
+	}
+
+	out := make([]byte, len(c.Data))
+	for i := 0; i < len(c.Data); i += size {
+		for j := 0; j < size; j++ {
+			out[i+j] = c.Data[i+size-1-j]
+		}
+	}
+
+	return Buffer{
+		Format: BufferFormat{
+			Channels: c.Format.Channels,
+			SFormat:  to,
+			Rate:     c.Format.Rate,
+		},
+		Data: out,
+	}, nil
+}
+
 // gcd is used for calculating the greatest common divisor of two positive integers, a and b.
 // assumes given a and b are positive.
 func gcd(a, b uint) uint {
@@ -199,6 +324,14 @@ func (f SampleFormat) String() string {
 		return "S16_LE"
 	case S32_LE:
 		return "S32_LE"
+	case U8:
+		return "U8"
+	case S8:
+		return "S8"
+	case S16_BE:
+		return "S16_BE"
+	case S32_BE:
+		return "S32_BE"
 	default:
 		return "Unknown"
 	}
@@ -211,7 +344,95 @@ func SFFromString(s string) (SampleFormat, error) {
 		return S16_LE, nil
 	case "S32_LE":
 		return S32_LE, nil
+	case "U8":
+		return U8, nil
+	case "S8":
+		return S8, nil
+	case "S16_BE":
+		return S16_BE, nil
+	case "S32_BE":
+		return S32_BE, nil
 	default:
 		return Unknown, errors.Errorf("unknown sample format (%s)", s)
 	}
 }
+
+// Convert returns the audio of Buffer c converted to the sample format f. Each
+// sample is scaled to the bit depth of f, and unsigned formats are offset so
+// that their midpoint maps to zero in signed formats, e.g. U8 128 maps to
+// S16_LE 0. Converting to a smaller bit depth truncates the least significant
+// bits.
+func Convert(c Buffer, f SampleFormat) (Buffer, error) {
+	if c.Format.SFormat == f {
+		return c, nil
+	}
+	from, to := sampleSize(c.Format.SFormat), sampleSize(f)
+	if from == 0 {
+		return Buffer{}, fmt.Errorf("Unhandled sample format %v", c.Format.SFormat)
+	}
+	if to == 0 {
+		return Buffer{}, fmt.Errorf("Unhandled sample format %v", f)
+	}
+
+	n := len(c.Data) / from
+	out := make([]byte, n*to)
+	for i := 0; i < n; i++ {
+		putSample(out[i*to:], f, sample(c.Data[i*from:], c.Format.SFormat))
+	}
+
+	return Buffer{
+		Format: BufferFormat{
+			Channels: c.Format.Channels,
+			SFormat:  f,
+			Rate:     c.Format.Rate,
+		},
+		Data: out,
+	}, nil
+}
+
+// sampleSize returns the number of bytes in a single sample of format f, or
+// 0 if f is not handled.
+func sampleSize(f SampleFormat) int {
+	switch f {
+	case U8, S8:
+		return 1
+	case S16_LE:
+		return 2
+	case S32_LE:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// sample returns the first sample of format f in b, scaled to the range of a
+// signed 32 bit integer.
+func sample(b []byte, f SampleFormat) int32 {
+	switch f {
+	case U8:
+		return int32(int(b[0])-128) << 24
+	case S8:
+		return int32(int8(b[0])) << 24
+	case S16_LE:
+		return int32(int16(binary.LittleEndian.Uint16(b))) << 16
+	case S32_LE:
+		return int32(binary.LittleEndian.Uint32(b))
+	default:
+		return 0
+	}
+}
+
+// putSample puts the sample s, scaled to the range of a signed 32 bit integer,
+// into b using format f.
+func putSample(b []byte, f SampleFormat, s int32) {
+	switch f {
+	case U8:
+		b[0] = byte((s >> 24) + 128)
+	case S8:
+		b[0] = byte(s >> 24)
+	case S16_LE:
+		binary.LittleEndian.PutUint16(b, uint16(s>>16))
+	case S32_LE:
+		binary.LittleEndian.PutUint32(b, uint32(s))
+	}
+}
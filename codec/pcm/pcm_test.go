@@ -22,6 +22,7 @@ package pcm
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io/ioutil"
 	"log"
 	"testing"
@@ -108,3 +109,212 @@ func TestStereoToMono(t *testing.T) {
 		t.Error("Converted data does not match expected result.")
 	}
 }
+
+// TestConvert checks that Convert correctly maps samples between 8 bit and
+// 16 bit formats.
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		from Buffer
+		to   SampleFormat
+		want []byte
+	}{
+		{
+			name: "U8 to S16_LE",
+			from: Buffer{Format: BufferFormat{SFormat: U8, Rate: 8000, Channels: 1}, Data: []byte{0, 64, 128, 192, 255}},
+			to:   S16_LE,
+			want: []byte{0x00, 0x80, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x40, 0x00, 0x7f},
+		},
+		{
+			name: "S16_LE to U8",
+			from: Buffer{Format: BufferFormat{SFormat: S16_LE, Rate: 8000, Channels: 1}, Data: []byte{0x00, 0x80, 0x00, 0x00, 0xff, 0x00, 0xff, 0xff, 0xff, 0x7f}},
+			to:   U8,
+			want: []byte{0, 128, 128, 127, 255},
+		},
+		{
+			name: "S8 to S16_LE",
+			from: Buffer{Format: BufferFormat{SFormat: S8, Rate: 8000, Channels: 1}, Data: []byte{0x80, 0x00, 0x7f}},
+			to:   S16_LE,
+			want: []byte{0x00, 0x80, 0x00, 0x00, 0x00, 0x7f},
+		},
+		{
+			name: "U8 to S8",
+			from: Buffer{Format: BufferFormat{SFormat: U8, Rate: 8000, Channels: 1}, Data: []byte{0, 128, 255}},
+			to:   S8,
+			want: []byte{0x80, 0x00, 0x7f},
+		},
+	}
+	for _, test := range tests {
+		got, err := Convert(test.from, test.to)
+		if err != nil {
+			t.Fatalf("did not expect error for test %q: %v", test.name, err)
+		}
+		if got.Format.SFormat != test.to || got.Format.Rate != test.from.Format.Rate || got.Format.Channels != test.from.Format.Channels {
+			t.Errorf("did not get expected format for test %q.\nGot: %v\n", test.name, got.Format)
+		}
+		if !bytes.Equal(got.Data, test.want) {
+			t.Errorf("did not get expected data for test %q.\nGot: %v\nWant: %v\n", test.name, got.Data, test.want)
+		}
+	}
+
+	// Converting U8 to S16_LE and back should be lossless.
+	u8 := make([]byte, 256)
+	for i := range u8 {
+		u8[i] = byte(i)
+	}
+	s16, err := Convert(Buffer{Format: BufferFormat{SFormat: U8, Rate: 8000, Channels: 1}, Data: u8}, S16_LE)
+	if err != nil {
+		t.Fatalf("did not expect error converting to S16_LE: %v", err)
+	}
+	back, err := Convert(s16, U8)
+	if err != nil {
+		t.Fatalf("did not expect error converting to U8: %v", err)
+	}
+	if !bytes.Equal(back.Data, u8) {
+		t.Errorf("U8 round trip did not give original data.\nGot: %v\nWant: %v\n", back.Data, u8)
+	}
+
+	_, err = Convert(Buffer{Format: BufferFormat{SFormat: Unknown}}, S16_LE)
+	if err == nil {
+		t.Errorf("expected error converting from unknown format")
+	}
+}
+
+// TestSFFromString checks that SFFromString and String round trip.
+func TestSFFromString(t *testing.T) {
+	for _, sf := range []SampleFormat{S16_LE, S32_LE, U8, S8, S16_BE, S32_BE} {
+		got, err := SFFromString(sf.String())
+		if err != nil {
+			t.Fatalf("did not expect error for %v: %v", sf, err)
+		}
+		if got != sf {
+			t.Errorf("did not get expected sample format.\nGot: %v\nWant: %v\n", got, sf)
+		}
+	}
+}
+
+// TestInterleaveMono checks that InterleaveMono interleaves generated mono
+// buffers and that ExtractChannel recovers them.
+func TestInterleaveMono(t *testing.T) {
+	for _, sf := range []SampleFormat{S16_LE, S32_LE, U8, S8} {
+		size := sampleSize(sf)
+		f := BufferFormat{SFormat: sf, Rate: 8000, Channels: 1}
+		left := Buffer{Format: f, Data: make([]byte, 10*size)}
+		right := Buffer{Format: f, Data: make([]byte, 10*size)}
+		for i := range left.Data {
+			left.Data[i] = byte(i)
+			right.Data[i] = byte(0x80 + i)
+		}
+
+		got, err := InterleaveMono(left, right)
+		if err != nil {
+			t.Fatalf("did not expect error for %v: %v", sf, err)
+		}
+		want := BufferFormat{SFormat: sf, Rate: 8000, Channels: 2}
+		if got.Format != want {
+			t.Errorf("did not get expected format for %v.\nGot: %+v\nWant: %+v\n", sf, got.Format, want)
+		}
+		for ch, b := range []Buffer{left, right} {
+			mono, err := ExtractChannel(got, uint(ch))
+			if err != nil {
+				t.Fatalf("did not expect error extracting channel %d for %v: %v", ch, sf, err)
+			}
+			if !bytes.Equal(mono.Data, b.Data) {
+				t.Errorf("did not get expected channel %d for %v.\nGot: %v\nWant: %v\n", ch, sf, mono.Data, b.Data)
+			}
+		}
+	}
+}
+
+// TestInterleaveMonoErrors checks that InterleaveMono rejects buffers that
+// are not mono or do not match.
+func TestInterleaveMonoErrors(t *testing.T) {
+	mono := Buffer{Format: BufferFormat{SFormat: S16_LE, Rate: 8000, Channels: 1}, Data: make([]byte, 8)}
+	with := func(f func(*Buffer)) Buffer {
+		b := mono
+		b.Data = append([]byte(nil), mono.Data...)
+		f(&b)
+		return b
+	}
+
+	tests := []struct {
+		name  string
+		right Buffer
+	}{
+		{name: "stereo", right: with(func(b *Buffer) { b.Format.Channels = 2 })},
+		{name: "rate", right: with(func(b *Buffer) { b.Format.Rate = 16000 })},
+		{name: "sample format", right: with(func(b *Buffer) { b.Format.SFormat = S32_LE })},
+		{name: "length", right: with(func(b *Buffer) { b.Data = b.Data[:6] })},
+	}
+	for _, test := range tests {
+		_, err := InterleaveMono(mono, test.right)
+		if err == nil {
+			t.Errorf("did not get expected error for test %q", test.name)
+		}
+	}
+
+	odd := with(func(b *Buffer) { b.Data = b.Data[:7] })
+	_, err := InterleaveMono(odd, odd)
+	if err == nil {
+		t.Errorf("did not get expected error for partial sample")
+	}
+}
+
+// TestByteSwap checks that ByteSwap reverses the byte order of samples and
+// that swapping twice gives the original audio.
+func TestByteSwap(t *testing.T) {
+	samples := []int32{0, 1, -1, 0x1234, -0x1234, 0x7fff, -0x8000}
+
+	tests := []struct {
+		from, to SampleFormat
+		put      func(b []byte, s int32) []byte
+		get      func(b []byte) int32
+	}{
+		{
+			from: S16_BE,
+			to:   S16_LE,
+			put:  func(b []byte, s int32) []byte { return binary.BigEndian.AppendUint16(b, uint16(s)) },
+			get:  func(b []byte) int32 { return int32(int16(binary.LittleEndian.Uint16(b))) },
+		},
+		{
+			from: S32_BE,
+			to:   S32_LE,
+			put:  func(b []byte, s int32) []byte { return binary.BigEndian.AppendUint32(b, uint32(s<<16)) },
+			get:  func(b []byte) int32 { return int32(binary.LittleEndian.Uint32(b)) >> 16 },
+		},
+	}
+	for _, test := range tests {
+		var data []byte
+		for _, s := range samples {
+			data = test.put(data, s)
+		}
+		in := Buffer{Format: BufferFormat{SFormat: test.from, Rate: 8000, Channels: 1}, Data: data}
+
+		got, err := ByteSwap(in)
+		if err != nil {
+			t.Fatalf("did not expect error swapping %v: %v", test.from, err)
+		}
+		if got.Format.SFormat != test.to {
+			t.Errorf("did not get expected sample format.\nGot: %v\nWant: %v\n", got.Format.SFormat, test.to)
+		}
+		size := len(data) / len(samples)
+		for i, want := range samples {
+			if s := test.get(got.Data[i*size:]); s != want {
+				t.Errorf("did not get expected sample %d for %v.\nGot: %d\nWant: %d\n", i, test.from, s, want)
+			}
+		}
+
+		back, err := ByteSwap(got)
+		if err != nil {
+			t.Fatalf("did not expect error swapping %v: %v", test.to, err)
+		}
+		if back.Format != in.Format || !bytes.Equal(back.Data, in.Data) {
+			t.Errorf("did not get original audio after swapping %v twice", test.from)
+		}
+	}
+
+	_, err := ByteSwap(Buffer{Format: BufferFormat{SFormat: S16_BE}, Data: make([]byte, 3)})
+	if err == nil {
+		t.Errorf("did not get expected error for partial sample")
+	}
+}
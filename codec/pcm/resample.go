@@ -0,0 +1,190 @@
+/*
+NAME
+  resample.go
+
+DESCRIPTION
+  resample.go provides resampling of PCM audio between arbitrary rates with a
+  selectable quality.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package pcm
+
+import (
+	"fmt"
+	"math"
+)
+
+// ResampleQuality selects the interpolation used by ResampleWith.
+type ResampleQuality int
+
+// Resample qualities, in order of increasing CPU cost. Linear is the default.
+const (
+	// Linear interpolates linearly between the two nearest input samples.
+	Linear ResampleQuality = iota
+
+	// Nearest takes the nearest input sample. It is the cheapest, but aliases
+	// badly.
+	Nearest
+
+	// Sinc uses a windowed-sinc filter which also low-pass filters the audio
+	// when downsampling. It is intended for offline, high quality conversions.
+	Sinc
+)
+
+// sincZeroCrossings is the number of zero crossings of the sinc function
+// either side of the centre tap used by the Sinc quality.
+const sincZeroCrossings = 16
+
+// String returns the string representation of a ResampleQuality.
+func (q ResampleQuality) String() string {
+	switch q {
+	case Linear:
+		return "linear"
+	case Nearest:
+		return "nearest"
+	case Sinc:
+		return "sinc"
+	default:
+		return "unknown"
+	}
+}
+
+// ResampleWith takes Buffer c and resamples the pcm audio data to 'rate' Hz
+// using the quality q, and returns a Buffer with the resampled data. Unlike
+// Resample, any ratio of rates is supported. Trailing bytes in c.Data that do
+// not make up a whole frame are ignored.
+func ResampleWith(c Buffer, rate uint, q ResampleQuality) (Buffer, error) {
+	if c.Format.Rate == rate {
+		return c, nil
+	}
+	if c.Format.Rate == 0 {
+		return Buffer{}, fmt.Errorf("Unable to convert from: %v Hz", c.Format.Rate)
+	}
+	if rate == 0 {
+		return Buffer{}, fmt.Errorf("Unable to convert to: %v Hz", rate)
+	}
+	size := sampleSize(c.Format.SFormat)
+	if size == 0 {
+		return Buffer{}, fmt.Errorf("Unhandled sample format %v", c.Format.SFormat)
+	}
+	if c.Format.Channels == 0 {
+		return Buffer{}, fmt.Errorf("Unable to resample audio with %v channels", c.Format.Channels)
+	}
+
+	var interp func(in []float64, x, step float64) float64
+	switch q {
+	case Linear:
+		interp = linear
+	case Nearest:
+		interp = nearest
+	case Sinc:
+		interp = sinc
+	default:
+		return Buffer{}, fmt.Errorf("Unhandled resample quality %v", q)
+	}
+
+	chans := int(c.Format.Channels)
+	frameLen := size * chans
+	inFrames := len(c.Data) / frameLen
+	outFrames := int(uint64(inFrames) * uint64(rate) / uint64(c.Format.Rate))
+	step := float64(c.Format.Rate) / float64(rate)
+
+	out := make([]byte, outFrames*frameLen)
+	in := make([]float64, inFrames)
+	for ch := 0; ch < chans; ch++ {
+		for i := range in {
+			in[i] = float64(sample(c.Data[i*frameLen+ch*size:], c.Format.SFormat))
+		}
+		for i := 0; i < outFrames; i++ {
+			v := math.Round(interp(in, float64(i)*step, step))
+			v = math.Max(math.MinInt32, math.Min(math.MaxInt32, v))
+			putSample(out[i*frameLen+ch*size:], c.Format.SFormat, int32(v))
+		}
+	}
+
+	return Buffer{
+		Format: BufferFormat{
+			Channels: c.Format.Channels,
+			SFormat:  c.Format.SFormat,
+			Rate:     rate,
+		},
+		Data: out,
+	}, nil
+}
+
+// nearest returns the sample of in nearest to position x.
+func nearest(in []float64, x, step float64) float64 {
+	i := int(math.Round(x))
+	if i >= len(in) {
+		i = len(in) - 1
+	}
+	return in[i]
+}
+
+// linear returns the value at position x by linear interpolation between the
+// samples of in either side of x.
+func linear(in []float64, x, step float64) float64 {
+	i := int(x)
+	if i+1 >= len(in) {
+		return in[len(in)-1]
+	}
+	frac := x - float64(i)
+	return in[i]*(1-frac) + in[i+1]*frac
+}
+
+// sinc returns the value at position x using a Blackman windowed-sinc filter.
+// When downsampling, i.e. step > 1, the cutoff of the filter is lowered to the
+// output Nyquist frequency to prevent aliasing. The taps are normalised so
+// that the filter has unity gain at DC, including near the ends of in.
+func sinc(in []float64, x, step float64) float64 {
+	fc := 1.0
+	if step > 1 {
+		fc = 1 / step
+	}
+	width := sincZeroCrossings / fc
+
+	lo := int(math.Ceil(x - width))
+	if lo < 0 {
+		lo = 0
+	}
+	hi := int(math.Floor(x + width))
+	if hi >= len(in) {
+		hi = len(in) - 1
+	}
+
+	var sum, wsum float64
+	for j := lo; j <= hi; j++ {
+		t := x - float64(j)
+		w := fc * normSinc(fc*t) * blackman(t/width)
+		sum += in[j] * w
+		wsum += w
+	}
+	if wsum == 0 {
+		return 0
+	}
+	return sum / wsum
+}
+
+// normSinc returns the normalised sinc function, sin(πx)/(πx).
+func normSinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman returns the Blackman window at x, where x is in [-1, 1].
+func blackman(x float64) float64 {
+	if x < -1 || x > 1 {
+		return 0
+	}
+	return 0.42 + 0.5*math.Cos(math.Pi*x) + 0.08*math.Cos(2*math.Pi*x)
+}
@@ -0,0 +1,136 @@
+/*
+NAME
+  resample_test.go
+
+DESCRIPTION
+  resample_test.go provides testing for functionality in resample.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package pcm
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// TestResampleWithResponse checks the frequency response of each resample
+// quality by resampling tones swept across the band from 44.1 kHz to 16 kHz,
+// and comparing the amplitude of the output with that of the input.
+func TestResampleWithResponse(t *testing.T) {
+	const (
+		inRate  = 44100
+		outRate = 16000
+		amp     = 0.5
+	)
+
+	tests := []struct {
+		freq float64
+
+		// Bounds for the gain of each quality, indexed by ResampleQuality.
+		min [3]float64
+		max [3]float64
+	}{
+		// Well within the passband, all qualities should preserve amplitude.
+		{freq: 500, min: [3]float64{0.95, 0.95, 0.95}, max: [3]float64{1.05, 1.05, 1.05}},
+		{freq: 2000, min: [3]float64{0.9, 0.95, 0.95}, max: [3]float64{1.05, 1.05, 1.05}},
+		{freq: 6000, min: [3]float64{0.9, 0.95, 0.95}, max: [3]float64{1.05, 1.05, 1.05}},
+
+		// Above the output Nyquist frequency, only sinc should remove the tone;
+		// the others alias it into the passband.
+		{freq: 10000, min: [3]float64{0.7, 0.95, 0}, max: [3]float64{1.05, 1.05, 0.01}},
+		{freq: 14000, min: [3]float64{0.6, 0.95, 0}, max: [3]float64{1.05, 1.05, 0.01}},
+	}
+
+	for _, test := range tests {
+		in := tone(test.freq, inRate, amp, inRate)
+		for _, q := range []ResampleQuality{Linear, Nearest, Sinc} {
+			out, err := ResampleWith(in, outRate, q)
+			if err != nil {
+				t.Fatalf("did not expect error resampling %v Hz with %v: %v", test.freq, q, err)
+			}
+			if out.Format.Rate != outRate {
+				t.Errorf("did not get expected rate.\nGot: %v\nWant: %v\n", out.Format.Rate, outRate)
+			}
+			if len(out.Data) != outRate*2 {
+				t.Errorf("did not get expected length.\nGot: %v\nWant: %v\n", len(out.Data), outRate*2)
+			}
+
+			gain := rms(out.Data) / (amp / math.Sqrt2)
+			if gain < test.min[q] || gain > test.max[q] {
+				t.Errorf("unexpected gain for %v Hz with %v quality.\nGot: %.3f\nWant: [%.3f, %.3f]\n", test.freq, q, gain, test.min[q], test.max[q])
+			}
+		}
+	}
+}
+
+// TestResampleWithUpsample checks that upsampling a tone preserves it.
+func TestResampleWithUpsample(t *testing.T) {
+	const amp = 0.5
+	in := tone(1000, 8000, amp, 8000)
+	for _, q := range []ResampleQuality{Linear, Nearest, Sinc} {
+		out, err := ResampleWith(in, 44100, q)
+		if err != nil {
+			t.Fatalf("did not expect error upsampling with %v: %v", q, err)
+		}
+		if len(out.Data) != 44100*2 {
+			t.Errorf("did not get expected length with %v.\nGot: %v\nWant: %v\n", q, len(out.Data), 44100*2)
+		}
+		gain := rms(out.Data) / (amp / math.Sqrt2)
+		if gain < 0.9 || gain > 1.1 {
+			t.Errorf("unexpected gain upsampling with %v quality: %.3f", q, gain)
+		}
+	}
+}
+
+func TestResampleWithErrors(t *testing.T) {
+	buf := Buffer{Format: BufferFormat{SFormat: S16_LE, Rate: 8000, Channels: 1}, Data: make([]byte, 100)}
+	for _, test := range []struct {
+		name string
+		buf  Buffer
+		rate uint
+		q    ResampleQuality
+	}{
+		{name: "zero rate", buf: buf, rate: 0, q: Linear},
+		{name: "bad quality", buf: buf, rate: 16000, q: ResampleQuality(-1)},
+		{name: "bad format", buf: Buffer{Format: BufferFormat{SFormat: Unknown, Rate: 8000, Channels: 1}}, rate: 16000, q: Linear},
+		{name: "no channels", buf: Buffer{Format: BufferFormat{SFormat: S16_LE, Rate: 8000}}, rate: 16000, q: Linear},
+	} {
+		_, err := ResampleWith(test.buf, test.rate, test.q)
+		if err == nil {
+			t.Errorf("expected error for test %q", test.name)
+		}
+	}
+}
+
+// tone returns a mono S16_LE Buffer of n samples of a sine of frequency freq
+// Hz and amplitude amp, relative to full scale, at the given rate.
+func tone(freq float64, rate uint, amp float64, n int) Buffer {
+	data := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		v := amp * math.MaxInt16 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(v)))
+	}
+	return Buffer{Format: BufferFormat{SFormat: S16_LE, Rate: rate, Channels: 1}, Data: data}
+}
+
+// rms returns the RMS level of the S16_LE samples in b, relative to full scale.
+// A margin at each end is excluded to avoid edge effects of the filters.
+func rms(b []byte) float64 {
+	const margin = 256
+	n := len(b)/2 - 2*margin
+	var sum float64
+	for i := margin; i < margin+n; i++ {
+		v := float64(int16(binary.LittleEndian.Uint16(b[i*2:]))) / math.MaxInt16
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
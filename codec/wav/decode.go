@@ -0,0 +1,96 @@
+/*
+NAME
+  decode.go
+
+DESCRIPTION
+  decode.go provides parsing of WAV files into their format metadata and
+  audio data.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Sizes relating to the RIFF structure of a WAV file.
+const (
+	riffHeaderSize  = 12 // "RIFF", file size and "WAVE".
+	chunkHeaderSize = 8  // Chunk ID and chunk size.
+	minFmtSize      = 16 // Size of the PCM fmt chunk body.
+)
+
+var (
+	errNotWAV    = fmt.Errorf("not a RIFF WAVE file")
+	errBadChunk  = fmt.Errorf("chunk extends past end of file")
+	errNoFmt     = fmt.Errorf("no fmt chunk before data chunk")
+	errShortFmt  = fmt.Errorf("fmt chunk too short")
+	errNoData    = fmt.Errorf("no data chunk")
+	errBadFormat = fmt.Errorf("invalid format in fmt chunk")
+)
+
+// Decode parses the WAV file b, returning its metadata and audio data. Chunks
+// other than fmt and data are skipped. The returned audio shares the
+// underlying array of b.
+func Decode(b []byte) (Metadata, []byte, error) {
+	if len(b) < riffHeaderSize || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return Metadata{}, nil, errNotWAV
+	}
+
+	var (
+		md     Metadata
+		gotFmt bool
+	)
+	for off := riffHeaderSize; off+chunkHeaderSize <= len(b); {
+		id := string(b[off : off+4])
+		size := int(binary.LittleEndian.Uint32(b[off+4 : off+8]))
+		body := b[off+chunkHeaderSize:]
+
+		switch id {
+		case "fmt ":
+			if size > len(body) {
+				return Metadata{}, nil, errBadChunk
+			}
+			if size < minFmtSize {
+				return Metadata{}, nil, errShortFmt
+			}
+			md = Metadata{
+				AudioFormat: int(binary.LittleEndian.Uint16(body[0:2])),
+				Channels:    int(binary.LittleEndian.Uint16(body[2:4])),
+				SampleRate:  int(binary.LittleEndian.Uint32(body[4:8])),
+				BitDepth:    int(binary.LittleEndian.Uint16(body[14:16])),
+			}
+			if md.Channels == 0 || md.SampleRate == 0 || md.BitDepth == 0 {
+				return Metadata{}, nil, errBadFormat
+			}
+			gotFmt = true
+		case "data":
+			if !gotFmt {
+				return Metadata{}, nil, errNoFmt
+			}
+			// Tolerate truncated files, which are common when recording is
+			// interrupted, by returning the audio that is present.
+			if size > len(body) {
+				size = len(body)
+			}
+			return md, body[:size], nil
+		default:
+			if size > len(body) {
+				return Metadata{}, nil, errBadChunk
+			}
+		}
+
+		// Chunks are padded to an even number of bytes.
+		off += chunkHeaderSize + size + size%2
+	}
+	return Metadata{}, nil, errNoData
+}
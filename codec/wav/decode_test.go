@@ -0,0 +1,87 @@
+/*
+NAME
+  decode_test.go
+
+DESCRIPTION
+  decode_test.go provides testing for functionality in decode.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package wav
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	md := Metadata{AudioFormat: PCMFormat, Channels: 2, SampleRate: 44100, BitDepth: 16}
+	audio := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+	w := &WAV{Metadata: md}
+	_, err := w.Write(audio)
+	if err != nil {
+		t.Fatalf("did not expect error writing WAV: %v", err)
+	}
+
+	// Insert a LIST chunk with odd size, and therefore a pad byte, between the
+	// fmt and data chunks.
+	list := []byte{'L', 'I', 'S', 'T', 0x03, 0x00, 0x00, 0x00, 'a', 'b', 'c', 0x00}
+	withList := append(append(append([]byte{}, w.Audio[:36]...), list...), w.Audio[36:]...)
+
+	for _, file := range [][]byte{w.Audio, withList} {
+		gotMD, gotAudio, err := Decode(file)
+		if err != nil {
+			t.Fatalf("did not expect error decoding WAV: %v", err)
+		}
+		if gotMD != md {
+			t.Errorf("did not get expected metadata.\nGot: %v\nWant: %v\n", gotMD, md)
+		}
+		if !bytes.Equal(gotAudio, audio) {
+			t.Errorf("did not get expected audio.\nGot: %v\nWant: %v\n", gotAudio, audio)
+		}
+	}
+
+	// A truncated data chunk should give the audio that is present.
+	_, gotAudio, err := Decode(w.Audio[:len(w.Audio)-2])
+	if err != nil {
+		t.Fatalf("did not expect error decoding truncated WAV: %v", err)
+	}
+	if !bytes.Equal(gotAudio, audio[:len(audio)-2]) {
+		t.Errorf("did not get expected truncated audio.\nGot: %v\nWant: %v\n", gotAudio, audio[:len(audio)-2])
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	w := &WAV{Metadata: Metadata{AudioFormat: PCMFormat, Channels: 1, SampleRate: 8000, BitDepth: 16}}
+	_, err := w.Write([]byte{0x00, 0x00})
+	if err != nil {
+		t.Fatalf("did not expect error writing WAV: %v", err)
+	}
+
+	noFmt := append(append([]byte{}, w.Audio[:12]...), w.Audio[36:]...)
+	tests := []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{name: "empty", in: nil, want: errNotWAV},
+		{name: "not wave", in: append([]byte("RIFF\x00\x00\x00\x00AVI "), w.Audio[12:]...), want: errNotWAV},
+		{name: "no data", in: w.Audio[:36], want: errNoData},
+		{name: "no fmt", in: noFmt, want: errNoFmt},
+		{name: "short fmt", in: append(append([]byte{}, w.Audio[:12]...), 'f', 'm', 't', ' ', 0x02, 0x00, 0x00, 0x00, 0x01, 0x00), want: errShortFmt},
+	}
+	for _, test := range tests {
+		_, _, err := Decode(test.in)
+		if err != test.want {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+NAME
+  reader.go
+
+DESCRIPTION
+  reader.go provides a Reader for reading the audio of a WAV file from a
+  given time offset.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package wav
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	errNegativeSeek = fmt.Errorf("cannot seek to negative offset")
+	errBlockAlign   = fmt.Errorf("bit depth and channels do not give whole byte samples")
+)
+
+// Reader reads the audio of a decoded WAV file. It implements io.Reader.
+type Reader struct {
+	md    Metadata
+	audio []byte
+	off   int
+}
+
+// NewReader returns a Reader for the audio of the WAV file b. The Reader
+// shares the underlying array of b.
+func NewReader(b []byte) (*Reader, error) {
+	md, audio, err := Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	if (md.BitDepth*md.Channels)%8 != 0 {
+		return nil, errBlockAlign
+	}
+	return &Reader{md: md, audio: audio}, nil
+}
+
+// Metadata returns the format of the audio being read.
+func (r *Reader) Metadata() Metadata { return r.md }
+
+// Read implements io.Reader, reading audio from the current position.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.off >= len(r.audio) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.audio[r.off:])
+	r.off += n
+	return n, nil
+}
+
+// Seek positions the Reader at the sample frame nearest to the time offset d
+// from the start of the audio, using the byte rate of the format. Seeking past
+// the end of the audio positions the Reader at the end, so that the next Read
+// returns io.EOF.
+func (r *Reader) Seek(d time.Duration) error {
+	if d < 0 {
+		return errNegativeSeek
+	}
+	blockAlign := r.md.BitDepth * r.md.Channels / 8
+	n := int64(len(r.audio) / blockAlign) // Sample frames in the audio.
+	rate := int64(r.md.SampleRate)
+
+	// Split d into whole seconds and a fraction, and check the whole seconds
+	// against the duration of the audio first, so that large offsets can't
+	// overflow.
+	secs, frac := int64(d/time.Second), int64(d%time.Second)
+	frames := n
+	if rate > 0 && secs <= n/rate {
+		frames = secs*rate + (frac*rate+int64(time.Second)/2)/int64(time.Second)
+	}
+	if frames > n {
+		frames = n
+	}
+	r.off = int(frames) * blockAlign
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+NAME
+  reader_test.go
+
+DESCRIPTION
+  reader_test.go provides testing for functionality in reader.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReaderSeek(t *testing.T) {
+	// Generate one second of 16 bit stereo audio at 1 kHz where each sample
+	// frame holds its own index in both channels.
+	const rate, nFrames = 1000, 1000
+	audio := make([]byte, nFrames*4)
+	for i := 0; i < nFrames; i++ {
+		binary.LittleEndian.PutUint16(audio[i*4:], uint16(i))
+		binary.LittleEndian.PutUint16(audio[i*4+2:], uint16(i))
+	}
+	w := &WAV{Metadata: Metadata{AudioFormat: PCMFormat, Channels: 2, SampleRate: rate, BitDepth: 16}}
+	_, err := w.Write(audio)
+	if err != nil {
+		t.Fatalf("did not expect error writing WAV: %v", err)
+	}
+
+	r, err := NewReader(w.Audio)
+	if err != nil {
+		t.Fatalf("did not expect error creating reader: %v", err)
+	}
+
+	tests := []struct {
+		seek  time.Duration
+		frame int
+	}{
+		{seek: 0, frame: 0},
+		{seek: 250 * time.Millisecond, frame: 250},
+		{seek: 250*time.Millisecond + 400*time.Microsecond, frame: 250},
+		{seek: 250*time.Millisecond + 600*time.Microsecond, frame: 251},
+		{seek: 999 * time.Millisecond, frame: 999},
+	}
+	buf := make([]byte, 4)
+	for _, test := range tests {
+		err := r.Seek(test.seek)
+		if err != nil {
+			t.Fatalf("did not expect error seeking to %v: %v", test.seek, err)
+		}
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			t.Fatalf("did not expect error reading after seek to %v: %v", test.seek, err)
+		}
+		want := audio[test.frame*4 : test.frame*4+4]
+		if !bytes.Equal(buf, want) {
+			t.Errorf("did not get expected samples after seek to %v.\nGot: %v\nWant: %v\n", test.seek, buf, want)
+		}
+	}
+
+	// Seeking past the end should give io.EOF on the next read.
+	for _, d := range []time.Duration{time.Second, time.Hour, 60 * time.Hour, math.MaxInt64} {
+		err = r.Seek(d)
+		if err != nil {
+			t.Fatalf("did not expect error seeking past end: %v", err)
+		}
+		n, err := r.Read(buf)
+		if n != 0 || err != io.EOF {
+			t.Errorf("did not get expected read past end.\nGot: %d, %v\nWant: 0, %v\n", n, err, io.EOF)
+		}
+	}
+
+	err = r.Seek(-time.Millisecond)
+	if err != errNegativeSeek {
+		t.Errorf("did not get expected error for negative seek.\nGot: %v\nWant: %v\n", err, errNegativeSeek)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+NAME
+  split.go
+
+DESCRIPTION
+  split.go provides functionality for splitting a stereo WAV file into a mono
+  WAV file for each channel.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package wav
+
+import (
+	"fmt"
+
+	"github.com/ausocean/av/codec/pcm"
+)
+
+var errNotStereo = fmt.Errorf("audio is not stereo")
+
+// SplitStereo splits the stereo PCM WAV file b into two mono WAV files
+// holding its left and right channels respectively.
+func SplitStereo(b []byte) (left, right []byte, err error) {
+	md, audio, err := Decode(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode WAV: %w", err)
+	}
+	if md.AudioFormat != PCMFormat {
+		return nil, nil, errInvalidFormat
+	}
+	if md.Channels != 2 {
+		return nil, nil, fmt.Errorf("%w: got %d channels", errNotStereo, md.Channels)
+	}
+
+	// As in WAV, 8 bit samples are unsigned.
+	var sf pcm.SampleFormat
+	switch md.BitDepth {
+	case 8:
+		sf = pcm.U8
+	case 16:
+		sf = pcm.S16_LE
+	case 32:
+		sf = pcm.S32_LE
+	default:
+		return nil, nil, errInvalidBitDepth
+	}
+	buf := pcm.Buffer{
+		Format: pcm.BufferFormat{SFormat: sf, Rate: uint(md.SampleRate), Channels: 2},
+		Data:   audio,
+	}
+
+	mono := md
+	mono.Channels = 1
+	var out [2][]byte
+	for ch := range out {
+		c, err := pcm.ExtractChannel(buf, uint(ch))
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not extract channel %d: %w", ch, err)
+		}
+		w := &WAV{Metadata: mono}
+		_, err = w.Write(c.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not write channel %d: %w", ch, err)
+		}
+		out[ch] = w.Audio
+	}
+	return out[0], out[1], nil
+}
@@ -0,0 +1,81 @@
+/*
+NAME
+  split_test.go
+
+DESCRIPTION
+  split_test.go provides testing for functionality in split.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestSplitStereo(t *testing.T) {
+	// Generate 16 bit stereo audio where the left channel counts up from 0
+	// and the right channel counts down from -1.
+	const rate, nFrames = 8000, 100
+	audio := make([]byte, nFrames*4)
+	wantLeft := make([]byte, nFrames*2)
+	wantRight := make([]byte, nFrames*2)
+	for i := 0; i < nFrames; i++ {
+		l, r := uint16(i), uint16(-1-i)
+		binary.LittleEndian.PutUint16(audio[i*4:], l)
+		binary.LittleEndian.PutUint16(audio[i*4+2:], r)
+		binary.LittleEndian.PutUint16(wantLeft[i*2:], l)
+		binary.LittleEndian.PutUint16(wantRight[i*2:], r)
+	}
+	stereo := &WAV{Metadata: Metadata{AudioFormat: PCMFormat, Channels: 2, SampleRate: rate, BitDepth: 16}}
+	_, err := stereo.Write(audio)
+	if err != nil {
+		t.Fatalf("did not expect error writing WAV: %v", err)
+	}
+
+	left, right, err := SplitStereo(stereo.Audio)
+	if err != nil {
+		t.Fatalf("did not expect error splitting WAV: %v", err)
+	}
+
+	wantMD := Metadata{AudioFormat: PCMFormat, Channels: 1, SampleRate: rate, BitDepth: 16}
+	for _, test := range []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{name: "left", got: left, want: wantLeft},
+		{name: "right", got: right, want: wantRight},
+	} {
+		md, got, err := Decode(test.got)
+		if err != nil {
+			t.Fatalf("did not expect error decoding %s channel: %v", test.name, err)
+		}
+		if md != wantMD {
+			t.Errorf("did not get expected metadata for %s channel.\nGot: %+v\nWant: %+v\n", test.name, md, wantMD)
+		}
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("did not get expected audio for %s channel.\nGot: %v\nWant: %v\n", test.name, got, test.want)
+		}
+	}
+
+	mono := &WAV{Metadata: wantMD}
+	_, err = mono.Write(wantLeft)
+	if err != nil {
+		t.Fatalf("did not expect error writing WAV: %v", err)
+	}
+	_, _, err = SplitStereo(mono.Audio)
+	if !errors.Is(err, errNotStereo) {
+		t.Errorf("did not get expected error for mono input.\nGot: %v\nWant: %v\n", err, errNotStereo)
+	}
+}
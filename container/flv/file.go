@@ -0,0 +1,197 @@
+/*
+NAME
+  file.go
+
+DESCRIPTION
+  file.go provides tag level reading and writing of FLV streams, as found in
+  .flv files and carried by RTMP.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package flv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ScriptTagType is the tag type of script data (e.g. onMetaData) tags.
+const ScriptTagType = 18
+
+// Sizes relating to the FLV file header.
+const (
+	sizeofFLVHeader = 9
+	audioFlag       = 0x04
+	videoFlag       = 0x01
+)
+
+// Errors returned when reading FLV.
+var (
+	ErrInvalidHeader   = errors.New("invalid FLV header")
+	ErrInvalidTag      = errors.New("invalid FLV tag")
+	ErrPrevTagSize     = errors.New("previous tag size does not match tag")
+	ErrUnknownTagType  = errors.New("unknown FLV tag type")
+	errTagDataTooLarge = errors.New("tag data too large")
+)
+
+// Header represents the FLV file header.
+type Header struct {
+	Audio bool // Audio tags are present.
+	Video bool // Video tags are present.
+}
+
+// Bytes returns the byte representation of the FLV header.
+func (h Header) Bytes() []byte {
+	b := []byte{'F', 'L', 'V', version, 0, 0, 0, 0, sizeofFLVHeader}
+	if h.Audio {
+		b[4] |= audioFlag
+	}
+	if h.Video {
+		b[4] |= videoFlag
+	}
+	return b
+}
+
+// Tag is a generic FLV tag. Data holds the tag body, i.e. for audio and video
+// tags it begins with the audio or video tag header byte(s).
+type Tag struct {
+	Type      uint8  // AudioTagType, VideoTagType or ScriptTagType.
+	Timestamp uint32 // Timestamp in milliseconds, including the extended byte.
+	Data      []byte // Tag body.
+}
+
+// Bytes returns the byte representation of the tag, including the trailing
+// previous tag size.
+func (t *Tag) Bytes() []byte {
+	b := make([]byte, sizeofFLVTagHeader+len(t.Data)+sizeofPrevTagSize)
+	b[0] = t.Type
+	orderPutUint24(b[1:4], uint32(len(t.Data)))
+	orderPutUint24(b[4:7], t.Timestamp)
+	b[7] = byte(t.Timestamp >> 24)
+	copy(b[sizeofFLVTagHeader:], t.Data)
+	order.PutUint32(b[len(b)-sizeofPrevTagSize:], uint32(sizeofFLVTagHeader+len(t.Data)))
+	return b
+}
+
+// ParseTagHeader parses the 11 byte tag header at the start of b, returning
+// the tag type, the size of the tag body and the timestamp in milliseconds.
+func ParseTagHeader(b []byte) (typ uint8, size, timestamp uint32, err error) {
+	if len(b) < sizeofFLVTagHeader {
+		return 0, 0, 0, ErrInvalidTag
+	}
+	typ = b[0]
+	switch typ {
+	case AudioTagType, VideoTagType, ScriptTagType:
+	default:
+		return 0, 0, 0, fmt.Errorf("%w: %d", ErrUnknownTagType, typ)
+	}
+	size = uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+	timestamp = uint32(b[7])<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	return typ, size, timestamp, nil
+}
+
+// Writer writes FLV tags to an io.Writer, writing the FLV header and the
+// initial previous tag size before the first tag.
+type Writer struct {
+	dst    io.Writer
+	header Header
+	begun  bool
+}
+
+// NewWriter returns a new Writer writing to dst. The header describes which
+// tag types will be written.
+func NewWriter(dst io.Writer, header Header) *Writer {
+	return &Writer{dst: dst, header: header}
+}
+
+// WriteTag writes the tag t, followed by its previous tag size.
+func (w *Writer) WriteTag(t *Tag) error {
+	if len(t.Data) > 0xffffff {
+		return errTagDataTooLarge
+	}
+	if !w.begun {
+		var zero [sizeofPrevTagSize]byte
+		_, err := w.dst.Write(append(w.header.Bytes(), zero[:]...))
+		if err != nil {
+			return fmt.Errorf("could not write FLV header: %w", err)
+		}
+		w.begun = true
+	}
+	_, err := w.dst.Write(t.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not write tag: %w", err)
+	}
+	return nil
+}
+
+// Reader reads FLV tags from an io.Reader.
+type Reader struct {
+	src    io.Reader
+	header Header
+	head   [sizeofFLVTagHeader]byte
+}
+
+// NewReader returns a new Reader reading from src. The FLV header and the
+// initial previous tag size are read and checked before returning.
+func NewReader(src io.Reader) (*Reader, error) {
+	var b [sizeofFLVHeader + sizeofPrevTagSize]byte
+	_, err := io.ReadFull(src, b[:])
+	if err != nil {
+		return nil, fmt.Errorf("could not read FLV header: %w", err)
+	}
+	if b[0] != 'F' || b[1] != 'L' || b[2] != 'V' || order.Uint32(b[5:9]) != sizeofFLVHeader {
+		return nil, ErrInvalidHeader
+	}
+	if order.Uint32(b[sizeofFLVHeader:]) != 0 {
+		return nil, ErrPrevTagSize
+	}
+	return &Reader{
+		src: src,
+		header: Header{
+			Audio: b[4]&audioFlag != 0,
+			Video: b[4]&videoFlag != 0,
+		},
+	}, nil
+}
+
+// Header returns the FLV header read by NewReader.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// ReadTag reads the next tag and checks the previous tag size that follows
+// it. io.EOF is returned if there are no more tags.
+func (r *Reader) ReadTag() (*Tag, error) {
+	_, err := io.ReadFull(r.src, r.head[:])
+	if err != nil {
+		// Pass on io.EOF unwrapped so that callers can detect end of stream.
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("could not read tag header: %w", err)
+	}
+
+	typ, size, ts, err := ParseTagHeader(r.head[:])
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, size+sizeofPrevTagSize)
+	_, err = io.ReadFull(r.src, b)
+	if err != nil {
+		return nil, fmt.Errorf("could not read tag body: %w", err)
+	}
+
+	if order.Uint32(b[size:]) != sizeofFLVTagHeader+size {
+		return nil, ErrPrevTagSize
+	}
+	return &Tag{Type: typ, Timestamp: ts, Data: b[:size]}, nil
+}
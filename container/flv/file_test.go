@@ -0,0 +1,119 @@
+/*
+NAME
+  file_test.go
+
+DESCRIPTION
+  file_test.go provides testing for functionality provided in file.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package flv
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// TestWriteReadTags checks that tags written using a Writer can be read back
+// unchanged using a Reader.
+func TestWriteReadTags(t *testing.T) {
+	tags := []*Tag{
+		{Type: ScriptTagType, Timestamp: 0, Data: []byte{0x02, 0x00, 0x0a, 'o', 'n', 'M', 'e', 't', 'a', 'D', 'a', 't', 'a'}},
+		{Type: VideoTagType, Timestamp: 0, Data: []byte{0x17, 0x00, 0x00, 0x00, 0x00, 0x01, 0x64}},
+		{Type: AudioTagType, Timestamp: 23, Data: []byte{0xaf, 0x01, 0x21, 0x10}},
+		{Type: VideoTagType, Timestamp: 40, Data: []byte{0x27, 0x01, 0x00, 0x00, 0x00, 0x41, 0x9a}},
+		{Type: VideoTagType, Timestamp: 0x01000028, Data: []byte{0x27, 0x01, 0x00, 0x00, 0x00}}, // Uses extended timestamp.
+	}
+	header := Header{Audio: true, Video: true}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, header)
+	for i, tag := range tags {
+		err := w.WriteTag(tag)
+		if err != nil {
+			t.Fatalf("did not expect error writing tag %d: %v", i, err)
+		}
+	}
+
+	// Check the file header and initial previous tag size.
+	wantHead := []byte{'F', 'L', 'V', 0x01, 0x05, 0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x00}
+	if got := buf.Bytes()[:len(wantHead)]; !bytes.Equal(got, wantHead) {
+		t.Errorf("did not get expected file header.\nGot: %v\nWant: %v\n", got, wantHead)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("did not expect error creating reader: %v", err)
+	}
+	if r.Header() != header {
+		t.Errorf("did not get expected header.\nGot: %v\nWant: %v\n", r.Header(), header)
+	}
+
+	var got []*Tag
+	for {
+		tag, err := r.ReadTag()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("did not expect error reading tag %d: %v", len(got), err)
+		}
+		got = append(got, tag)
+	}
+
+	if !reflect.DeepEqual(got, tags) {
+		t.Errorf("did not get expected tags.\nGot: %v\nWant: %v\n", got, tags)
+	}
+}
+
+// TestReadTagBadPrevTagSize checks that a Reader detects a previous tag size
+// that does not match the preceding tag.
+func TestReadTagBadPrevTagSize(t *testing.T) {
+	var buf bytes.Buffer
+	err := NewWriter(&buf, Header{Video: true}).WriteTag(&Tag{Type: VideoTagType, Data: []byte{0x17, 0x01}})
+	if err != nil {
+		t.Fatalf("did not expect error writing tag: %v", err)
+	}
+
+	// Corrupt the last byte of the previous tag size.
+	b := buf.Bytes()
+	b[len(b)-1]++
+
+	r, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("did not expect error creating reader: %v", err)
+	}
+	_, err = r.ReadTag()
+	if !errors.Is(err, ErrPrevTagSize) {
+		t.Errorf("did not get expected error.\nGot: %v\nWant: %v\n", err, ErrPrevTagSize)
+	}
+}
+
+// TestParseTagHeader checks that ParseTagHeader matches the fields written by
+// AudioTag.Bytes and rejects unknown tag types.
+func TestParseTagHeader(t *testing.T) {
+	b := NewAudioTag(0x12345678, AACAudioFormat, SoundRate44kHz, true, true, []byte{AACRaw, 0x01}).Bytes()
+	typ, size, ts, err := ParseTagHeader(b)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if typ != AudioTagType || size != 3 || ts != 0x12345678 {
+		t.Errorf("did not get expected fields.\nGot: type=%d size=%d ts=%#x\nWant: type=%d size=3 ts=0x12345678\n", typ, size, ts, AudioTagType)
+	}
+
+	b[0] = 0x07
+	_, _, _, err = ParseTagHeader(b)
+	if !errors.Is(err, ErrUnknownTagType) {
+		t.Errorf("did not get expected error.\nGot: %v\nWant: %v\n", err, ErrUnknownTagType)
+	}
+}
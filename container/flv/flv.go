@@ -44,6 +44,23 @@ const (
 	NoTimestampExtension = 0
 	AACAudioFormat       = 10
 	PCMAudioFormat       = 0
+	MP3AudioFormat       = 2
+	PCMLEAudioFormat     = 3
+)
+
+// Sound rates used in the audio tag header. Note that AAC audio must always use
+// SoundRate44kHz, regardless of the actual sample rate.
+const (
+	SoundRate5kHz  = 0 // 5.5 kHz.
+	SoundRate11kHz = 1
+	SoundRate22kHz = 2
+	SoundRate44kHz = 3
+)
+
+// AAC packet types, which form the first byte of AAC audio tag data.
+const (
+	AACSequenceHeader = 0
+	AACRaw            = 1
 )
 
 const (
@@ -120,13 +137,39 @@ func (t *AudioTag) Bytes() []byte {
 	orderPutUint24(b[1:4], t.DataSize)
 	orderPutUint24(b[4:7], t.Timestamp)
 	b[7] = t.TimestampExtended
-	b[11] = t.SoundFormat<<4 | t.SoundRate<<2 | btb(t.SoundSize)<<1 | btb(t.SoundType)
+	b[11] = t.Header()
 	copy(b[12:], t.Data)
 	order.PutUint32(b[len(b)-4:], t.PrevTagSize)
 
 	return b
 }
 
+// NewAudioTag returns an AudioTag holding data with the given timestamp (in
+// milliseconds) and audio header fields. size16 indicates 16 bit samples and
+// stereo indicates two channel audio. For AAC, the first byte of data must be
+// the AAC packet type, i.e. AACSequenceHeader or AACRaw. The data size and
+// previous tag size fields are computed from the length of data.
+func NewAudioTag(timestamp uint32, format, rate uint8, size16, stereo bool, data []byte) *AudioTag {
+	return &AudioTag{
+		TagType:           AudioTagType,
+		DataSize:          uint32(len(data)) + 1,
+		Timestamp:         timestamp & 0xffffff,
+		TimestampExtended: uint8(timestamp >> 24),
+		SoundFormat:       format,
+		SoundRate:         rate,
+		SoundSize:         size16,
+		SoundType:         stereo,
+		Data:              data,
+		PrevTagSize:       uint32(sizeofFLVTagHeader + len(data) + 1),
+	}
+}
+
+// Header returns the audio tag header byte, which packs the sound format,
+// rate, size and type fields.
+func (t *AudioTag) Header() byte {
+	return t.SoundFormat<<4 | (t.SoundRate&0x3)<<2 | btb(t.SoundSize)<<1 | btb(t.SoundType)
+}
+
 func btb(b bool) byte {
 	if b {
 		return 1
@@ -106,3 +106,80 @@ func TestAudioTagBytes(t *testing.T) {
 		}
 	}
 }
+
+// TestNewAudioTag checks that NewAudioTag produces audio tags with the correct
+// header byte and size fields for a few different audio codecs.
+func TestNewAudioTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   uint8
+		rate     uint8
+		size16   bool
+		stereo   bool
+		data     []byte
+		expected []byte
+	}{
+		{
+			name:   "AAC",
+			format: AACAudioFormat,
+			rate:   SoundRate44kHz,
+			size16: true,
+			stereo: true,
+			data:   []byte{AACRaw, 0x01, 0x02},
+			expected: []byte{
+				0x08,             // TagType.
+				0x00, 0x00, 0x04, // DataSize.
+				0x00, 0x00, 0x64, // Timestamp.
+				0x00,             // TimestampExtended.
+				0x00, 0x00, 0x00, // StreamID. (always 0)
+				0xaf,             // SoundFormat=1010,SoundRate=11,SoundSize=1,SoundType=1
+				0x01, 0x01, 0x02, // AudioData.
+				0x00, 0x00, 0x00, 0x0f, // previousTagSize.
+			},
+		},
+		{
+			name:   "MP3",
+			format: MP3AudioFormat,
+			rate:   SoundRate22kHz,
+			size16: true,
+			stereo: false,
+			data:   []byte{0x01, 0x02},
+			expected: []byte{
+				0x08,             // TagType.
+				0x00, 0x00, 0x03, // DataSize.
+				0x00, 0x00, 0x64, // Timestamp.
+				0x00,             // TimestampExtended.
+				0x00, 0x00, 0x00, // StreamID. (always 0)
+				0x2a,       // SoundFormat=0010,SoundRate=10,SoundSize=1,SoundType=0
+				0x01, 0x02, // AudioData.
+				0x00, 0x00, 0x00, 0x0e, // previousTagSize.
+			},
+		},
+		{
+			name:   "PCM",
+			format: PCMLEAudioFormat,
+			rate:   SoundRate11kHz,
+			size16: false,
+			stereo: false,
+			data:   []byte{0x01},
+			expected: []byte{
+				0x08,             // TagType.
+				0x00, 0x00, 0x02, // DataSize.
+				0x00, 0x00, 0x64, // Timestamp.
+				0x00,             // TimestampExtended.
+				0x00, 0x00, 0x00, // StreamID. (always 0)
+				0x34,                   // SoundFormat=0011,SoundRate=01,SoundSize=0,SoundType=0
+				0x01,                   // AudioData.
+				0x00, 0x00, 0x00, 0x0d, // previousTagSize.
+			},
+		},
+	}
+
+	const timestamp = 100
+	for _, test := range tests {
+		got := NewAudioTag(timestamp, test.format, test.rate, test.size16, test.stereo, test.data).Bytes()
+		if !bytes.Equal(got, test.expected) {
+			t.Errorf("did not get expected result for test: %s.\n Got: %v\n Want: %v\n", test.name, got, test.expected)
+		}
+	}
+}
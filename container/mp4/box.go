@@ -0,0 +1,164 @@
+/*
+NAME
+  box.go
+
+DESCRIPTION
+  box.go provides parsing of the ISO base media file format box structure
+  used by MP4.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mp4
+
+import (
+	"encoding/binary"
+)
+
+// box is an ISO base media file format box.
+type box struct {
+	typ  string // Four character box type.
+	off  int    // Offset of the start of the box in the file.
+	hdr  int    // Size of the box header.
+	body []byte // Contents of the box after its header.
+}
+
+// parseBoxes parses the sequence of boxes in b, which begins at offset off in
+// the file. A box size of zero extends the box to the end of b.
+func parseBoxes(b []byte, off int) ([]box, error) {
+	var boxes []box
+	for i := 0; i < len(b); {
+		if len(b)-i < 8 {
+			return nil, ErrInvalidBox
+		}
+		size := uint64(binary.BigEndian.Uint32(b[i:]))
+		typ := string(b[i+4 : i+8])
+		hdr := 8
+		switch size {
+		case 0:
+			size = uint64(len(b) - i)
+		case 1:
+			if len(b)-i < 16 {
+				return nil, ErrInvalidBox
+			}
+			size = binary.BigEndian.Uint64(b[i+8:])
+			hdr = 16
+		}
+		if size < uint64(hdr) || size > uint64(len(b)-i) {
+			return nil, ErrInvalidBox
+		}
+		boxes = append(boxes, box{typ: typ, off: off + i, hdr: hdr, body: b[i+hdr : i+int(size)]})
+		i += int(size)
+	}
+	return boxes, nil
+}
+
+// children returns the boxes contained in bx, which start skip bytes into
+// its body.
+func (bx box) children(skip int) ([]box, error) {
+	if skip > len(bx.body) {
+		return nil, ErrInvalidBox
+	}
+	return parseBoxes(bx.body[skip:], bx.off+bx.hdr+skip)
+}
+
+// find returns the box at the end of the given path of box types, starting
+// from boxes and taking the first box of each type. ok is false if no such
+// box exists.
+func find(boxes []box, path ...string) (bx box, ok bool) {
+	for i, typ := range path {
+		ok = false
+		for _, b := range boxes {
+			if b.typ == typ {
+				bx, ok = b, true
+				break
+			}
+		}
+		if !ok {
+			return box{}, false
+		}
+		if i == len(path)-1 {
+			break
+		}
+		var err error
+		boxes, err = bx.children(0)
+		if err != nil {
+			return box{}, false
+		}
+	}
+	return bx, ok
+}
+
+// reader is a big endian reader for box bodies. Reads past the end of the
+// data return zero and set err to ErrInvalidBox.
+type reader struct {
+	b   []byte
+	off int
+	err error
+}
+
+// next returns the next n bytes, or nil if there are fewer than n bytes.
+func (r *reader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.b)-r.off < n {
+		r.err = ErrInvalidBox
+		return nil
+	}
+	b := r.b[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) u8() uint8 {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) u16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *reader) u32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) u64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// count reads a 32 bit entry count, setting err to ErrInvalidBox if the
+// remaining bytes cannot hold that many entries of size bytes.
+func (r *reader) count(size int) int {
+	n := r.u32()
+	if r.err == nil && uint64(n)*uint64(size) > uint64(len(r.b)-r.off) {
+		r.err = ErrInvalidBox
+		return 0
+	}
+	return int(n)
+}
+
+// fullBox reads the version and flags of a full box.
+func (r *reader) fullBox() (version uint8, flags uint32) {
+	v := r.u32()
+	return uint8(v >> 24), v & 0xffffff
+}
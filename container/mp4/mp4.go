@@ -0,0 +1,539 @@
+/*
+NAME
+  mp4.go
+
+DESCRIPTION
+  mp4.go provides functionality for extracting H.264 access units from
+  progressive and fragmented MP4 files.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+// Package mp4 provides functionality for reading media from MP4 files.
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ausocean/av/codec/h264"
+	"github.com/ausocean/av/codec/h264/h264dec"
+)
+
+var (
+	ErrInvalidBox    = errors.New("invalid MP4 box")
+	ErrNoH264Track   = errors.New("no H.264 video track")
+	ErrInvalidAVCC   = errors.New("invalid avcC record")
+	ErrInvalidSample = errors.New("invalid sample")
+)
+
+// visualSampleEntrySize is the size of the fields of a VisualSampleEntry,
+// such as avc1, before its child boxes.
+const visualSampleEntrySize = 78
+
+// Flags of the track fragment header (tfhd) and track run (trun) boxes.
+const (
+	tfhdBaseDataOffset   = 0x01
+	tfhdSampleDescIndex  = 0x02
+	tfhdDefaultDuration  = 0x08
+	tfhdDefaultSize      = 0x10
+	trunDataOffset       = 0x01
+	trunFirstSampleFlags = 0x04
+	trunSampleDuration   = 0x100
+	trunSampleSize       = 0x200
+	trunSampleFlags      = 0x400
+	trunSampleCTO        = 0x800
+)
+
+// AccessUnit is an H.264 access unit read from an MP4 file.
+type AccessUnit struct {
+	Data []byte        // Access unit in Annex-B byte stream format.
+	PTS  time.Duration // Presentation time relative to the start of the track.
+	Key  bool          // True if the access unit contains an IDR slice.
+}
+
+// track holds the properties of an H.264 track needed to read its samples.
+type track struct {
+	id              uint32
+	timescale       uint32
+	lenSize         int    // Size of the NAL unit lengths in samples.
+	params          []byte // SPS and PPS in Annex-B byte stream format.
+	defaultDuration uint32 // From the trex box, for fragmented files.
+	defaultSize     uint32 // From the trex box, for fragmented files.
+}
+
+// sample describes the location and timing of a sample in the file.
+type sample struct {
+	off, size int
+	dts       uint64 // Decode time in track timescale units.
+	cto       int64  // Composition time offset in track timescale units.
+}
+
+// ExtractH264 returns the access units of the first H.264 video track in the
+// MP4 file b, in decode order. Both progressive files, with sample tables in
+// the moov box, and fragmented files, with samples described by moof boxes,
+// are supported. Each IDR access unit is preceded by the SPS and PPS from the
+// avcC record so that it can be decoded independently.
+func ExtractH264(b []byte) ([]AccessUnit, error) {
+	top, err := parseBoxes(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	moov, ok := find(top, "moov")
+	if !ok {
+		return nil, fmt.Errorf("%w: no moov box", ErrInvalidBox)
+	}
+	t, stbl, err := h264Track(moov)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := tableSamples(stbl, len(b))
+	if err != nil {
+		return nil, fmt.Errorf("could not read sample tables: %w", err)
+	}
+	var dts uint64
+	for _, bx := range top {
+		if bx.typ != "moof" {
+			continue
+		}
+		s, err := fragmentSamples(bx, t, &dts, len(b))
+		if err != nil {
+			return nil, fmt.Errorf("could not read fragment at %d: %w", bx.off, err)
+		}
+		samples = append(samples, s...)
+	}
+
+	aus := make([]AccessUnit, 0, len(samples))
+	for i, s := range samples {
+		if s.off < 0 || s.size < 0 || s.off+s.size > len(b) {
+			return nil, fmt.Errorf("%w: sample %d extends past end of file", ErrInvalidSample, i)
+		}
+		au, key, err := toAnnexB(b[s.off:s.off+s.size], t)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert sample %d: %w", i, err)
+		}
+		pts := int64(s.dts) + s.cto
+		aus = append(aus, AccessUnit{Data: au, PTS: ticksToDuration(pts, t.timescale), Key: key})
+	}
+	return aus, nil
+}
+
+// h264Track returns the first H.264 video track in moov and its sample table
+// box.
+func h264Track(moov box) (track, box, error) {
+	boxes, err := moov.children(0)
+	if err != nil {
+		return track{}, box{}, err
+	}
+	for _, trak := range boxes {
+		if trak.typ != "trak" {
+			continue
+		}
+		children, err := trak.children(0)
+		if err != nil {
+			return track{}, box{}, err
+		}
+
+		hdlr, ok := find(children, "mdia", "hdlr")
+		if !ok || len(hdlr.body) < 12 || string(hdlr.body[8:12]) != "vide" {
+			continue
+		}
+		stbl, ok := find(children, "mdia", "minf", "stbl")
+		if !ok {
+			continue
+		}
+		avcC, ok := findAVCC(stbl)
+		if !ok {
+			continue
+		}
+
+		var t track
+		t.lenSize, t.params, err = parseAVCC(avcC.body)
+		if err != nil {
+			return track{}, box{}, err
+		}
+
+		tkhd, ok := find(children, "tkhd")
+		if !ok {
+			return track{}, box{}, fmt.Errorf("%w: no tkhd box", ErrInvalidBox)
+		}
+		r := &reader{b: tkhd.body}
+		if v, _ := r.fullBox(); v == 1 {
+			r.next(16)
+		} else {
+			r.next(8)
+		}
+		t.id = r.u32()
+
+		mdhd, ok := find(children, "mdia", "mdhd")
+		if !ok {
+			return track{}, box{}, fmt.Errorf("%w: no mdhd box", ErrInvalidBox)
+		}
+		r = &reader{b: mdhd.body}
+		if v, _ := r.fullBox(); v == 1 {
+			r.next(16)
+		} else {
+			r.next(8)
+		}
+		t.timescale = r.u32()
+		if r.err != nil || t.timescale == 0 {
+			return track{}, box{}, fmt.Errorf("%w: invalid tkhd or mdhd", ErrInvalidBox)
+		}
+
+		// Fragmented files may give sample defaults in moov/mvex/trex.
+		if mvex, ok := find(boxes, "mvex"); ok {
+			exts, err := mvex.children(0)
+			if err != nil {
+				return track{}, box{}, err
+			}
+			for _, trex := range exts {
+				r := &reader{b: trex.body}
+				r.fullBox()
+				if trex.typ != "trex" || r.u32() != t.id {
+					continue
+				}
+				r.u32() // default_sample_description_index.
+				t.defaultDuration = r.u32()
+				t.defaultSize = r.u32()
+			}
+		}
+		return t, stbl, nil
+	}
+	return track{}, box{}, ErrNoH264Track
+}
+
+// findAVCC returns the avcC box of the avc1 or avc3 sample entry in stbl.
+func findAVCC(stbl box) (box, bool) {
+	boxes, err := stbl.children(0)
+	if err != nil {
+		return box{}, false
+	}
+	stsd, ok := find(boxes, "stsd")
+	if !ok {
+		return box{}, false
+	}
+	// Sample entries follow the version, flags and entry count.
+	entries, err := stsd.children(8)
+	if err != nil {
+		return box{}, false
+	}
+	for _, e := range entries {
+		if e.typ != "avc1" && e.typ != "avc3" {
+			continue
+		}
+		children, err := e.children(visualSampleEntrySize)
+		if err != nil {
+			return box{}, false
+		}
+		return find(children, "avcC")
+	}
+	return box{}, false
+}
+
+// parseAVCC parses the AVCDecoderConfigurationRecord b, returning the size of
+// the NAL unit lengths used in samples and the SPS and PPS NAL units in
+// Annex-B byte stream format.
+func parseAVCC(b []byte) (lenSize int, params []byte, err error) {
+	params, err = h264.AVCCExtradataToAnnexB(b)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %v", ErrInvalidAVCC, err)
+	}
+	// AVCCExtradataToAnnexB has checked that b holds the length size.
+	lenSize = int(b[4]&0x3) + 1
+	if lenSize == 3 {
+		return 0, nil, fmt.Errorf("%w: invalid NAL unit length size", ErrInvalidAVCC)
+	}
+	return lenSize, params, nil
+}
+
+// tableSamples returns the samples described by the sample table box stbl.
+// This is empty for fragmented files. As each sample occupies at least one
+// byte of the file, no more than max samples are accepted.
+func tableSamples(stbl box, max int) ([]sample, error) {
+	boxes, err := stbl.children(0)
+	if err != nil {
+		return nil, err
+	}
+	stsz, ok := find(boxes, "stsz")
+	if !ok {
+		return nil, nil
+	}
+
+	// Sample sizes.
+	r := &reader{b: stsz.body}
+	r.fullBox()
+	fixed := r.u32()
+	var n int
+	if fixed != 0 {
+		n = r.count(0)
+		if n > max {
+			r.err = ErrInvalidBox
+		}
+	} else {
+		n = r.count(4)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: stsz", ErrInvalidBox)
+	}
+	sizes := make([]int, n)
+	for i := range sizes {
+		if fixed != 0 {
+			sizes[i] = int(fixed)
+		} else {
+			sizes[i] = int(r.u32())
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: stsz", ErrInvalidBox)
+	}
+	if len(sizes) == 0 {
+		return nil, nil
+	}
+
+	// Chunk offsets.
+	var offsets []int
+	if co, ok := find(boxes, "stco"); ok {
+		r = &reader{b: co.body}
+		r.fullBox()
+		offsets = make([]int, r.count(4))
+		for i := range offsets {
+			offsets[i] = int(r.u32())
+		}
+	} else if co, ok := find(boxes, "co64"); ok {
+		r = &reader{b: co.body}
+		r.fullBox()
+		offsets = make([]int, r.count(8))
+		for i := range offsets {
+			offsets[i] = int(r.u64())
+		}
+	}
+	if r.err != nil || len(offsets) == 0 {
+		return nil, fmt.Errorf("%w: no valid chunk offsets", ErrInvalidBox)
+	}
+
+	// Samples per chunk, as runs starting at first_chunk.
+	stsc, ok := find(boxes, "stsc")
+	if !ok {
+		return nil, fmt.Errorf("%w: no stsc box", ErrInvalidBox)
+	}
+	r = &reader{b: stsc.body}
+	r.fullBox()
+	type run struct{ first, n int }
+	runs := make([]run, r.count(12))
+	for i := range runs {
+		runs[i] = run{first: int(r.u32()), n: int(r.u32())}
+		r.u32() // sample_description_index.
+	}
+	if r.err != nil || len(runs) == 0 {
+		return nil, fmt.Errorf("%w: stsc", ErrInvalidBox)
+	}
+
+	samples := make([]sample, 0, len(sizes))
+	for c, ri := 0, 0; c < len(offsets) && len(samples) < len(sizes); c++ {
+		for ri+1 < len(runs) && runs[ri+1].first <= c+1 {
+			ri++
+		}
+		off := offsets[c]
+		for i := 0; i < runs[ri].n && len(samples) < len(sizes); i++ {
+			size := sizes[len(samples)]
+			samples = append(samples, sample{off: off, size: size})
+			off += size
+		}
+	}
+	if len(samples) != len(sizes) {
+		return nil, fmt.Errorf("%w: chunks hold %d of %d samples", ErrInvalidBox, len(samples), len(sizes))
+	}
+
+	// Decode times.
+	stts, ok := find(boxes, "stts")
+	if !ok {
+		return nil, fmt.Errorf("%w: no stts box", ErrInvalidBox)
+	}
+	r = &reader{b: stts.body}
+	r.fullBox()
+	var dts uint64
+	i := 0
+	for n := r.count(8); n > 0 && r.err == nil; n-- {
+		count, delta := r.u32(), r.u32()
+		for ; count > 0 && i < len(samples); count-- {
+			samples[i].dts = dts
+			dts += uint64(delta)
+			i++
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: stts", ErrInvalidBox)
+	}
+
+	// Composition time offsets are optional.
+	if ctts, ok := find(boxes, "ctts"); ok {
+		r = &reader{b: ctts.body}
+		r.fullBox()
+		i := 0
+		for n := r.count(8); n > 0 && r.err == nil; n-- {
+			count, off := r.u32(), int64(int32(r.u32()))
+			for ; count > 0 && i < len(samples); count-- {
+				samples[i].cto = off
+				i++
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("%w: ctts", ErrInvalidBox)
+		}
+	}
+	return samples, nil
+}
+
+// fragmentSamples returns the samples of track t described by the movie
+// fragment box moof. dts holds the decode time following the previous
+// fragment and is updated to follow this fragment. As each sample occupies at
+// least one byte of the file, no more than max samples are accepted per track
+// run.
+func fragmentSamples(moof box, t track, dts *uint64, max int) ([]sample, error) {
+	trafs, err := moof.children(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []sample
+	for _, traf := range trafs {
+		if traf.typ != "traf" {
+			continue
+		}
+		boxes, err := traf.children(0)
+		if err != nil {
+			return nil, err
+		}
+		tfhd, ok := find(boxes, "tfhd")
+		if !ok {
+			return nil, fmt.Errorf("%w: no tfhd box", ErrInvalidBox)
+		}
+		r := &reader{b: tfhd.body}
+		_, flags := r.fullBox()
+		if r.u32() != t.id {
+			continue
+		}
+		base := moof.off
+		if flags&tfhdBaseDataOffset != 0 {
+			base = int(r.u64())
+		}
+		if flags&tfhdSampleDescIndex != 0 {
+			r.u32()
+		}
+		duration, size := t.defaultDuration, t.defaultSize
+		if flags&tfhdDefaultDuration != 0 {
+			duration = r.u32()
+		}
+		if flags&tfhdDefaultSize != 0 {
+			size = r.u32()
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("%w: tfhd", ErrInvalidBox)
+		}
+
+		if tfdt, ok := find(boxes, "tfdt"); ok {
+			r := &reader{b: tfdt.body}
+			if v, _ := r.fullBox(); v == 1 {
+				*dts = r.u64()
+			} else {
+				*dts = uint64(r.u32())
+			}
+			if r.err != nil {
+				return nil, fmt.Errorf("%w: tfdt", ErrInvalidBox)
+			}
+		}
+
+		off := base
+		for _, trun := range boxes {
+			if trun.typ != "trun" {
+				continue
+			}
+			r := &reader{b: trun.body}
+			_, flags := r.fullBox()
+			n := r.u32()
+			if flags&trunDataOffset != 0 {
+				off = base + int(int32(r.u32()))
+			}
+			if flags&trunFirstSampleFlags != 0 {
+				r.u32()
+			}
+			var entrySize int
+			for _, f := range []uint32{trunSampleDuration, trunSampleSize, trunSampleFlags, trunSampleCTO} {
+				if flags&f != 0 {
+					entrySize += 4
+				}
+			}
+			if uint64(n) > uint64(max) || uint64(n)*uint64(entrySize) > uint64(len(r.b)-r.off) {
+				return nil, fmt.Errorf("%w: trun sample count %d", ErrInvalidBox, n)
+			}
+			for ; n > 0 && r.err == nil; n-- {
+				s := sample{off: off, size: int(size), dts: *dts}
+				d := duration
+				if flags&trunSampleDuration != 0 {
+					d = r.u32()
+				}
+				if flags&trunSampleSize != 0 {
+					s.size = int(r.u32())
+				}
+				if flags&trunSampleFlags != 0 {
+					r.u32()
+				}
+				if flags&trunSampleCTO != 0 {
+					s.cto = int64(int32(r.u32()))
+				}
+				samples = append(samples, s)
+				off += s.size
+				*dts += uint64(d)
+			}
+			if r.err != nil {
+				return nil, fmt.Errorf("%w: trun", ErrInvalidBox)
+			}
+		}
+	}
+	return samples, nil
+}
+
+// toAnnexB converts the sample b, made of length prefixed NAL units, to an
+// Annex-B byte stream, prefixed by the parameter sets of t if the sample
+// contains an IDR slice.
+func toAnnexB(b []byte, t track) (au []byte, key bool, err error) {
+	var nals []byte
+	for len(b) != 0 {
+		if len(b) < t.lenSize {
+			return nil, false, fmt.Errorf("%w: truncated NAL unit length", ErrInvalidSample)
+		}
+		var n int
+		for _, c := range b[:t.lenSize] {
+			n = n<<8 | int(c)
+		}
+		b = b[t.lenSize:]
+		if n == 0 || n > len(b) {
+			return nil, false, fmt.Errorf("%w: invalid NAL unit length %d", ErrInvalidSample, n)
+		}
+		if b[0]&0x1f == h264dec.NALTypeIDR {
+			key = true
+		}
+		nals = append(nals, 0x00, 0x00, 0x00, 0x01)
+		nals = append(nals, b[:n]...)
+		b = b[n:]
+	}
+	if key {
+		au = append(au, t.params...)
+	}
+	return append(au, nals...), key, nil
+}
+
+// ticksToDuration returns the duration of n ticks of a clock with the given
+// rate in Hz, avoiding overflow for large n.
+func ticksToDuration(n int64, rate uint32) time.Duration {
+	r := int64(rate)
+	return time.Duration(n/r)*time.Second + time.Duration(n%r)*time.Second/time.Duration(r)
+}
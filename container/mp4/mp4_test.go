@@ -0,0 +1,248 @@
+/*
+NAME
+  mp4_test.go
+
+DESCRIPTION
+  mp4_test.go provides testing for functionality in mp4.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	testTimescale = 90000
+	testDuration  = 3000 // Sample duration, i.e. 30 fps.
+)
+
+var (
+	testSPS = []byte{0x67, 0x42, 0xc0, 0x1e, 0xd9, 0x00}
+	testPPS = []byte{0x68, 0xce, 0x3c, 0x80}
+)
+
+// mkbox returns a box of the given type containing the concatenated parts.
+func mkbox(typ string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	b := be32(uint32(8 + len(body)))
+	b = append(b, typ...)
+	return append(b, body...)
+}
+
+func be16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+func be64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+// testFrame describes a sample used to generate a test MP4.
+type testFrame struct {
+	idr bool
+	cto uint32
+}
+
+// sampleData returns the sample for frame i, with NAL unit lengths of
+// lenSize bytes.
+func sampleData(i int, f testFrame, lenSize int) []byte {
+	nal := []byte{0x41, 0x9a, byte(i)}
+	if f.idr {
+		nal = []byte{0x65, 0x88, byte(i), 0x84}
+	}
+	nal = append(nal, bytes.Repeat([]byte{0xaa}, i)...)
+	b := be32(uint32(len(nal)))[4-lenSize:]
+	return append(b, nal...)
+}
+
+// moov returns a moov box describing an H.264 track. stbl holds the sample
+// tables and mvex is appended for fragmented files.
+func moov(lenSize int, stbl, mvex []byte) []byte {
+	avcC := []byte{1, 0x42, 0xc0, 0x1e, 0xfc | byte(lenSize-1), 0xe1}
+	avcC = append(append(avcC, be16(uint16(len(testSPS)))...), testSPS...)
+	avcC = append(append(append(avcC, 1), be16(uint16(len(testPPS)))...), testPPS...)
+	avc1 := mkbox("avc1", make([]byte, visualSampleEntrySize), mkbox("avcC", avcC))
+	stsd := mkbox("stsd", be32(0), be32(1), avc1)
+
+	tkhd := mkbox("tkhd", be32(0), be32(0), be32(0), be32(1), make([]byte, 68))
+	mdhd := mkbox("mdhd", be32(0), be32(0), be32(0), be32(testTimescale), be32(0), be32(0))
+	hdlr := mkbox("hdlr", be32(0), be32(0), []byte("vide"), make([]byte, 13))
+	audio := mkbox("trak", mkbox("mdia", mkbox("hdlr", be32(0), be32(0), []byte("soun"), make([]byte, 13))))
+	trak := mkbox("trak", tkhd, mkbox("mdia", mdhd, hdlr, mkbox("minf", mkbox("stbl", stsd, stbl))))
+	return mkbox("moov", audio, trak, mvex)
+}
+
+// fragmented returns a fragmented MP4 of the given frames, with fragments of
+// perFrag samples.
+func fragmented(frames []testFrame, perFrag int) []byte {
+	emptyTables := bytes.Join([][]byte{
+		mkbox("stts", be32(0), be32(0)),
+		mkbox("stsc", be32(0), be32(0)),
+		mkbox("stsz", be32(0), be32(0), be32(0)),
+		mkbox("stco", be32(0), be32(0)),
+	}, nil)
+	mvex := mkbox("mvex", mkbox("trex", be32(0), be32(1), be32(1), be32(testDuration), be32(0), be32(0)))
+	out := append(mkbox("ftyp", []byte("iso5"), be32(0)), moov(4, emptyTables, mvex)...)
+
+	for start := 0; start < len(frames); start += perFrag {
+		end := start + perFrag
+		if end > len(frames) {
+			end = len(frames)
+		}
+		var mdat, entries []byte
+		for i := start; i < end; i++ {
+			s := sampleData(i, frames[i], 4)
+			mdat = append(mdat, s...)
+			entries = append(entries, be32(uint32(len(s)))...)
+			entries = append(entries, be32(frames[i].cto)...)
+		}
+
+		// The data offset depends on the size of the moof, so build it twice.
+		build := func(dataOff uint32) []byte {
+			const flags = trunDataOffset | trunSampleSize | trunSampleCTO
+			return mkbox("moof",
+				mkbox("mfhd", be32(0), be32(uint32(start/perFrag+1))),
+				mkbox("traf",
+					mkbox("tfhd", be32(0x020000), be32(1)),
+					mkbox("tfdt", be32(1<<24), be64(uint64(start*testDuration))),
+					mkbox("trun", be32(flags), be32(uint32(end-start)), be32(dataOff), entries),
+				),
+			)
+		}
+		moof := build(0)
+		moof = build(uint32(len(moof) + 8))
+		out = append(out, moof...)
+		out = append(out, mkbox("mdat", mdat)...)
+	}
+	return out
+}
+
+// progressive returns a progressive MP4 of the given frames, with two samples
+// per chunk and NAL unit lengths of lenSize bytes.
+func progressive(frames []testFrame, lenSize int) []byte {
+	ftyp := mkbox("ftyp", []byte("isom"), be32(0))
+	var mdat, sizes, ctts, offsets []byte
+	mdatOff := len(ftyp) + 8
+	for i, f := range frames {
+		s := sampleData(i, f, lenSize)
+		if i%2 == 0 {
+			offsets = append(offsets, be32(uint32(mdatOff+len(mdat)))...)
+		}
+		mdat = append(mdat, s...)
+		sizes = append(sizes, be32(uint32(len(s)))...)
+		ctts = append(ctts, be32(1)...)
+		ctts = append(ctts, be32(f.cto)...)
+	}
+	stbl := bytes.Join([][]byte{
+		mkbox("stts", be32(0), be32(1), be32(uint32(len(frames))), be32(testDuration)),
+		mkbox("ctts", be32(0), be32(uint32(len(frames))), ctts),
+		mkbox("stsc", be32(0), be32(1), be32(1), be32(2), be32(1)),
+		mkbox("stsz", be32(0), be32(0), be32(uint32(len(frames))), sizes),
+		mkbox("stco", be32(0), be32(uint32(len(offsets)/4)), offsets),
+	}, nil)
+	return bytes.Join([][]byte{ftyp, mkbox("mdat", mdat), moov(lenSize, stbl, nil)}, nil)
+}
+
+func TestExtractH264(t *testing.T) {
+	// A GOP of I P B B P, with composition offsets giving display order.
+	gop := []testFrame{
+		{idr: true, cto: 1 * testDuration},
+		{cto: 4 * testDuration},
+		{cto: 1 * testDuration},
+		{cto: 1 * testDuration},
+		{cto: 2 * testDuration},
+	}
+	frames := append(append([]testFrame{}, gop...), gop...)
+
+	params := bytes.Join([][]byte{{0, 0, 0, 1}, testSPS, {0, 0, 0, 1}, testPPS}, nil)
+
+	tests := []struct {
+		name string
+		mp4  []byte
+	}{
+		{name: "fragmented", mp4: fragmented(frames, 3)},
+		{name: "progressive 4 byte lengths", mp4: progressive(frames, 4)},
+		{name: "progressive 2 byte lengths", mp4: progressive(frames, 2)},
+	}
+	for _, test := range tests {
+		got, err := ExtractH264(test.mp4)
+		if err != nil {
+			t.Fatalf("did not expect error for test %q: %v", test.name, err)
+		}
+		if len(got) != len(frames) {
+			t.Fatalf("did not get expected number of access units for test %q.\nGot: %d\nWant: %d\n", test.name, len(got), len(frames))
+		}
+		for i, f := range frames {
+			s := sampleData(i, f, 4)
+			want := append([]byte{0, 0, 0, 1}, s[4:]...)
+			if f.idr {
+				want = append(append([]byte{}, params...), want...)
+			}
+			if !bytes.Equal(got[i].Data, want) {
+				t.Errorf("did not get expected data for test %q access unit %d.\nGot: %x\nWant: %x\n", test.name, i, got[i].Data, want)
+			}
+			wantPTS := time.Duration(i*testDuration+int(f.cto)) * time.Second / testTimescale
+			if got[i].PTS != wantPTS {
+				t.Errorf("did not get expected PTS for test %q access unit %d.\nGot: %v\nWant: %v\n", test.name, i, got[i].PTS, wantPTS)
+			}
+			if got[i].Key != f.idr {
+				t.Errorf("did not get expected key for test %q access unit %d.\nGot: %v\nWant: %v\n", test.name, i, got[i].Key, f.idr)
+			}
+		}
+	}
+}
+
+func TestExtractH264Errors(t *testing.T) {
+	frames := []testFrame{{idr: true}, {}}
+	valid := fragmented(frames, 2)
+
+	// Tables with entry counts far beyond what their boxes hold.
+	const huge = 0xffffffff
+	tables := func(stsz, stco, stsc []byte) []byte {
+		return moov(4, bytes.Join([][]byte{
+			mkbox("stts", be32(0), be32(0)),
+			mkbox("stsc", be32(0), stsc),
+			mkbox("stsz", be32(0), stsz),
+			mkbox("stco", be32(0), stco),
+		}, nil), nil)
+	}
+	hugeStsz := tables(append(be32(0), be32(huge)...), be32(0), be32(0))
+	hugeFixedStsz := tables(append(be32(1), be32(huge)...), be32(0), be32(0))
+	hugeStco := tables(append(be32(1), be32(1)...), be32(huge), be32(0))
+	hugeStsc := tables(append(be32(1), be32(1)...), append(be32(1), be32(0)...), be32(huge))
+	mvex := mkbox("mvex", mkbox("trex", be32(0), be32(1), be32(1), be32(testDuration), be32(1), be32(0)))
+	hugeTrun := append(moov(4, nil, mvex), mkbox("moof", mkbox("traf",
+		mkbox("tfhd", be32(0), be32(1)),
+		mkbox("trun", be32(0), be32(huge)),
+	))...)
+
+	tests := []struct {
+		name string
+		mp4  []byte
+		want error
+	}{
+		{name: "truncated box", mp4: valid[:len(valid)-1], want: ErrInvalidBox},
+		{name: "no moov", mp4: mkbox("ftyp", []byte("isom")), want: ErrInvalidBox},
+		{name: "no video", mp4: mkbox("moov", mkbox("trak", mkbox("mdia"))), want: ErrNoH264Track},
+		{name: "huge stsz count", mp4: hugeStsz, want: ErrInvalidBox},
+		{name: "huge fixed size stsz count", mp4: hugeFixedStsz, want: ErrInvalidBox},
+		{name: "huge stco count", mp4: hugeStco, want: ErrInvalidBox},
+		{name: "huge stsc count", mp4: hugeStsc, want: ErrInvalidBox},
+		{name: "huge trun count", mp4: hugeTrun, want: ErrInvalidBox},
+	}
+	for _, test := range tests {
+		_, err := ExtractH264(test.mp4)
+		if !errors.Is(err, test.want) {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, test.want)
+		}
+	}
+}
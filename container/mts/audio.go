@@ -0,0 +1,157 @@
+/*
+NAME
+  audio.go
+
+DESCRIPTION
+  audio.go provides an AudioEncoder for packetising a stream of PCM audio
+  into MPEG-TS as either PCM or ADPCM.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ausocean/av/codec/adpcm"
+	"github.com/ausocean/av/codec/pcm"
+	"github.com/ausocean/utils/logging"
+)
+
+// Used to consistently read and write audio MTS metadata entries.
+const (
+	SampleRateKey = "sampleRate"
+	ChannelsKey   = "channels"
+	BitDepthKey   = "bitDepth"
+)
+
+// Errors used by NewAudioEncoder and AudioEncoder.Write.
+var (
+	ErrInvalidFormat  = errors.New("invalid audio format")
+	ErrFormatMismatch = errors.New("audio format does not match encoder")
+	ErrADPCMFormat    = errors.New("ADPCM requires 16 bit mono audio")
+)
+
+// AudioEncoder packetises a stream of PCM audio into MPEG-TS, optionally
+// compressing it to ADPCM. Each call to Write produces one PES packet, with
+// a PTS derived from the number of samples written before it and the sample
+// rate. The sample rate, channels and bit depth are added to the metadata, and
+// the write rate is updated to match the size of the blocks written.
+type AudioEncoder struct {
+	e           *Encoder
+	format      pcm.BufferFormat
+	codec       int
+	frameSize   int    // Size of one sample frame in bytes.
+	samples     uint64 // Number of sample frames written.
+	blockFrames uint64 // Number of sample frames in the last block written.
+}
+
+// NewAudioEncoder returns an AudioEncoder that writes audio of format f to
+// dst, encoded with codec, which must be EncodePCM or EncodeADPCM. PSI are
+// written every psiSendCount packets unless the given options select
+// otherwise. ADPCM requires 16 bit mono audio.
+func NewAudioEncoder(dst io.WriteCloser, f pcm.BufferFormat, codec int, log logging.Logger, options ...func(*Encoder) error) (*AudioEncoder, error) {
+	var bits int
+	switch f.SFormat {
+	case pcm.U8, pcm.S8:
+		bits = 8
+	case pcm.S16_LE:
+		bits = 16
+	case pcm.S32_LE:
+		bits = 32
+	default:
+		return nil, fmt.Errorf("%w: unhandled sample format %v", ErrInvalidFormat, f.SFormat)
+	}
+	if f.Rate == 0 || f.Channels == 0 {
+		return nil, fmt.Errorf("%w: rate %d, channels %d", ErrInvalidFormat, f.Rate, f.Channels)
+	}
+
+	switch codec {
+	case EncodePCM:
+	case EncodeADPCM:
+		if f.SFormat != pcm.S16_LE || f.Channels != 1 {
+			return nil, ErrADPCMFormat
+		}
+	default:
+		return nil, ErrUnsupportedMedia
+	}
+
+	options = append([]func(*Encoder) error{PacketBasedPSI(psiSendCount), MediaType(codec)}, options...)
+	e, err := NewEncoder(dst, log, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	Meta.Add(SampleRateKey, strconv.Itoa(int(f.Rate)))
+	Meta.Add(ChannelsKey, strconv.Itoa(int(f.Channels)))
+	Meta.Add(BitDepthKey, strconv.Itoa(bits))
+
+	return &AudioEncoder{
+		e:         e,
+		format:    f,
+		codec:     codec,
+		frameSize: bits / 8 * int(f.Channels),
+	}, nil
+}
+
+// Write encodes the audio in b as a single PES packet. The format of b must
+// match that given to NewAudioEncoder and b must hold whole sample frames.
+func (a *AudioEncoder) Write(b pcm.Buffer) error {
+	if b.Format != a.format {
+		return fmt.Errorf("%w: got %+v, want %+v", ErrFormatMismatch, b.Format, a.format)
+	}
+	if len(b.Data)%a.frameSize != 0 {
+		return fmt.Errorf("%w: %d bytes is not a whole number of %d byte frames", ErrInvalidLen, len(b.Data), a.frameSize)
+	}
+
+	data := b.Data
+	if a.codec == EncodeADPCM {
+		var buf bytes.Buffer
+		_, err := adpcm.NewEncoder(&buf).Write(data)
+		if err != nil {
+			return fmt.Errorf("could not encode ADPCM: %w", err)
+		}
+		data = buf.Bytes()
+	}
+
+	// The encoder's clock advances by the write period after each write, so
+	// set it to the duration of this block. This is calculated from the total
+	// samples written so that rounding errors do not accumulate.
+	n := uint64(len(b.Data) / a.frameSize)
+	a.e.writePeriod = a.duration(a.samples+n) - a.duration(a.samples)
+	a.samples += n
+
+	// The write rate published by NewEncoder is for its default period, so
+	// replace it with the rate for this block size, before any PSI is
+	// written with the block.
+	if n != a.blockFrames && n != 0 {
+		Meta.Add(WriteRateKey, fmt.Sprintf("%f", float64(a.format.Rate)/float64(n)))
+		a.blockFrames = n
+	}
+
+	_, err := a.e.Write(data)
+	return err
+}
+
+// duration returns the duration of n sample frames.
+func (a *AudioEncoder) duration(n uint64) time.Duration {
+	r := uint64(a.format.Rate)
+	return time.Duration(n/r)*time.Second + time.Duration(n%r)*time.Second/time.Duration(r)
+}
+
+// Close closes the destination of the AudioEncoder.
+func (a *AudioEncoder) Close() error {
+	return a.e.Close()
+}
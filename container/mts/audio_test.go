@@ -0,0 +1,199 @@
+/*
+NAME
+  audio_test.go
+
+DESCRIPTION
+  audio_test.go provides testing for functionality in audio.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ausocean/av/codec/adpcm"
+	"github.com/ausocean/av/codec/pcm"
+	"github.com/ausocean/av/container/mts/meta"
+	"github.com/ausocean/utils/logging"
+)
+
+func TestAudioEncoder(t *testing.T) {
+	const rate = 48000
+	format := pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: rate, Channels: 1}
+
+	// Generate blocks of a ramp, of differing numbers of samples.
+	var blocks []pcm.Buffer
+	var s int
+	for _, n := range []int{480, 960, 1601, 320} {
+		b := make([]byte, n*2)
+		for i := 0; i < n; i++ {
+			binary.LittleEndian.PutUint16(b[i*2:], uint16(s*7))
+			s++
+		}
+		blocks = append(blocks, pcm.Buffer{Format: format, Data: b})
+	}
+
+	for _, codec := range []int{EncodePCM, EncodeADPCM} {
+		Meta = meta.New()
+		var buf bytes.Buffer
+		e, err := NewAudioEncoder(nopCloser{&buf}, format, codec, (*logging.TestLogger)(t))
+		if err != nil {
+			t.Fatalf("did not expect error creating encoder for codec %d: %v", codec, err)
+		}
+		for _, b := range blocks {
+			err = e.Write(b)
+			if err != nil {
+				t.Fatalf("did not expect error writing for codec %d: %v", codec, err)
+			}
+		}
+
+		clip, err := Extract(buf.Bytes())
+		if err != nil {
+			t.Fatalf("did not expect error extracting for codec %d: %v", codec, err)
+		}
+		frames := clip.Frames()
+		if len(frames) != len(blocks) {
+			t.Fatalf("did not get expected number of frames for codec %d.\nGot: %d\nWant: %d\n", codec, len(frames), len(blocks))
+		}
+
+		var samples uint64
+		for i, f := range frames {
+			want := blocks[i].Data
+			got := f.Media
+			if codec == EncodeADPCM {
+				var dec bytes.Buffer
+				_, err = adpcm.NewDecoder(&dec).Write(f.Media)
+				if err != nil {
+					t.Fatalf("did not expect error decoding ADPCM frame %d: %v", i, err)
+				}
+				got = dec.Bytes()
+				// ADPCM is lossy, so only the first sample, which is stored
+				// uncompressed, and the length are expected to match.
+				want = append(want[:2:2], got[2:]...)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("did not get expected audio for codec %d frame %d", codec, i)
+			}
+
+			wantPTS := uint64(ptsOffset.Seconds()*PTSFrequency) + samples*PTSFrequency/rate
+			if f.PTS+1 < wantPTS || f.PTS > wantPTS+1 {
+				t.Errorf("did not get expected PTS for codec %d frame %d.\nGot: %d\nWant: %d\n", codec, i, f.PTS, wantPTS)
+			}
+			samples += uint64(len(blocks[i].Data) / 2)
+
+			if f.Meta[SampleRateKey] != "48000" || f.Meta[ChannelsKey] != "1" || f.Meta[BitDepthKey] != "16" {
+				t.Errorf("did not get expected meta for codec %d frame %d.\nGot: %v\n", codec, i, f.Meta)
+			}
+		}
+		if len(buf.Bytes()) == 0 {
+			t.Errorf("did not expect empty output for codec %d", codec)
+		}
+	}
+}
+
+// TestAudioEncoderWriteRate checks that the write rate in the metadata
+// matches the size of the audio blocks written.
+func TestAudioEncoderWriteRate(t *testing.T) {
+	Meta = meta.New()
+	const rate = 8000
+	format := pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: rate, Channels: 1}
+
+	var buf bytes.Buffer
+	e, err := NewAudioEncoder(nopCloser{&buf}, format, EncodePCM, (*logging.TestLogger)(t), PacketBasedPSI(1))
+	if err != nil {
+		t.Fatalf("did not expect error creating encoder: %v", err)
+	}
+	sizes := []int{800, 800, 2000}
+	for i, n := range sizes {
+		err = e.Write(pcm.Buffer{Format: format, Data: make([]byte, n*2)})
+		if err != nil {
+			t.Fatalf("did not expect error writing block %d: %v", i, err)
+		}
+	}
+
+	clip, err := Extract(buf.Bytes())
+	if err != nil {
+		t.Fatalf("did not expect error extracting: %v", err)
+	}
+	frames := clip.Frames()
+	if len(frames) != len(sizes) {
+		t.Fatalf("did not get expected number of frames.\nGot: %d\nWant: %d\n", len(frames), len(sizes))
+	}
+	for i, f := range frames {
+		want := fmt.Sprintf("%f", float64(rate)/float64(sizes[i]))
+		if got := f.Meta[WriteRateKey]; got != want {
+			t.Errorf("did not get expected write rate for frame %d.\nGot: %s\nWant: %s\n", i, got, want)
+		}
+	}
+}
+
+// TestAudioEncoderLongRunning checks that the write period is still correct
+// for a block during which the duration of the samples written, in
+// nanoseconds, exceeds the range of a uint64.
+func TestAudioEncoderLongRunning(t *testing.T) {
+	Meta = meta.New()
+	const rate = 48000
+	format := pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: rate, Channels: 1}
+
+	e, err := NewAudioEncoder(nopCloser{io.Discard}, format, EncodePCM, (*logging.TestLogger)(t))
+	if err != nil {
+		t.Fatalf("did not expect error creating encoder: %v", err)
+	}
+	e.samples = math.MaxUint64/uint64(time.Second) - 100 // About 4.4 days at 48kHz.
+	err = e.Write(pcm.Buffer{Format: format, Data: make([]byte, rate/10*2)})
+	if err != nil {
+		t.Fatalf("did not expect error writing: %v", err)
+	}
+	const want = 100 * time.Millisecond
+	if got := e.e.writePeriod; got != want {
+		t.Errorf("did not get expected write period.\nGot: %v\nWant: %v\n", got, want)
+	}
+}
+
+func TestAudioEncoderErrors(t *testing.T) {
+	Meta = meta.New()
+	mono16 := pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: 8000, Channels: 1}
+	stereo16 := pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: 8000, Channels: 2}
+	log := (*logging.TestLogger)(t)
+
+	_, err := NewAudioEncoder(nopCloser{&bytes.Buffer{}}, stereo16, EncodeADPCM, log)
+	if !errors.Is(err, ErrADPCMFormat) {
+		t.Errorf("did not get expected error for stereo ADPCM.\nGot: %v\nWant: %v\n", err, ErrADPCMFormat)
+	}
+	_, err = NewAudioEncoder(nopCloser{&bytes.Buffer{}}, mono16, EncodeH264, log)
+	if !errors.Is(err, ErrUnsupportedMedia) {
+		t.Errorf("did not get expected error for video codec.\nGot: %v\nWant: %v\n", err, ErrUnsupportedMedia)
+	}
+	_, err = NewAudioEncoder(nopCloser{&bytes.Buffer{}}, pcm.BufferFormat{SFormat: pcm.S16_LE, Channels: 1}, EncodePCM, log)
+	if !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("did not get expected error for zero rate.\nGot: %v\nWant: %v\n", err, ErrInvalidFormat)
+	}
+
+	e, err := NewAudioEncoder(nopCloser{&bytes.Buffer{}}, stereo16, EncodePCM, log)
+	if err != nil {
+		t.Fatalf("did not expect error creating encoder: %v", err)
+	}
+	err = e.Write(pcm.Buffer{Format: mono16, Data: make([]byte, 8)})
+	if !errors.Is(err, ErrFormatMismatch) {
+		t.Errorf("did not get expected error for mismatched format.\nGot: %v\nWant: %v\n", err, ErrFormatMismatch)
+	}
+	err = e.Write(pcm.Buffer{Format: stereo16, Data: make([]byte, 6)})
+	if !errors.Is(err, ErrInvalidLen) {
+		t.Errorf("did not get expected error for partial frame.\nGot: %v\nWant: %v\n", err, ErrInvalidLen)
+	}
+}
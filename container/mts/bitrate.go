@@ -0,0 +1,94 @@
+/*
+NAME
+  bitrate.go
+
+DESCRIPTION
+  bitrate.go provides calculation of the average and peak bitrate of the media
+  in an MPEG-TS clip.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import "errors"
+
+// ErrNoDuration is returned by Bitrate when the last PTS of the media is not
+// after the first, so there is no interval to calculate a bitrate over.
+var ErrNoDuration = errors.New("clip does not span more than one PTS")
+
+// Bitrate returns the average and peak bitrate, in bits per second, of the
+// media of the given PID in the MPEG-TS clip. The bytes of each PES packet
+// with a PTS, including the MPEG-TS packets carrying it, are counted over the
+// interval to the next PTS. The peak is the greatest bitrate of these
+// intervals, and the average is taken from the first to the last PTS, so the
+// last PES packet is not counted. PTS are unwrapped. PES packets whose PTS do
+// not increase, as for reordered frames, are counted in the next interval
+// over which PTS does increase.
+func Bitrate(clip []byte, pid uint16) (avg, peak float64, err error) {
+	if len(clip)%PacketSize != 0 {
+		return 0, 0, ErrInvalidLen
+	}
+
+	// Find the PTS of each PES packet, and the number of bytes of the PID from
+	// the start of that PES packet to the start of the next.
+	var (
+		pts  []uint64
+		size []int
+	)
+	for i := 0; i < len(clip); i += PacketSize {
+		pkt := clip[i : i+PacketSize]
+		_pid, _ := PID(pkt)
+		if _pid != pid {
+			continue
+		}
+		_pts, err := GetPTS(pkt)
+		if err == nil {
+			pts = append(pts, uint64(_pts))
+			size = append(size, 0)
+		}
+		if len(size) != 0 {
+			size[len(size)-1] += PacketSize
+		}
+	}
+	if len(pts) == 0 {
+		return 0, 0, ErrNoPTS
+	}
+	UnwrapPTS(pts)
+
+	var (
+		total int // Bytes from the first to last PTS.
+		n     int // Bytes in the current interval.
+		start = pts[0]
+	)
+	for i := 1; i < len(pts); i++ {
+		n += size[i-1]
+		total += size[i-1]
+		if pts[i] <= start {
+			continue
+		}
+		rate := bitsPerSecond(n, pts[i]-start)
+		if rate > peak {
+			peak = rate
+		}
+		n = 0
+		start = pts[i]
+	}
+
+	last := pts[len(pts)-1]
+	if last <= pts[0] {
+		return 0, 0, ErrNoDuration
+	}
+	return bitsPerSecond(total, last-pts[0]), peak, nil
+}
+
+// bitsPerSecond returns the bitrate of n bytes over d PTS ticks.
+func bitsPerSecond(n int, d uint64) float64 {
+	return float64(n*8) * PTSFrequency / float64(d)
+}
@@ -0,0 +1,102 @@
+/*
+NAME
+  bitrate_test.go
+
+DESCRIPTION
+  bitrate_test.go provides testing for functionality in bitrate.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestBitrate(t *testing.T) {
+	const (
+		rate     = 25
+		interval = PTSFrequency / rate
+		nFrames  = 50
+		bigFrame = 10
+	)
+
+	// Write frames across a PTS wraparound, with one frame bigger than the rest.
+	for _, start := range []uint64{0, MaxPTS - 10*interval} {
+		var clip bytes.Buffer
+		err := writePSI(&clip)
+		if err != nil {
+			t.Fatalf("did not expect error writing PSI: %v", err)
+		}
+		var total, big int
+		for i := 0; i < nFrames; i++ {
+			size := 1000
+			if i == bigFrame {
+				size = 10000
+			}
+			before := clip.Len()
+			err = writeFrame(&clip, make([]byte, size), (start+uint64(i*interval))&MaxPTS)
+			if err != nil {
+				t.Fatalf("did not expect error writing frame %d: %v", i, err)
+			}
+			n := clip.Len() - before
+			if i == bigFrame {
+				big = n
+			}
+			// The last frame is not counted as its duration is unknown.
+			if i != nFrames-1 {
+				total += n
+			}
+		}
+
+		avg, peak, err := Bitrate(clip.Bytes(), PIDVideo)
+		if err != nil {
+			t.Fatalf("did not expect error getting bitrate: %v", err)
+		}
+
+		wantAvg := float64(total*8) / (float64(nFrames-1) / rate)
+		if math.Abs(avg-wantAvg) > 1e-6*wantAvg {
+			t.Errorf("did not get expected average bitrate for start %d.\nGot: %v\nWant: %v\n", start, avg, wantAvg)
+		}
+		wantPeak := float64(big * 8 * rate)
+		if math.Abs(peak-wantPeak) > 1e-6*wantPeak {
+			t.Errorf("did not get expected peak bitrate for start %d.\nGot: %v\nWant: %v\n", start, peak, wantPeak)
+		}
+	}
+}
+
+func TestBitrateErrors(t *testing.T) {
+	var clip bytes.Buffer
+	err := writePSI(&clip)
+	if err != nil {
+		t.Fatalf("did not expect error writing PSI: %v", err)
+	}
+
+	_, _, err = Bitrate(clip.Bytes(), PIDVideo)
+	if err != ErrNoPTS {
+		t.Errorf("did not get expected error for clip without PTS.\nGot: %v\nWant: %v\n", err, ErrNoPTS)
+	}
+
+	err = writeFrame(&clip, make([]byte, 1000), 0)
+	if err != nil {
+		t.Fatalf("did not expect error writing frame: %v", err)
+	}
+	_, _, err = Bitrate(clip.Bytes(), PIDVideo)
+	if err != ErrNoDuration {
+		t.Errorf("did not get expected error for single frame.\nGot: %v\nWant: %v\n", err, ErrNoDuration)
+	}
+
+	_, _, err = Bitrate(clip.Bytes()[1:], PIDVideo)
+	if err != ErrInvalidLen {
+		t.Errorf("did not get expected error for invalid length.\nGot: %v\nWant: %v\n", err, ErrInvalidLen)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+NAME
+  compact.go
+
+DESCRIPTION
+  compact.go provides compaction of MPEG-TS clips for storage by removing null
+  packets and redundant PSI.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"time"
+)
+
+// CompactPSIInterval is the interval of media time after which Compact keeps a
+// PSI packet even if it is identical to the last one kept for its PID, so that
+// a player can tune in to the compacted clip part way through.
+const CompactPSIInterval = time.Second
+
+// ccMask is the mask for the continuity counter in octet 3.
+const ccMask = 0x0f
+
+// Compact returns a copy of the MPEG-TS clip with null packets removed, and
+// with PAT and PMT packets removed where they are identical, apart from their
+// continuity counter, to the last packet kept for their PID, unless more than
+// CompactPSIInterval of media time has passed since then. Media time is taken
+// from the PTS of the PES packets in the clip, so if there are none, only the
+// first of each run of identical PSI is kept. The first PAT and PMT are always
+// kept. The continuity counters of the PSI that is kept are renumbered so that
+// they remain continuous.
+func Compact(clip []byte) ([]byte, error) {
+	if len(clip)%PacketSize != 0 {
+		return nil, ErrInvalidLen
+	}
+
+	interval := uint64(CompactPSIInterval.Seconds() * PTSFrequency)
+
+	var (
+		out      = make([]byte, 0, len(clip))
+		pmtPIDs  = map[uint16]bool{PmtPid: true}
+		lastPSI  = make(map[uint16][]byte) // Last PSI packet kept for each PID.
+		lastTime = make(map[uint16]uint64) // Media time of last PSI kept for each PID.
+		nextCC   = make(map[uint16]byte)   // Next continuity counter for each PSI PID.
+		now      uint64                    // Media time, unwrapped, from the first PTS.
+		prevPTS  = int64(-1)               // Last PTS used to advance media time, or -1 if none.
+	)
+	for i := 0; i < len(clip); i += PacketSize {
+		pkt := clip[i : i+PacketSize]
+		pid, _ := PID(pkt)
+
+		switch {
+		case pid == NullPid:
+			continue
+
+		case pid == PatPid || pmtPIDs[pid]:
+			if pid == PatPid {
+				progs, err := Programs(pkt)
+				if err == nil {
+					for _, p := range progs {
+						pmtPIDs[p] = true
+					}
+				}
+			}
+
+			last, ok := lastPSI[pid]
+			if ok && samePSI(last, pkt) && now-lastTime[pid] < interval {
+				continue
+			}
+			cc, ok := nextCC[pid]
+			if !ok {
+				cc = pkt[3] & ccMask
+			}
+			out = append(out, pkt...)
+			p := out[len(out)-PacketSize:]
+			p[3] = p[3]&^ccMask | cc
+			nextCC[pid] = (cc + 1) & ccMask
+			lastPSI[pid] = p
+			lastTime[pid] = now
+
+		default:
+			// Advance media time using PTS, ignoring PTS that go backwards as
+			// for reordered frames.
+			pts, err := GetPTS(pkt)
+			if err == nil {
+				if prevPTS != -1 {
+					d := uint64(pts-prevPTS) & MaxPTS
+					if d < (MaxPTS+1)/2 {
+						now += d
+						prevPTS = pts
+					}
+				} else {
+					prevPTS = pts
+				}
+			}
+			out = append(out, pkt...)
+		}
+	}
+	return out, nil
+}
+
+// samePSI returns true if the MPEG-TS packets a and b are identical apart from
+// their continuity counters.
+func samePSI(a, b []byte) bool {
+	return bytes.Equal(a[:3], b[:3]) &&
+		a[3]&^ccMask == b[3]&^ccMask &&
+		bytes.Equal(a[4:PacketSize], b[4:PacketSize])
+}
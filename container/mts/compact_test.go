@@ -0,0 +1,157 @@
+/*
+NAME
+  compact_test.go
+
+DESCRIPTION
+  compact_test.go provides testing for functionality in compact.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ausocean/av/container/mts/meta"
+	"github.com/ausocean/utils/logging"
+)
+
+// TestCompact checks that Compact removes null packets and redundant PSI from
+// a clip, keeping PSI for tune-in and continuity, and that the compacted clip
+// can still be parsed.
+func TestCompact(t *testing.T) {
+	Meta = meta.New()
+
+	const (
+		rate    = 25      // Access units per second.
+		muxRate = 1000000 // Bits per second.
+		nAUs    = 100     // i.e. 4 seconds.
+	)
+
+	var buf bytes.Buffer
+	e, err := NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount), Rate(rate), MuxRate(muxRate))
+	if err != nil {
+		t.Fatalf("could not create MTS encoder: %v", err)
+	}
+	for i := 0; i < nAUs; i++ {
+		_, err = e.Write(make([]byte, 1000))
+		if err != nil {
+			t.Fatalf("could not write access unit %d: %v", i, err)
+		}
+	}
+	clip := buf.Bytes()
+
+	got, err := Compact(clip)
+	if err != nil {
+		t.Fatalf("did not expect error compacting clip: %v", err)
+	}
+	if len(got) >= len(clip) {
+		t.Errorf("compacted clip is not smaller.\nGot: %d\nOriginal: %d\n", len(got), len(clip))
+	}
+
+	before, err := PIDCounts(clip)
+	if err != nil {
+		t.Fatalf("could not count PIDs of clip: %v", err)
+	}
+	after, err := PIDCounts(got)
+	if err != nil {
+		t.Fatalf("could not count PIDs of compacted clip: %v", err)
+	}
+	if after[NullPid] != 0 {
+		t.Errorf("compacted clip contains %d null packets", after[NullPid])
+	}
+	if after[PIDVideo] != before[PIDVideo] {
+		t.Errorf("did not keep all media packets.\nGot: %d\nWant: %d\n", after[PIDVideo], before[PIDVideo])
+	}
+
+	// The PSI is identical throughout, so we expect PSI at the start and then
+	// about once each CompactPSIInterval.
+	const wantPSI = nAUs/rate + 1
+	for _, pid := range []uint16{PatPid, PmtPid} {
+		if after[pid] < wantPSI-1 || after[pid] > wantPSI {
+			t.Errorf("did not get expected PSI count for PID %d.\nGot: %d\nWant: %d\n", pid, after[pid], wantPSI)
+		}
+		if after[pid] >= before[pid] {
+			t.Errorf("PSI of PID %d was not reduced from %d", pid, before[pid])
+		}
+	}
+
+	// Check continuity of each PID.
+	next := make(map[uint16]byte)
+	for i := 0; i < len(got); i += PacketSize {
+		pid, _ := PID(got[i:])
+		cc := got[i+3] & 0x0f
+		if want, ok := next[pid]; ok && cc != want {
+			t.Errorf("discontinuity at packet %d for PID %d.\nGot: %d\nWant: %d\n", i/PacketSize, pid, cc, want)
+		}
+		next[pid] = (cc + 1) & 0x0f
+	}
+
+	// The compacted clip should still be usable.
+	idx, streams, _, err := FindPSI(got)
+	if err != nil {
+		t.Fatalf("did not expect error finding PSI in compacted clip: %v", err)
+	}
+	if idx != 0 {
+		t.Errorf("did not get expected PSI index.\nGot: %d\nWant: 0\n", idx)
+	}
+	_, wantStreams, _, _ := FindPSI(clip)
+	if !reflect.DeepEqual(streams, wantStreams) {
+		t.Errorf("did not get expected streams.\nGot: %v\nWant: %v\n", streams, wantStreams)
+	}
+
+	gotRange, err := GetPTSRange(got, PIDVideo)
+	if err != nil {
+		t.Fatalf("did not expect error getting PTS range of compacted clip: %v", err)
+	}
+	wantRange, _ := GetPTSRange(clip, PIDVideo)
+	if gotRange != wantRange {
+		t.Errorf("did not get expected PTS range.\nGot: %v\nWant: %v\n", gotRange, wantRange)
+	}
+
+	_, err = Compact(clip[1:])
+	if err != ErrInvalidLen {
+		t.Errorf("did not get expected error for invalid length.\nGot: %v\nWant: %v\n", err, ErrInvalidLen)
+	}
+}
+
+// TestCompactChangedPSI checks that PSI that changes is kept.
+func TestCompactChangedPSI(t *testing.T) {
+	var clip bytes.Buffer
+	Meta = meta.New()
+	for i := 0; i < 3; i++ {
+		Meta.Add("n", string(rune('a'+i)))
+		for j := 0; j < 2; j++ {
+			err := writePSIWithMeta(&clip, t)
+			if err != nil {
+				t.Fatalf("did not expect error writing PSI: %v", err)
+			}
+		}
+	}
+
+	got, err := Compact(clip.Bytes())
+	if err != nil {
+		t.Fatalf("did not expect error compacting clip: %v", err)
+	}
+	counts, _ := PIDCounts(got)
+	if counts[PatPid] != 1 || counts[PmtPid] != 3 {
+		t.Errorf("did not get expected PSI counts.\nGot: PAT %d, PMT %d\nWant: PAT 1, PMT 3\n", counts[PatPid], counts[PmtPid])
+	}
+
+	tl, err := MetaTimeline(got)
+	if err != nil {
+		t.Fatalf("did not expect error getting meta timeline: %v", err)
+	}
+	if len(tl) != 3 {
+		t.Errorf("did not get expected meta timeline length.\nGot: %d\nWant: 3\n", len(tl))
+	}
+}
@@ -0,0 +1,72 @@
+/*
+NAME
+  drops.go
+
+DESCRIPTION
+  drops.go provides analysis of MPEG-TS clips for frames suspected to have
+  been dropped, using gaps in PTS.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import "math"
+
+// Drop describes a suspected drop of frames.
+type Drop struct {
+	PTS     uint64 // PTS of the frame after which frames are missing.
+	Missing int    // Number of frames suspected to be missing.
+}
+
+// DropReport describes the frames of a clip and those suspected dropped.
+type DropReport struct {
+	Expected int    // Number of frames expected from the PTS range and frame rate.
+	Received int    // Number of frames present in the clip.
+	Drops    []Drop // Suspected drops in order of PTS.
+}
+
+// Dropped returns the number of frames suspected to have been dropped.
+func (r *DropReport) Dropped() int {
+	return r.Expected - r.Received
+}
+
+// DroppedFrames reports frames suspected to have been dropped from the media
+// of the given PID in the MPEG-TS clip, given the frame rate of the media. For
+// H.264 the frame rate may be obtained from the SPS (see h264dec.SPS.FrameRate).
+// A gap between consecutive PTS of more than one and a half frame intervals is
+// taken to be a drop of the number of frames fitting in the gap. PTS that do
+// not increase, as for reordered frames, are not considered drops. PTS are
+// unwrapped, so drops are given in unwrapped PTS.
+func DroppedFrames(clip []byte, pid uint16, rate float64) (*DropReport, error) {
+	if rate <= 0 {
+		return nil, ErrInvalidRate
+	}
+	pts, err := AllPTS(clip, pid)
+	if err != nil {
+		return nil, err
+	}
+	UnwrapPTS(pts)
+
+	interval := PTSFrequency / rate
+	r := &DropReport{Expected: len(pts), Received: len(pts)}
+	for i := 1; i < len(pts); i++ {
+		if pts[i] <= pts[i-1] {
+			continue
+		}
+		gap := float64(pts[i] - pts[i-1])
+		if gap <= 1.5*interval {
+			continue
+		}
+		missing := int(math.Round(gap/interval)) - 1
+		r.Drops = append(r.Drops, Drop{PTS: pts[i-1], Missing: missing})
+		r.Expected += missing
+	}
+	return r, nil
+}
@@ -0,0 +1,75 @@
+/*
+NAME
+  drops_test.go
+
+DESCRIPTION
+  drops_test.go provides testing for functionality in drops.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDroppedFrames(t *testing.T) {
+	const (
+		rate     = 25
+		interval = PTSFrequency / rate
+		nFrames  = 20
+	)
+
+	// Frames to leave out of the clip, as if dropped.
+	dropped := map[int]bool{5: true, 12: true, 13: true, 14: true}
+
+	var clip bytes.Buffer
+	err := writePSI(&clip)
+	if err != nil {
+		t.Fatalf("did not expect error writing PSI: %v", err)
+	}
+	for i := 0; i < nFrames; i++ {
+		if dropped[i] {
+			continue
+		}
+		// Add some jitter to the PTS, which should not be taken as drops.
+		pts := uint64(i*interval + (i%3)*interval/4)
+		err = writeFrame(&clip, make([]byte, 200), pts)
+		if err != nil {
+			t.Fatalf("did not expect error writing frame %d: %v", i, err)
+		}
+	}
+
+	got, err := DroppedFrames(clip.Bytes(), PIDVideo, rate)
+	if err != nil {
+		t.Fatalf("did not expect error finding dropped frames: %v", err)
+	}
+	want := &DropReport{
+		Expected: nFrames,
+		Received: nFrames - len(dropped),
+		Drops: []Drop{
+			{PTS: 4*interval + interval/4, Missing: 1},
+			{PTS: 11*interval + 2*interval/4, Missing: 3},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("did not get expected report.\nGot: %+v\nWant: %+v\n", got, want)
+	}
+	if got.Dropped() != len(dropped) {
+		t.Errorf("did not get expected number of dropped frames.\nGot: %d\nWant: %d\n", got.Dropped(), len(dropped))
+	}
+
+	_, err = DroppedFrames(clip.Bytes(), PIDVideo, 0)
+	if err != ErrInvalidRate {
+		t.Errorf("did not get expected error for invalid rate.\nGot: %v\nWant: %v\n", err, ErrInvalidRate)
+	}
+}
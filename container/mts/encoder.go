@@ -18,6 +18,7 @@ LICENSE
 package mts
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"strconv"
@@ -130,9 +131,16 @@ type Encoder struct {
 	startTime    time.Time
 	mediaPID     uint16
 	streamID     byte
+	tsc          byte // Transport scrambling control of media packets.
+	muxRate      uint // Target mux rate in bits per second, or 0 for no padding.
+	muxCount     int  // Number of packets written, used for mux rate padding.
+
+	seiUUID [16]byte      // UUID of inserted user data SEI.
+	seiData func() []byte // Returns the data of inserted SEI, or nil for no SEI.
 
 	pmt                *psi.PSI
 	patBytes, pmtBytes []byte
+	pmtMeta            []byte // Metadata descriptor of the last PMT written.
 
 	// log is a function that will be used through the encoder code for logging.
 	log logging.Logger
@@ -163,6 +171,10 @@ func NewEncoder(dst io.WriteCloser, log logging.Logger, options ...func(*Encoder
 	}
 	log.Debug("encoder options applied")
 
+	if e.seiData != nil && e.streamID != pes.H264SID {
+		return nil, fmt.Errorf("%w: SEI insertion requires H.264", ErrUnsupportedMedia)
+	}
+
 	Meta.Add(WriteRateKey, fmt.Sprintf("%f", 1/float64(e.writePeriod.Seconds())))
 
 	e.pmt.SyntaxSection.SpecificData.(*psi.PMT).StreamSpecificData.StreamType = e.streamID
@@ -173,7 +185,10 @@ func NewEncoder(dst io.WriteCloser, log logging.Logger, options ...func(*Encoder
 }
 
 // Write implements io.Writer. Write takes raw video or audio data and encodes into MPEG-TS,
-// then sending it to the encoder's io.Writer destination.
+// then sending it to the encoder's io.Writer destination. Each call produces one PES packet
+// that begins a new MPEG-TS packet with the PUSI set; the remainder of the last MPEG-TS
+// packet of the PES is filled with adaptation field stuffing, so no two PES packets ever
+// share an MPEG-TS packet.
 func (e *Encoder) Write(data []byte) (int, error) {
 	e.log.Debug("writing data", "len(data)", len(data))
 	switch e.psiMethod {
@@ -213,13 +228,18 @@ func (e *Encoder) Write(data []byte) (int, error) {
 		panic("undefined PSI method")
 	}
 
+	au := data
+	if e.seiData != nil && containsIDR(data) {
+		au = h264.InsertBeforeVCL(data, h264.UserDataSEI(e.seiUUID, e.seiData()))
+	}
+
 	// Prepare PES data.
 	pts := e.pts()
 	pesPkt := pes.Packet{
 		StreamID:     e.streamID,
 		PDI:          hasPTS,
 		PTS:          pts,
-		Data:         data,
+		Data:         au,
 		HeaderLength: 5,
 	}
 
@@ -230,6 +250,7 @@ func (e *Encoder) Write(data []byte) (int, error) {
 		pkt := Packet{
 			PUSI: pusi,
 			PID:  uint16(e.mediaPID),
+			TSC:  e.tsc,
 			RAI:  pusi,
 			CC:   e.ccFor(e.mediaPID),
 			AFC:  hasAdaptationField | hasPayload,
@@ -254,6 +275,14 @@ func (e *Encoder) Write(data []byte) (int, error) {
 			return len(data), fmt.Errorf("could not write MTS packet to destination: %w", err)
 		}
 		e.pktCount++
+		e.muxCount++
+	}
+
+	if e.muxRate != 0 {
+		err := e.writeNulls()
+		if err != nil {
+			return len(data), fmt.Errorf("could not write null packets: %w", err)
+		}
 	}
 
 	e.tick()
@@ -277,12 +306,25 @@ func (e *Encoder) writePSI() error {
 		return fmt.Errorf("could not write pat packet: %w", err)
 	}
 	e.pktCount++
+	e.muxCount++
 
 	e.pmtBytes, err = updateMeta(e.pmtBytes, e.log)
 	if err != nil {
 		return fmt.Errorf("could not update pmt metadata: %w", err)
 	}
 
+	// Bump the PMT version when the metadata changes so that receivers
+	// don't keep using a cached PMT.
+	p := psi.PSIBytes(e.pmtBytes)
+	_, desc := p.HasDescriptor(psi.MetadataTag)
+	if e.pmtMeta != nil && !bytes.Equal(desc, e.pmtMeta) {
+		err = p.SetVersion(p.Version() + 1)
+		if err != nil {
+			return fmt.Errorf("could not set pmt version: %w", err)
+		}
+	}
+	e.pmtMeta = append(e.pmtMeta[:0], desc...)
+
 	// Create mts packet from pmt table.
 	pmtPkt := Packet{
 		PUSI:    true,
@@ -296,11 +338,37 @@ func (e *Encoder) writePSI() error {
 		return fmt.Errorf("could not write pmt packet: %w", err)
 	}
 	e.pktCount++
+	e.muxCount++
 
 	e.log.Debug("PSI written", "PAT CC", patPkt.CC, "PMT CC", pmtPkt.CC)
 	return nil
 }
 
+// writeNulls writes null packets so that the number of packets written up to
+// the end of the current frame interval matches the mux rate. As the PCR is
+// derived from the same clock, this paces the output at the mux rate relative
+// to the PCR. If more packets than the mux rate allows have already been
+// written, no null packets are written.
+func (e *Encoder) writeNulls() error {
+	target := int((e.clock + e.writePeriod).Seconds() * float64(e.muxRate) / (PacketSize * 8))
+	if e.muxCount > target {
+		e.log.Warning("mux rate exceeded", "packets", e.muxCount, "target", target, "mux rate", e.muxRate)
+		return nil
+	}
+
+	// Null packets carry only stuffing and their continuity counter is
+	// ignored by decoders.
+	nullPkt := Packet{PID: NullPid, AFC: hasPayload}
+	b := nullPkt.Bytes(e.tsSpace[:PacketSize])
+	for ; e.muxCount < target; e.muxCount++ {
+		_, err := e.dst.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // tick advances the clock one frame interval.
 func (e *Encoder) tick() {
 	e.clock += e.writePeriod
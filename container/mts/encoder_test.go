@@ -19,6 +19,7 @@ package mts
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -28,6 +29,9 @@ import (
 	"github.com/Comcast/gots/v2/packet"
 	"github.com/Comcast/gots/v2/pes"
 
+	"github.com/ausocean/av/codec/codecutil"
+	"github.com/ausocean/av/codec/h264"
+	"github.com/ausocean/av/codec/h264/h264dec"
 	"github.com/ausocean/av/container/mts/meta"
 	"github.com/ausocean/av/container/mts/psi"
 	"github.com/ausocean/utils/logging"
@@ -356,3 +360,378 @@ func TestExtractMeta(t *testing.T) {
 		t.Errorf("did not get expected result.\ngot: %v\nwant: %v\n", got, want)
 	}
 }
+
+// TestEncodeH265StreamType checks that an encoder configured for H.265
+// advertises the HEVC stream type (0x24) in the PMT, rather than the H.264
+// stream type.
+func TestEncodeH265StreamType(t *testing.T) {
+	Meta = meta.New()
+
+	const hevcStreamType = 0x24
+
+	// An HEVC access unit consisting of VPS, SPS, PPS and IDR NAL units.
+	au := []byte{
+		0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c, 0x01, 0xff, 0xff, 0x01, 0x60,
+		0x00, 0x00, 0x00, 0x01, 0x42, 0x01, 0x01, 0x01, 0x60, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x01, 0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62, 0x40,
+		0x00, 0x00, 0x01, 0x26, 0x01, 0xaf, 0x06, 0xb8, 0x63, 0xef, 0x3a,
+	}
+
+	var buf bytes.Buffer
+	e, err := NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount), Rate(25), MediaType(EncodeH265))
+	if err != nil {
+		t.Fatalf("could not create MTS encoder: %v", err)
+	}
+	_, err = e.Write(au)
+	if err != nil {
+		t.Fatalf("could not write access unit: %v", err)
+	}
+
+	_, streams, _, err := FindPSI(buf.Bytes())
+	if err != nil {
+		t.Fatalf("did not expect error finding PSI: %v", err)
+	}
+	typ, ok := streams[PIDVideo]
+	if !ok || len(streams) != 1 {
+		t.Fatalf("did not get expected streams, got: %v", streams)
+	}
+	if typ != hevcStreamType {
+		t.Errorf("did not get expected stream type.\nGot: %#x\nWant: %#x\n", typ, hevcStreamType)
+	}
+
+	pmt, _, err := FindPmt(buf.Bytes())
+	if err != nil {
+		t.Fatalf("could not find PMT: %v", err)
+	}
+	es, err := Streams(pmt)
+	if err != nil {
+		t.Fatalf("could not get streams from PMT: %v", err)
+	}
+	if len(es) != 1 || es[0].StreamType() != hevcStreamType || es[0].ElementaryPid() != PIDVideo {
+		t.Errorf("did not get expected elementary stream, got: %v", es)
+	}
+}
+
+// TestEncodeScramblingControl checks that the ScramblingControl option sets the
+// transport scrambling control of media packets only.
+func TestEncodeScramblingControl(t *testing.T) {
+	Meta = meta.New()
+
+	dst := &destination{}
+	e, err := NewEncoder(nopCloser{dst}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount), ScramblingControl(TSCEvenKey))
+	if err != nil {
+		t.Fatalf("could not create MTS encoder: %v", err)
+	}
+	_, err = e.Write(make([]byte, 400))
+	if err != nil {
+		t.Fatalf("could not write data: %v", err)
+	}
+
+	for i, p := range dst.packets {
+		pid, err := PID(p)
+		if err != nil {
+			t.Fatalf("could not get PID of packet %d: %v", i, err)
+		}
+		want := byte(TSCNotScrambled)
+		if pid == PIDVideo {
+			want = TSCEvenKey
+		}
+		if got, _ := TSC(p); got != want {
+			t.Errorf("did not get expected TSC for packet %d with PID %d.\nGot: %d\nWant: %d\n", i, pid, got, want)
+		}
+	}
+
+	_, err = NewEncoder(nopCloser{dst}, (*logging.TestLogger)(t), ScramblingControl(4))
+	if !errors.Is(err, ErrInvalidTSC) {
+		t.Errorf("did not get expected error for invalid TSC.\nGot: %v\nWant: %v\n", err, ErrInvalidTSC)
+	}
+}
+
+// TestPayloadOfEncoded checks that Payload excludes the adaptation field of
+// encoded media packets by comparing with the payload given by gots.
+func TestPayloadOfEncoded(t *testing.T) {
+	Meta = meta.New()
+
+	dst := &destination{}
+	e, err := NewEncoder(nopCloser{dst}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount))
+	if err != nil {
+		t.Fatalf("could not create MTS encoder: %v", err)
+	}
+	_, err = e.Write(make([]byte, 400))
+	if err != nil {
+		t.Fatalf("could not write data: %v", err)
+	}
+
+	var withAF int
+	for i, p := range dst.packets {
+		if pid, _ := PID(p); pid != PIDVideo {
+			continue
+		}
+		var pkt packet.Packet
+		copy(pkt[:], p)
+		if !pkt.HasAdaptationField() || !pkt.HasPayload() {
+			continue
+		}
+		withAF++
+
+		want, err := pkt.Payload()
+		if err != nil {
+			t.Fatalf("could not get payload of packet %d using gots: %v", i, err)
+		}
+		got, err := Payload(p)
+		if err != nil {
+			t.Fatalf("did not expect error getting payload of packet %d: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("did not get expected payload for packet %d.\nGot: %v\nWant: %v\n", i, got, want)
+		}
+	}
+	if withAF == 0 {
+		t.Fatal("no media packets with both adaptation field and payload")
+	}
+}
+
+// TestEncodeMuxRate checks that the MuxRate option pads the output with null
+// packets to give the configured packet rate relative to the PCR.
+func TestEncodeMuxRate(t *testing.T) {
+	Meta = meta.New()
+
+	const (
+		rate    = 25      // Access units per second.
+		muxRate = 2000000 // Bits per second.
+		nAUs    = 50
+	)
+
+	dst := &destination{}
+	e, err := NewEncoder(nopCloser{dst}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount), Rate(rate), MuxRate(muxRate))
+	if err != nil {
+		t.Fatalf("could not create MTS encoder: %v", err)
+	}
+	for i := 0; i < nAUs; i++ {
+		_, err = e.Write(make([]byte, 1000))
+		if err != nil {
+			t.Fatalf("could not write access unit %d: %v", i, err)
+		}
+	}
+
+	// The output should span nAUs frame intervals at the mux rate.
+	want := nAUs * muxRate / rate / (PacketSize * 8)
+	if len(dst.packets) != want {
+		t.Errorf("did not get expected number of packets.\nGot: %d\nWant: %d\n", len(dst.packets), want)
+	}
+
+	// Check the packet rate between each PCR and that null packets are valid.
+	var (
+		nulls   int
+		lastPCR = -1
+	)
+	for i, p := range dst.packets {
+		pid, err := PID(p)
+		if err != nil {
+			t.Fatalf("could not get PID of packet %d: %v", i, err)
+		}
+		if pid == NullPid {
+			nulls++
+			payload, err := Payload(p)
+			if err != nil {
+				t.Fatalf("could not get payload of null packet %d: %v", i, err)
+			}
+			if !bytes.Equal(payload, bytes.Repeat([]byte{0xff}, PacketSize-HeadSize)) {
+				t.Errorf("null packet %d does not contain only stuffing", i)
+			}
+			continue
+		}
+
+		var pkt packet.Packet
+		copy(pkt[:], p)
+		if pid != PIDVideo || !pkt.PayloadUnitStartIndicator() {
+			continue
+		}
+		if lastPCR != -1 {
+			// Allow one packet either way for rounding of the rate.
+			const perAU = muxRate / rate / (PacketSize * 8)
+			if n := i - lastPCR; n < perAU || n > perAU+1 {
+				t.Errorf("did not get expected packets between PCRs at packet %d.\nGot: %d\nWant: %d\n", i, n, perAU)
+			}
+		}
+		lastPCR = i
+	}
+	if nulls == 0 {
+		t.Error("no null packets written")
+	}
+
+	_, err = NewEncoder(nopCloser{dst}, (*logging.TestLogger)(t), MuxRate(0))
+	if !errors.Is(err, ErrInvalidMuxRate) {
+		t.Errorf("did not get expected error for invalid mux rate.\nGot: %v\nWant: %v\n", err, ErrInvalidMuxRate)
+	}
+}
+
+// TestEncodePESAlignment checks that each PES packet begins a new MPEG-TS
+// packet, i.e. that the last packet of a PES is stuffed rather than shared
+// with the next PES.
+func TestEncodePESAlignment(t *testing.T) {
+	Meta = meta.New()
+
+	dst := &destination{}
+	e, err := NewEncoder(nopCloser{dst}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount))
+	if err != nil {
+		t.Fatalf("could not create MTS encoder: %v", err)
+	}
+
+	// Sizes chosen so that most PES packets end part way through a packet.
+	sizes := []int{1, 100, 170, 171, 400, 1000, 2000}
+	for i, n := range sizes {
+		_, err = e.Write(bytes.Repeat([]byte{byte(i + 1)}, n))
+		if err != nil {
+			t.Fatalf("could not write access unit %d: %v", i, err)
+		}
+	}
+
+	// Group the media packets into PES packets by PUSI and check that each
+	// contains exactly one PES packet, with nothing after the data written.
+	var pesPkts [][]byte
+	for i, p := range dst.packets {
+		if pid, _ := PID(p); pid != PIDVideo {
+			continue
+		}
+		payload, err := Payload(p)
+		if err != nil {
+			t.Fatalf("could not get payload of packet %d: %v", i, err)
+		}
+		var pkt packet.Packet
+		copy(pkt[:], p)
+		if pkt.PayloadUnitStartIndicator() {
+			pesPkts = append(pesPkts, nil)
+		}
+		if len(pesPkts) == 0 {
+			t.Fatalf("media packet %d before first PUSI", i)
+		}
+		pesPkts[len(pesPkts)-1] = append(pesPkts[len(pesPkts)-1], payload...)
+	}
+	if len(pesPkts) != len(sizes) {
+		t.Fatalf("did not get expected number of PES packets.\nGot: %d\nWant: %d\n", len(pesPkts), len(sizes))
+	}
+	for i, b := range pesPkts {
+		hdr, err := pes.NewPESHeader(b)
+		if err != nil {
+			t.Fatalf("could not parse PES header %d: %v", i, err)
+		}
+		want := bytes.Repeat([]byte{byte(i + 1)}, sizes[i])
+		if !bytes.Equal(hdr.Data(), want) {
+			t.Errorf("did not get expected data for PES %d.\nGot len: %d\nWant len: %d\n", i, len(hdr.Data()), len(want))
+		}
+	}
+}
+
+// TestEncodeSEI checks that the SEI option inserts a user data SEI into
+// access units containing an IDR slice, and only those.
+func TestEncodeSEI(t *testing.T) {
+	Meta = meta.New()
+
+	uuid := [16]byte{0: 0xa0, 15: 0x0f}
+	var n int
+	data := func() []byte {
+		n++
+		return []byte(fmt.Sprintf("ts=%d;site=test", n))
+	}
+
+	var buf bytes.Buffer
+	e, err := NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount), SEI(uuid, data))
+	if err != nil {
+		t.Fatalf("could not create MTS encoder: %v", err)
+	}
+	aus := [][]byte{h264IDR, h264NonIDR, h264NonIDR, h264IDR}
+	for i, au := range aus {
+		_, err = e.Write(au)
+		if err != nil {
+			t.Fatalf("could not write access unit %d: %v", i, err)
+		}
+	}
+
+	clip, err := Extract(buf.Bytes())
+	if err != nil {
+		t.Fatalf("could not extract clip: %v", err)
+	}
+	frames := clip.Frames()
+	if len(frames) != len(aus) {
+		t.Fatalf("did not get expected number of frames.\nGot: %d\nWant: %d\n", len(frames), len(aus))
+	}
+	var idrs int
+	for i, f := range frames {
+		var got []string
+		for _, nal := range codecutil.SplitNALUnits(f.Media) {
+			if nal[0]&0x1f != h264dec.NALTypeSEI {
+				continue
+			}
+			msgs, err := h264.ParseSEI(nal)
+			if err != nil {
+				t.Fatalf("could not parse SEI in frame %d: %v", i, err)
+			}
+			for _, m := range msgs {
+				u, d, ok := m.UserData()
+				if !ok || u != uuid {
+					t.Errorf("did not get expected user data SEI in frame %d: %+v", i, m)
+				}
+				got = append(got, string(d))
+			}
+		}
+
+		var want []string
+		if containsIDR(aus[i]) {
+			idrs++
+			want = []string{fmt.Sprintf("ts=%d;site=test", idrs)}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("did not get expected SEI data for frame %d.\nGot: %q\nWant: %q\n", i, got, want)
+		}
+	}
+
+	_, err = NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), MediaType(EncodePCM), SEI(uuid, data))
+	if !errors.Is(err, ErrUnsupportedMedia) {
+		t.Errorf("did not get expected error for SEI with audio.\nGot: %v\nWant: %v\n", err, ErrUnsupportedMedia)
+	}
+}
+
+// TestEncodePMTVersion checks that the PMT version number is incremented when
+// the metadata changes, and only then, and that each PMT has a valid CRC.
+func TestEncodePMTVersion(t *testing.T) {
+	Meta = meta.New()
+	Meta.Add(LocationKey, "1234,4321,1234")
+
+	dst := &destination{}
+	e, err := NewEncoder(nopCloser{dst}, (*logging.TestLogger)(t), PacketBasedPSI(1))
+	if err != nil {
+		t.Fatalf("could not create MTS encoder: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if i == 2 {
+			Meta.Add(LocationKey, "4321,1234,4321")
+		}
+		_, err = e.Write([]byte{byte(i)})
+		if err != nil {
+			t.Fatalf("could not write data %d: %v", i, err)
+		}
+	}
+
+	var got []byte
+	for i, p := range dst.packets {
+		if pid, _ := PID(p); pid != PmtPid {
+			continue
+		}
+		payload, err := Payload(p)
+		if err != nil {
+			t.Fatalf("could not get payload of packet %d: %v", i, err)
+		}
+		pmt := psi.PSIBytes(payload)
+		err = pmt.CheckCRC()
+		if err != nil {
+			t.Errorf("PMT at packet %d has invalid CRC: %v", i, err)
+		}
+		got = append(got, pmt.Version())
+	}
+	want := []byte{0, 0, 1, 1}
+	if !bytes.Equal(got, want) {
+		t.Errorf("did not get expected PMT versions.\nGot: %v\nWant: %v\n", got, want)
+	}
+}
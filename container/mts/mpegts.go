@@ -24,6 +24,8 @@ package mts
 
 import (
 	"fmt"
+	"maps"
+	"sort"
 
 	"github.com/Comcast/gots/v2/packet"
 	gotspsi "github.com/Comcast/gots/v2/psi"
@@ -37,9 +39,10 @@ const PacketSize = 188
 
 // Standard program IDs for program specific information MPEG-TS packets.
 const (
-	SdtPid = 17
-	PatPid = 0
-	PmtPid = 4096
+	SdtPid  = 17
+	PatPid  = 0
+	PmtPid  = 4096
+	NullPid = 0x1fff
 )
 
 // HeadSize is the size of an MPEG-TS packet header.
@@ -57,6 +60,19 @@ const (
 	DiscontinuityIndicatorIdx  = AdaptationIdx + 1 // The index at which the discontinuity indicator is found in an MTS packet.
 )
 
+// Transport scrambling control values. Values other than TSCNotScrambled
+// indicate a scrambled payload, with the meaning of the remaining values
+// being user defined.
+const (
+	TSCNotScrambled = 0x0
+	TSCReserved     = 0x1
+	TSCEvenKey      = 0x2
+	TSCOddKey       = 0x3
+)
+
+// TSCMask is the mask for the transport scrambling control in octet 3.
+const TSCMask = 0xc0
+
 // TODO: make this better - currently doesn't make sense.
 const (
 	HasPayload         = 0x1
@@ -164,9 +180,11 @@ func FindPat(d []byte) ([]byte, int, error) {
 	return FindPid(d, PatPid)
 }
 
-// Errors used by FindPid.
+// Errors used by FindPid and LastPid. Errors returned for a PID that is not
+// found wrap ErrPIDNotFound and give the PID.
 var (
-	ErrInvalidLen = errors.New("MPEG-TS data not of valid length")
+	ErrInvalidLen  = errors.New("MPEG-TS data not of valid length")
+	ErrPIDNotFound = errors.New("could not find packet with PID")
 )
 
 // FindPid will take a clip of MPEG-TS and try to find a packet with given PID - if one
@@ -182,7 +200,7 @@ func FindPid(d []byte, pid uint16) (pkt []byte, i int, err error) {
 			return
 		}
 	}
-	return nil, -1, fmt.Errorf("could not find packet with PID %d", pid)
+	return nil, -1, fmt.Errorf("%w %d", ErrPIDNotFound, pid)
 }
 
 // LastPid will take a clip of MPEG-TS and try to find a packet
@@ -201,7 +219,7 @@ func LastPid(d []byte, pid uint16) (pkt []byte, i int, err error) {
 			return
 		}
 	}
-	return nil, -1, fmt.Errorf("could not find packet with PID %d", pid)
+	return nil, -1, fmt.Errorf("%w %d", ErrPIDNotFound, pid)
 }
 
 // Errors used by FindPSI.
@@ -213,7 +231,23 @@ var (
 
 // FindPSI finds the index of a PAT in an a slice of MPEG-TS and returns, along
 // with a map of meta from the PMT and the stream PIDs and their types.
+// The PMT must directly follow the PAT, otherwise ErrNotConsecutive is returned.
 func FindPSI(d []byte) (int, map[uint16]uint8, map[string]string, error) {
+	return findPSI(d, false)
+}
+
+// ScanPSI is like FindPSI, but is intended for clips captured mid-stream. It
+// scans forward to the first PAT anywhere in the clip, and then to the first
+// packet carrying the PMT PID given by that PAT, tolerating any packets, such
+// as media, interleaved between the PAT and PMT. The index of the PAT is
+// returned.
+func ScanPSI(d []byte) (int, map[uint16]uint8, map[string]string, error) {
+	return findPSI(d, true)
+}
+
+// findPSI provides the implementation of FindPSI and ScanPSI. If interleaved
+// is false, the PMT must directly follow the PAT.
+func findPSI(d []byte, interleaved bool) (int, map[uint16]uint8, map[string]string, error) {
 	if len(d) < PacketSize {
 		return -1, nil, nil, ErrInvalidLen
 	}
@@ -224,6 +258,13 @@ func FindPSI(d []byte) (int, map[uint16]uint8, map[string]string, error) {
 		return -1, nil, nil, errors.Wrap(err, "error finding PAT")
 	}
 
+	// Tables with a bad CRC would be rejected by players, so reject them here.
+	pat := psi.PSIBytes(pkt[HeadSize:])
+	err = pat.CheckCRC()
+	if err != nil {
+		return i, nil, nil, errors.Wrap(err, "invalid PAT")
+	}
+
 	// Let's take this opportunity to check what programs are in this MPEG-TS
 	// stream, and therefore the PID of the PMT, from which we can get metadata.
 	// NB: currently we only support one program.
@@ -250,11 +291,18 @@ func FindPSI(d []byte) (int, map[uint16]uint8, map[string]string, error) {
 		return i, nil, nil, errors.Wrap(err, "error finding PMT")
 	}
 
-	// Check that the PMT comes straight after the PAT.
-	if pmtIdx != 0 {
+	// Check that the PMT comes straight after the PAT, unless we're tolerating
+	// packets in between.
+	if !interleaved && pmtIdx != 0 {
 		return i, nil, nil, ErrNotConsecutive
 	}
 
+	pmt := psi.PSIBytes(pkt[HeadSize:])
+	err = pmt.CheckCRC()
+	if err != nil {
+		return i, nil, nil, errors.Wrap(err, "invalid PMT")
+	}
+
 	// Now we can try to get meta from the PMT.
 	meta, _ := metaFromPMT(pkt)
 
@@ -321,7 +369,7 @@ func (p *Packet) Bytes(buf []byte) []byte {
 	buf[0] = 0x47
 	buf[1] = (asByte(p.TEI)<<7 | asByte(p.PUSI)<<6 | asByte(p.Priority)<<5 | byte((p.PID&0xFF00)>>8))
 	buf[2] = byte(p.PID & 0x00FF)
-	buf[3] = (p.TSC<<6 | p.AFC<<4 | p.CC)
+	buf[3] = ((p.TSC&0x3)<<6 | p.AFC<<4 | p.CC)
 
 	var maxPayloadSize int
 	if p.AFC&0x2 != 0 {
@@ -414,8 +462,8 @@ func DiscontinuityIndicator(f bool) Option {
 	}
 }
 
-// Error used by GetPTSRange.
-var errNoPTS = errors.New("could not find PTS")
+// ErrNoPTS is returned by GetPTSRange and AllPTS when no PTS is found.
+var ErrNoPTS = errors.New("could not find PTS")
 
 // GetPTSRange retreives the first and last PTS of an MPEGTS clip.
 // If there is only one PTS, it is included twice in the pts return value.
@@ -425,7 +473,7 @@ func GetPTSRange(clip []byte, pid uint16) (pts [2]uint64, err error) {
 	var i int
 	for {
 		if i >= len(clip) {
-			return pts, errNoPTS
+			return pts, ErrNoPTS
 		}
 		pkt, _i, err := FindPid(clip[i:], pid)
 		if err != nil {
@@ -461,6 +509,48 @@ func GetPTSRange(clip []byte, pid uint16) (pts [2]uint64, err error) {
 	return
 }
 
+// AllPTS returns the PTS of every PES packet of the given PID in the MPEG-TS
+// clip, in the order they appear. PES packets without a PTS are skipped. The
+// values are as found in the clip, so may wrap around; see UnwrapPTS.
+func AllPTS(clip []byte, pid uint16) ([]uint64, error) {
+	if len(clip)%PacketSize != 0 {
+		return nil, ErrInvalidLen
+	}
+
+	var pts []uint64
+	for i := 0; i < len(clip); i += PacketSize {
+		pkt := clip[i : i+PacketSize]
+		_pid, _ := PID(pkt)
+		if _pid != pid {
+			continue
+		}
+		_pts, err := GetPTS(pkt)
+		if err != nil {
+			continue
+		}
+		pts = append(pts, uint64(_pts))
+	}
+	if len(pts) == 0 {
+		return nil, ErrNoPTS
+	}
+	return pts, nil
+}
+
+// UnwrapPTS modifies the PTS values in pts, in place, so that they increase
+// monotonically across wraparounds of the 33 bit PTS. A decrease of more than
+// half the PTS range is taken to be a wraparound.
+func UnwrapPTS(pts []uint64) {
+	const ptsRange = MaxPTS + 1
+	var off uint64
+	for i := 1; i < len(pts); i++ {
+		prev := pts[i-1] - off
+		if pts[i] < prev && prev-pts[i] > ptsRange/2 {
+			off += ptsRange
+		}
+		pts[i] += off
+	}
+}
+
 var (
 	errNoPesPayload      = errors.New("no PES payload")
 	errNoPesPTS          = errors.New("no PES PTS")
@@ -504,7 +594,9 @@ func extractPTS(d []byte) int64 {
 	return (int64((d[0]>>1)&0x07) << 30) | (int64(d[1]) << 22) | (int64((d[2]>>1)&0x7f) << 15) | (int64(d[3]) << 7) | int64((d[4]>>1)&0x7f)
 }
 
-var errNoMeta = errors.New("PMT does not contain meta")
+// ErrNoMeta is returned by ExtractMeta when the PMT has no metadata
+// descriptor.
+var ErrNoMeta = errors.New("PMT does not contain meta")
 
 // ExtractMeta returns a map of metadata from the first PMT's metaData
 // descriptor, that is found in the MPEG-TS clip d. d must contain a series of
@@ -525,62 +617,113 @@ func metaFromPMT(d []byte) (m map[string]string, err error) {
 	// Get the metadata descriptor.
 	_, desc := pmt.HasDescriptor(psi.MetadataTag)
 	if desc == nil {
-		return m, errNoMeta
+		return m, ErrNoMeta
 	}
 	// Get the metadata as a map, skipping the descriptor head.
 	return meta.GetAllAsMap(desc[2:])
 }
 
-// TrimToMetaRange trims a slice of MPEG-TS to a segment between two points of
-// meta data described by key, from and to.
-func TrimToMetaRange(d []byte, key, from, to string) ([]byte, error) {
+// Errors used by SetMeta.
+var (
+	ErrMetaTooLarge   = errors.New("PMT with meta does not fit in packet")
+	ErrPMTSpansPacket = errors.New("PMT does not start at beginning of packet payload")
+)
+
+// SetMeta returns a copy of the MPEG-TS clip d in which the metadata
+// descriptor of each PMT has the entries of m added, replacing the values of
+// any existing entries with the same keys. A descriptor is created for PMTs
+// without one. The section length and CRC of each PMT are recomputed. Each PMT
+// must be contained in a single packet and remains so, so no packets are added
+// or removed and continuity counters are unaffected.
+func SetMeta(d []byte, m map[string]string) ([]byte, error) {
 	if len(d)%PacketSize != 0 {
-		return nil, errors.New("MTS clip is not of valid size")
+		return nil, ErrInvalidLen
 	}
 
-	if from == to {
-		return nil, errors.New("'from' and 'to' cannot be identical")
+	// Add the new keys in a consistent order after any existing keys.
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	var (
-		start = -1 // Index of the start of the segment in d.
-		end   = -1 // Index of the end of segment in d.
-		off   int  // Index of remaining slice of d to check after each PMT found.
-	)
+	out := append([]byte(nil), d...)
+	for _, i := range pmtIndices(out) {
+		pkt := out[i : i+PacketSize]
+		if pkt[1]&0x40 == 0 {
+			continue
+		}
 
-	for {
-		// Find the next PMT.
-		pmt, idx, err := FindPid(d[off:], PmtPid)
+		payload, err := Payload(pkt)
 		if err != nil {
-			switch -1 {
-			case start:
-				return nil, errMetaLowerBound
-			case end:
-				return nil, errMetaUpperBound
-			default:
-				panic("should not have got error from FindPid")
+			return nil, fmt.Errorf("could not get payload of PMT at packet %d: %w", i/PacketSize, err)
+		}
+		if len(payload) < 4 || payload[0] != 0 {
+			return nil, ErrPMTSpansPacket
+		}
+		table := psi.PSIBytes(append([]byte(nil), payload[:4+psi.SyntaxSecLenFrom(payload)]...))
+
+		var entries [][2]string
+		_, desc := table.HasDescriptor(psi.MetadataTag)
+		if desc != nil {
+			entries, err = meta.GetAll(desc[2:])
+			if err != nil {
+				return nil, fmt.Errorf("could not get meta of PMT at packet %d: %w", i/PacketSize, err)
 			}
 		}
-		off += idx + PacketSize
+		for _, k := range keys {
+			entries = append(entries, [2]string{k, m[k]})
+		}
+
+		err = table.AddDescriptor(psi.MetadataTag, meta.NewWith(entries).Encode())
+		if err != nil {
+			return nil, fmt.Errorf("could not set meta of PMT at packet %d: %w", i/PacketSize, err)
+		}
+		if len(table) > len(payload) {
+			return nil, ErrMetaTooLarge
+		}
+		n := copy(payload, table)
+		for j := range payload[n:] {
+			payload[n+j] = 0xff
+		}
+	}
+	return out, nil
+}
 
-		meta, err := ExtractMeta(pmt)
+// TrimToMetaRange trims a slice of MPEG-TS to a segment between two points of
+// meta data described by key, from and to.
+func TrimToMetaRange(d []byte, key, from, to string) ([]byte, error) {
+	if len(d)%PacketSize != 0 {
+		return nil, ErrInvalidLen
+	}
+
+	if from == to {
+		return nil, errors.New("'from' and 'to' cannot be identical")
+	}
+
+	start := -1 // Index of the start of the segment in d.
+	for _, i := range pmtIndices(d) {
+		_meta, err := metaFromPMT(d[i : i+PacketSize])
 		switch err {
 		case nil: // do nothing
-		case errNoMeta:
+		case ErrNoMeta:
 			continue
 		default:
 			return nil, err
 		}
 
 		if start == -1 {
-			if meta[key] == from {
-				start = off - PacketSize
+			if _meta[key] == from {
+				start = i
 			}
-		} else if meta[key] == to {
-			end = off
-			return d[start:end], nil
+		} else if _meta[key] == to {
+			return d[start : i+PacketSize], nil
 		}
 	}
+	if start == -1 {
+		return nil, errMetaLowerBound
+	}
+	return nil, errMetaUpperBound
 }
 
 // SegmentForMeta returns segments of MTS slice d that correspond to a value of
@@ -588,41 +731,37 @@ func TrimToMetaRange(d []byte, key, from, to string) ([]byte, error) {
 // key and val will be appended to the returned [][]byte.
 func SegmentForMeta(d []byte, key, val string) ([][]byte, error) {
 	var (
-		pkt        packet.Packet // We copy data to this so that we can use comcast gots stuff.
-		segmenting bool          // If true we are currently in a segment corresponsing to given meta.
-		res        [][]byte      // The resultant [][]byte holding the segments.
-		start      int           // The start index of the current segment.
+		segmenting bool     // If true we are currently in a segment corresponsing to given meta.
+		res        [][]byte // The resultant [][]byte holding the segments.
+		start      int      // The start index of the current segment.
 	)
 
-	// Go through packets.
-	for i := 0; i < len(d); i += PacketSize {
-		copy(pkt[:], d[i:i+PacketSize])
-		if pkt.PID() == PmtPid {
-			_meta, err := ExtractMeta(pkt[:])
-			switch err {
-			// If there's no meta or a problem with meta, we consider this the end
-			// of the segment.
-			case errNoMeta, meta.ErrUnexpectedMetaFormat:
-				if segmenting {
-					res = append(res, d[start:i])
-					segmenting = false
-				}
-				continue
-			case nil: // do nothing.
-			default:
-				return nil, err
-			}
-
-			// If we've got the meta of interest in the PMT and we're not segmenting
-			// then start segmenting. If we don't have the meta of interest in the PMT
-			// and we are segmenting then we want to stop and append the segment to result.
-			if _meta[key] == val && !segmenting {
-				start = i
-				segmenting = true
-			} else if _meta[key] != val && segmenting {
+	// Go through PMTs.
+	for _, i := range pmtIndices(d) {
+		_meta, err := metaFromPMT(d[i : i+PacketSize])
+		switch err {
+		// If there's no meta or a problem with meta, we consider this the end
+		// of the segment.
+		case ErrNoMeta, meta.ErrUnexpectedMetaFormat:
+			if segmenting {
 				res = append(res, d[start:i])
 				segmenting = false
 			}
+			continue
+		case nil: // do nothing.
+		default:
+			return nil, err
+		}
+
+		// If we've got the meta of interest in the PMT and we're not segmenting
+		// then start segmenting. If we don't have the meta of interest in the PMT
+		// and we are segmenting then we want to stop and append the segment to result.
+		if _meta[key] == val && !segmenting {
+			start = i
+			segmenting = true
+		} else if _meta[key] != val && segmenting {
+			res = append(res, d[start:i])
+			segmenting = false
 		}
 	}
 
@@ -635,14 +774,89 @@ func SegmentForMeta(d []byte, key, val string) ([][]byte, error) {
 	return res, nil
 }
 
+// MetaState describes the metadata carried by a PMT in an MPEG-TS clip.
+type MetaState struct {
+	Packet int               // Index of the PMT packet in the clip, in packets.
+	Meta   map[string]string // Metadata of the PMT; empty if the PMT has none.
+}
+
+// MetaTimeline returns the distinct metadata states of the PMTs in the MPEG-TS
+// clip d, in order. A state is only included when it differs from the state
+// of the PMT before it, so repeated PSI with unchanged metadata are omitted.
+// d must contain a series of complete MPEG-TS packets.
+func MetaTimeline(d []byte) ([]MetaState, error) {
+	if len(d)%PacketSize != 0 {
+		return nil, ErrInvalidLen
+	}
+
+	var res []MetaState
+	for _, i := range pmtIndices(d) {
+		_meta, err := metaFromPMT(d[i : i+PacketSize])
+		switch err {
+		case nil: // do nothing.
+		case ErrNoMeta, meta.ErrUnexpectedMetaFormat:
+			_meta = map[string]string{}
+		default:
+			return nil, err
+		}
+
+		if len(res) != 0 && maps.Equal(res[len(res)-1].Meta, _meta) {
+			continue
+		}
+		res = append(res, MetaState{Packet: i / PacketSize, Meta: _meta})
+	}
+	return res, nil
+}
+
+// pmtIndices returns the indices, in bytes, of the PMT packets in the MPEG-TS
+// clip d, in order. PMTs are identified by PmtPid, and by the PMT PIDs given by
+// any PAT earlier in d.
+func pmtIndices(d []byte) []int {
+	var (
+		idx     []int
+		pmtPIDs = map[uint16]bool{PmtPid: true}
+	)
+	for i := 0; i+PacketSize <= len(d); i += PacketSize {
+		pkt := d[i : i+PacketSize]
+		pid, _ := PID(pkt)
+		if pid == PatPid {
+			progs, err := Programs(pkt)
+			if err == nil {
+				for _, p := range progs {
+					pmtPIDs[p] = true
+				}
+			}
+			continue
+		}
+		if pmtPIDs[pid] {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
 // PID returns the packet identifier for the given packet.
 func PID(p []byte) (uint16, error) {
 	if len(p) < PacketSize {
-		return 0, errors.New("packet length less than 188")
+		return 0, ErrShortPacket
 	}
 	return uint16(p[1]&0x1f)<<8 | uint16(p[2]), nil
 }
 
+// PIDCounts returns the number of packets of each PID in the MPEG-TS clip,
+// keyed by PID.
+func PIDCounts(clip []byte) (map[uint16]int, error) {
+	if len(clip)%PacketSize != 0 {
+		return nil, ErrInvalidLen
+	}
+	counts := make(map[uint16]int)
+	for i := 0; i < len(clip); i += PacketSize {
+		pid, _ := PID(clip[i : i+PacketSize])
+		counts[pid]++
+	}
+	return counts, nil
+}
+
 // Programs returns a map of program numbers and corresponding PMT PIDs for a
 // given MPEG-TS PAT packet.
 func Programs(p []byte) (map[uint16]uint16, error) {
@@ -671,6 +885,13 @@ func Streams(p []byte) ([]gotspsi.PmtElementaryStream, error) {
 	return pmt.ElementaryStreams(), nil
 }
 
+// Errors used by MediaStreams.
+var (
+	ErrShortPSI = errors.New("PSI is not two packets or more long")
+	ErrNotPAT   = errors.New("first packet is not a PAT")
+	ErrNotPMT   = errors.New("second packet is not desired PMT")
+)
+
 // MediaStreams retrieves the PmtElementaryStreams from the given PSI. This
 // function currently assumes that PSI contain a PAT followed by a PMT directly
 // after. We also assume that this MPEG-TS stream contains just one program,
@@ -678,14 +899,14 @@ func Streams(p []byte) ([]gotspsi.PmtElementaryStream, error) {
 // stream.
 func MediaStreams(p []byte) ([]gotspsi.PmtElementaryStream, error) {
 	if len(p) < 2*PacketSize {
-		return nil, errors.New("PSI is not two packets or more long")
+		return nil, ErrShortPSI
 	}
 	pat := p[:PacketSize]
 	pmt := p[PacketSize : 2*PacketSize]
 
 	pid, _ := PID(pat)
 	if pid != PatPid {
-		return nil, errors.New("first packet is not a PAT")
+		return nil, ErrNotPAT
 	}
 
 	m, err := Programs(pat)
@@ -703,7 +924,7 @@ func MediaStreams(p []byte) ([]gotspsi.PmtElementaryStream, error) {
 
 	pid, _ = PID(pmt)
 	if pid != pmtPIDs(m)[0] {
-		return nil, errors.New("second packet is not desired PMT")
+		return nil, ErrNotPMT
 	}
 
 	s, err := Streams(pmt)
@@ -724,21 +945,56 @@ func pmtPIDs(m map[uint16]uint16) []uint16 {
 }
 
 // Errors used by Payload.
-var ErrNoPayload = errors.New("no payload")
+var (
+	ErrNoPayload              = errors.New("no payload")
+	ErrScrambled              = errors.New("payload is scrambled")
+	ErrShortPacket            = errors.New("packet shorter than MPEG-TS packet size")
+	ErrInvalidAdaptationField = errors.New("adaptation field extends past end of packet")
+)
+
+// IsScrambled returns true if the transport scrambling control of the MPEG-TS
+// packet p indicates that its payload is scrambled. ErrShortPacket is returned
+// if p is shorter than PacketSize.
+func IsScrambled(p []byte) (bool, error) {
+	if len(p) < PacketSize {
+		return false, ErrShortPacket
+	}
+	return p[AdaptationControlIdx]&TSCMask != 0, nil
+}
 
-// Payload returns the payload of an MPEG-TS packet p.
+// TSC returns the transport scrambling control of the MPEG-TS packet p.
+// ErrShortPacket is returned if p is shorter than PacketSize.
+func TSC(p []byte) (byte, error) {
+	if len(p) < PacketSize {
+		return 0, ErrShortPacket
+	}
+	return (p[AdaptationControlIdx] & TSCMask) >> 6, nil
+}
+
+// Payload returns the payload of an MPEG-TS packet p. ErrScrambled is returned
+// if the payload is scrambled, since it cannot be interpreted. ErrShortPacket is
+// returned if p is shorter than PacketSize.
 // NB: this is not a copy of the payload in the interests of performance.
 // TODO: offer function that will do copy if we have interests in safety.
 func Payload(p []byte) ([]byte, error) {
+	if len(p) < PacketSize {
+		return nil, ErrShortPacket
+	}
 	c := byte((p[3] & 0x30) >> 4)
 	if c == 2 {
 		return nil, ErrNoPayload
 	}
+	if scrambled, _ := IsScrambled(p); scrambled {
+		return nil, ErrScrambled
+	}
 
 	// Check if there is an adaptation field.
-	off := 4
-	if p[3]&0x20 == 1 {
-		off = int(5 + p[4])
+	off := HeadSize
+	if p[3]&0x20 != 0 {
+		off = HeadSize + 1 + int(p[4])
+		if off > PacketSize {
+			return nil, ErrInvalidAdaptationField
+		}
 	}
-	return p[off:], nil
+	return p[off:PacketSize], nil
 }
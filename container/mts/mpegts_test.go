@@ -159,6 +159,59 @@ func writeFrame(b *bytes.Buffer, frame []byte, pts uint64) error {
 	return nil
 }
 
+// TestAllPTS checks that AllPTS returns the PTS of each frame of a clip, and
+// that UnwrapPTS makes PTS monotonic across a wraparound.
+func TestAllPTS(t *testing.T) {
+	const (
+		numOfFrames = 10
+		interval    = 3600 // PTS ticks per frame, i.e. 25fps.
+		start       = MaxPTS - 4*interval
+	)
+
+	var clip bytes.Buffer
+	err := writePSI(&clip)
+	if err != nil {
+		t.Fatalf("did not expect error writing psi: %v", err)
+	}
+
+	// Frames of varying size, such that some span several packets, with PTS
+	// wrapping around after the fourth frame.
+	var want, wantUnwrapped []uint64
+	for i := 0; i < numOfFrames; i++ {
+		pts := uint64(start + (i+1)*interval)
+		wantUnwrapped = append(wantUnwrapped, pts)
+		pts &= MaxPTS
+		want = append(want, pts)
+
+		err = writeFrame(&clip, make([]byte, 100+i*100), pts)
+		if err != nil {
+			t.Fatalf("did not expect error writing frame: %v", err)
+		}
+	}
+
+	got, err := AllPTS(clip.Bytes(), PIDVideo)
+	if err != nil {
+		t.Fatalf("did not expect error getting PTS: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("did not get expected PTS.\nGot: %v\nWant: %v\n", got, want)
+	}
+
+	UnwrapPTS(got)
+	if !reflect.DeepEqual(got, wantUnwrapped) {
+		t.Errorf("did not get expected unwrapped PTS.\nGot: %v\nWant: %v\n", got, wantUnwrapped)
+	}
+
+	_, err = AllPTS(clip.Bytes(), PIDAudio)
+	if err != ErrNoPTS {
+		t.Errorf("did not get expected error for PID with no PTS.\nGot: %v\nWant: %v\n", err, ErrNoPTS)
+	}
+	_, err = AllPTS(clip.Bytes()[1:], PIDVideo)
+	if err != ErrInvalidLen {
+		t.Errorf("did not get expected error for invalid length.\nGot: %v\nWant: %v\n", err, ErrInvalidLen)
+	}
+}
+
 // TestGetPTSRange2 checks that GetPTSRange behaves correctly with cases where
 // the first instance of a PID is not a payload start, and also where there
 // are no payload starts.
@@ -193,7 +246,7 @@ func TestGetPTSRange2(t *testing.T) {
 			[]uint16{0, 0, 1, 1, 1, 1, 1, 1},
 			[]uint64{0, 0, 0, 0, 0, 0, 0, 0},
 			[2]uint64{0, 0},
-			errNoPTS,
+			ErrNoPTS,
 		},
 	}
 
@@ -561,6 +614,7 @@ func TestFindPSI(t *testing.T) {
 	tests := []struct {
 		pkts []int
 		meta string
+		scan bool
 		want want
 	}{
 		{
@@ -602,6 +656,60 @@ func TestFindPSI(t *testing.T) {
 				err: ErrNotConsecutive,
 			},
 		},
+		{
+			pkts: []int{pat, media, pmt, media, media},
+			meta: "1",
+			scan: true,
+			want: want{
+				idx:        0,
+				streamType: gotspsi.PmtStreamTypeMpeg4Video,
+				streamPID:  4,
+				meta: map[string]string{
+					"key": "1",
+				},
+				err: nil,
+			},
+		},
+		{
+			pkts: []int{media, media, media, pat, media, media, pmt, media},
+			meta: "2",
+			scan: true,
+			want: want{
+				idx:        3 * PacketSize,
+				streamType: gotspsi.PmtStreamTypeMpeg4Video,
+				streamPID:  4,
+				meta: map[string]string{
+					"key": "2",
+				},
+				err: nil,
+			},
+		},
+		{
+			pkts: []int{media, pat, pmt, media, pat, media, pmt},
+			meta: "3",
+			scan: true,
+			want: want{
+				idx:        PacketSize,
+				streamType: gotspsi.PmtStreamTypeMpeg4Video,
+				streamPID:  4,
+				meta: map[string]string{
+					"key": "3",
+				},
+				err: nil,
+			},
+		},
+		{
+			pkts: []int{media, media, pat, media, media},
+			meta: "1",
+			scan: true,
+			want: want{err: ErrPIDNotFound},
+		},
+		{
+			pkts: []int{media, media, pmt, media},
+			meta: "1",
+			scan: true,
+			want: want{err: ErrPIDNotFound},
+		},
 	}
 
 	var clip bytes.Buffer
@@ -714,10 +822,14 @@ func TestFindPSI(t *testing.T) {
 			}
 		}
 
-		gotIdx, gotStreams, gotMeta, gotErr := FindPSI(clip.Bytes())
+		find := FindPSI
+		if test.scan {
+			find = ScanPSI
+		}
+		gotIdx, gotStreams, gotMeta, gotErr := find(clip.Bytes())
 
 		// Check error.
-		if errors.Cause(gotErr) != test.want.err {
+		if !errors.Is(gotErr, test.want.err) {
 			t.Errorf("did not get expected error for test %d\nGot: %v\nWant: %v\n", i, gotErr, test.want.err)
 		}
 
@@ -761,3 +873,479 @@ func TestFindPSI(t *testing.T) {
 		}
 	}
 }
+
+// TestFindPSIBadCRC checks that FindPSI rejects a PAT or PMT with a CRC that
+// does not match the table.
+func TestFindPSIBadCRC(t *testing.T) {
+	prog := psi.Program{
+		Number:  1,
+		PMTPID:  PmtPid,
+		PCRPID:  PIDVideo,
+		Streams: []psi.Stream{{Type: pes.H264SID, PID: PIDVideo}},
+	}
+	pat, err := psi.BuildPAT(prog)
+	if err != nil {
+		t.Fatalf("could not build PAT: %v", err)
+	}
+	pmt, err := psi.BuildPMT(prog)
+	if err != nil {
+		t.Fatalf("could not build PMT: %v", err)
+	}
+
+	clip := func() []byte {
+		var buf bytes.Buffer
+		for _, p := range []Packet{
+			{PUSI: true, PID: PatPid, AFC: HasPayload, Payload: psi.AddPadding(pat)},
+			{PUSI: true, PID: PmtPid, AFC: HasPayload, Payload: psi.AddPadding(pmt)},
+		} {
+			buf.Write(p.Bytes(nil))
+		}
+		return buf.Bytes()
+	}
+
+	_, _, _, err = FindPSI(clip())
+	if err != nil {
+		t.Fatalf("did not expect error for valid PSI: %v", err)
+	}
+
+	// Corrupt the program map PID in the PAT.
+	pat[12]++
+	_, _, _, err = FindPSI(clip())
+	if errors.Cause(err) != psi.ErrInvalidCRC {
+		t.Errorf("did not get expected error for corrupted PAT.\nGot: %v\nWant: %v\n", err, psi.ErrInvalidCRC)
+	}
+	pat[12]--
+
+	// Corrupt the stream type in the PMT.
+	pmt[13] = pes.H265SID
+	_, _, _, err = FindPSI(clip())
+	if errors.Cause(err) != psi.ErrInvalidCRC {
+		t.Errorf("did not get expected error for corrupted PMT.\nGot: %v\nWant: %v\n", err, psi.ErrInvalidCRC)
+	}
+}
+
+// TestTSCRoundTrip checks that the transport scrambling control of a Packet is
+// preserved through Bytes, and that scrambled payloads are flagged by Payload.
+func TestTSCRoundTrip(t *testing.T) {
+	payload := []byte{0x01, 0x02, 0x03, 0x04}
+	for _, tsc := range []byte{TSCNotScrambled, TSCReserved, TSCEvenKey, TSCOddKey} {
+		pkt := Packet{
+			PUSI:    true,
+			PID:     PIDVideo,
+			TSC:     tsc,
+			CC:      5,
+			AFC:     HasAdaptationField | HasPayload,
+			Payload: payload,
+		}
+		b := pkt.Bytes(nil)
+
+		if got, _ := TSC(b); got != tsc {
+			t.Errorf("did not get expected TSC.\nGot: %d\nWant: %d\n", got, tsc)
+		}
+
+		var gotsPkt packet.Packet
+		copy(gotsPkt[:], b)
+		if got := byte(gotsPkt.TransportScramblingControl()); got != tsc {
+			t.Errorf("did not get expected TSC from gots.\nGot: %d\nWant: %d\n", got, tsc)
+		}
+
+		// Other header fields should be unaffected by the TSC.
+		if pid, _ := PID(b); pid != PIDVideo || b[3]&0x0f != 5 || (b[3]&AdaptationControlMask)>>4 != HasAdaptationField|HasPayload {
+			t.Errorf("header corrupted for TSC %d: %#v", tsc, b[:4])
+		}
+
+		if scrambled, _ := IsScrambled(b); scrambled != (tsc != TSCNotScrambled) {
+			t.Errorf("unexpected result from IsScrambled for TSC %d", tsc)
+		}
+		_, err := Payload(b)
+		if tsc != TSCNotScrambled && err != ErrScrambled {
+			t.Errorf("did not get expected error from Payload for TSC %d.\nGot: %v\nWant: %v\n", tsc, err, ErrScrambled)
+		}
+	}
+
+	short := make([]byte, AdaptationControlIdx)
+	_, err := TSC(short)
+	if err != ErrShortPacket {
+		t.Errorf("did not get expected error from TSC for short packet.\nGot: %v\nWant: %v\n", err, ErrShortPacket)
+	}
+	_, err = IsScrambled(short)
+	if err != ErrShortPacket {
+		t.Errorf("did not get expected error from IsScrambled for short packet.\nGot: %v\nWant: %v\n", err, ErrShortPacket)
+	}
+}
+
+// TestPayload checks that Payload returns the payload following any
+// adaptation field, and returns errors for short or malformed packets.
+func TestPayload(t *testing.T) {
+	// newPacket returns a packet with the given adaptation field control and
+	// adaptation field length, and payload bytes counting up from 0.
+	newPacket := func(afc, afl byte) []byte {
+		p := make([]byte, PacketSize)
+		p[0] = 0x47
+		p[3] = afc << 4
+		off := HeadSize
+		if afc&HasAdaptationField != 0 {
+			p[4] = afl
+			off += 1 + int(afl)
+		}
+		for i := off; i < PacketSize; i++ {
+			p[i] = byte(i - off)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		pkt     []byte
+		wantLen int
+		wantErr error
+	}{
+		{name: "payload only", pkt: newPacket(HasPayload, 0), wantLen: PacketSize - HeadSize},
+		{name: "adaptation field and payload", pkt: newPacket(HasAdaptationField|HasPayload, 7), wantLen: PacketSize - HeadSize - 8},
+		{name: "empty adaptation field", pkt: newPacket(HasAdaptationField|HasPayload, 0), wantLen: PacketSize - HeadSize - 1},
+		{name: "adaptation field only", pkt: newPacket(HasAdaptationField, PacketSize-HeadSize-1), wantErr: ErrNoPayload},
+		{name: "adaptation field too long", pkt: newPacket(HasAdaptationField|HasPayload, PacketSize-HeadSize), wantErr: ErrInvalidAdaptationField},
+		{name: "short packet", pkt: newPacket(HasPayload, 0)[:HeadSize], wantErr: ErrShortPacket},
+		{name: "empty packet", pkt: nil, wantErr: ErrShortPacket},
+	}
+
+	for _, test := range tests {
+		got, err := Payload(test.pkt)
+		if err != test.wantErr {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, test.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(got) != test.wantLen {
+			t.Errorf("did not get expected payload length for test %q.\nGot: %d\nWant: %d\n", test.name, len(got), test.wantLen)
+		}
+		for i, b := range got {
+			if b != byte(i) {
+				t.Errorf("payload for test %q includes adaptation field bytes: %v", test.name, got)
+				break
+			}
+		}
+	}
+}
+
+// TestMetaTimeline checks that MetaTimeline returns each distinct metadata
+// state, and the index of the PMT at which it begins, for a clip with changing
+// metadata.
+func TestMetaTimeline(t *testing.T) {
+	const (
+		preambleKey = "copyright"
+		preambleVal = "ausocean.org/license/content2019"
+		key         = "loc"
+	)
+
+	Meta = meta.NewWith([][2]string{{preambleKey, preambleVal}})
+
+	// Metadata values for each PSI pair written; an empty string means the key
+	// is deleted.
+	vals := []string{"1,2", "1,2", "3,4", "", "", "3,4", "5,6", "5,6"}
+
+	var clip bytes.Buffer
+	for _, v := range vals {
+		if v != "" {
+			Meta.Add(key, v)
+		} else {
+			Meta.Delete(key)
+		}
+		err := writePSIWithMeta(&clip, t)
+		if err != nil {
+			t.Fatalf("did not expect error writing PSI: %v", err)
+		}
+	}
+
+	// Each PSI is a PAT followed by a PMT, so the PMT of the nth PSI is packet
+	// 2n+1.
+	want := []MetaState{
+		{Packet: 1, Meta: map[string]string{preambleKey: preambleVal, key: "1,2"}},
+		{Packet: 5, Meta: map[string]string{preambleKey: preambleVal, key: "3,4"}},
+		{Packet: 7, Meta: map[string]string{preambleKey: preambleVal}},
+		{Packet: 11, Meta: map[string]string{preambleKey: preambleVal, key: "3,4"}},
+		{Packet: 13, Meta: map[string]string{preambleKey: preambleVal, key: "5,6"}},
+	}
+
+	got, err := MetaTimeline(clip.Bytes())
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("did not get expected timeline.\nGot: %v\nWant: %v\n", got, want)
+	}
+
+	_, err = MetaTimeline(clip.Bytes()[:PacketSize+1])
+	if err != ErrInvalidLen {
+		t.Errorf("did not get expected error for invalid length.\nGot: %v\nWant: %v\n", err, ErrInvalidLen)
+	}
+}
+
+// TestSentinelErrors checks that errors returned for common failures can be
+// identified using errors.Is.
+func TestSentinelErrors(t *testing.T) {
+	var clip bytes.Buffer
+	err := writePSI(&clip)
+	if err != nil {
+		t.Fatalf("did not expect error writing PSI: %v", err)
+	}
+	psi := clip.Bytes()
+
+	_, _, findErr := FindPid(psi, PIDVideo)
+	_, _, lastErr := LastPid(psi, PIDVideo)
+	_, rangeErr := GetPTSRange(psi, PIDVideo)
+	_, pidErr := PID(psi[:PacketSize-1])
+	_, extractErr := Extract(psi[:PacketSize+1])
+	_, streamsErr := MediaStreams(psi[:PacketSize])
+	_, patErr := MediaStreams(append(append([]byte{}, psi[PacketSize:2*PacketSize]...), psi[:PacketSize]...))
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "FindPid", err: findErr, want: ErrPIDNotFound},
+		{name: "LastPid", err: lastErr, want: ErrPIDNotFound},
+		{name: "GetPTSRange", err: rangeErr, want: ErrPIDNotFound},
+		{name: "PID", err: pidErr, want: ErrShortPacket},
+		{name: "Extract", err: extractErr, want: ErrInvalidLen},
+		{name: "MediaStreams short", err: streamsErr, want: ErrShortPSI},
+		{name: "MediaStreams no PAT", err: patErr, want: ErrNotPAT},
+	}
+	for _, test := range tests {
+		if !errors.Is(test.err, test.want) {
+			t.Errorf("did not get expected error for %s.\nGot: %v\nWant: %v\n", test.name, test.err, test.want)
+		}
+	}
+
+	// The PID should still be given by the error message.
+	want := "could not find packet with PID " + strconv.Itoa(PIDVideo)
+	if findErr == nil || findErr.Error() != want {
+		t.Errorf("did not get expected error message.\nGot: %v\nWant: %v\n", findErr, want)
+	}
+}
+
+// TestPIDCounts checks that PIDCounts gives the number of packets of each PID
+// in a clip.
+func TestPIDCounts(t *testing.T) {
+	want := map[uint16]int{PatPid: 3, PmtPid: 3, PIDVideo: 10, PIDAudio: 4, NullPid: 1}
+
+	// Interleave the packets of each PID.
+	var clip []byte
+	remaining := make(map[uint16]int)
+	for pid, n := range want {
+		remaining[pid] = n
+	}
+	for len(remaining) != 0 {
+		for _, pid := range []uint16{PatPid, PmtPid, PIDVideo, PIDAudio, NullPid} {
+			if remaining[pid] == 0 {
+				delete(remaining, pid)
+				continue
+			}
+			p := Packet{PID: pid, AFC: HasPayload, Payload: []byte{0x00}}
+			clip = append(clip, p.Bytes(nil)...)
+			remaining[pid]--
+		}
+	}
+
+	got, err := PIDCounts(clip)
+	if err != nil {
+		t.Fatalf("did not expect error counting PIDs: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("did not get expected counts.\nGot: %v\nWant: %v\n", got, want)
+	}
+
+	got, err = PIDCounts(nil)
+	if err != nil {
+		t.Fatalf("did not expect error for empty clip: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no counts for empty clip, got: %v", got)
+	}
+
+	_, err = PIDCounts(clip[:len(clip)-1])
+	if err != ErrInvalidLen {
+		t.Errorf("did not get expected error for invalid length.\nGot: %v\nWant: %v\n", err, ErrInvalidLen)
+	}
+}
+
+// TestSetMeta checks that SetMeta adds metadata to each PMT of a clip, with or
+// without existing metadata, leaving other packets unchanged.
+func TestSetMeta(t *testing.T) {
+	prog := psi.Program{
+		Number:  1,
+		PMTPID:  PmtPid,
+		PCRPID:  PIDVideo,
+		Streams: []psi.Stream{{Type: pes.H264SID, PID: PIDVideo}},
+	}
+	pat, err := psi.BuildPAT(prog)
+	if err != nil {
+		t.Fatalf("could not build PAT: %v", err)
+	}
+	pmt, err := psi.BuildPMT(prog)
+	if err != nil {
+		t.Fatalf("could not build PMT: %v", err)
+	}
+
+	// A clip without metadata, and a clip with existing metadata.
+	var noMeta, withMeta bytes.Buffer
+	Meta = meta.NewWith([][2]string{{"loc", "here"}, {"src", "old"}})
+	for i := 0; i < 3; i++ {
+		for _, p := range []Packet{
+			{PUSI: true, PID: PatPid, CC: byte(i), AFC: HasPayload, Payload: psi.AddPadding(pat)},
+			{PUSI: true, PID: PmtPid, CC: byte(i), AFC: HasPayload, Payload: psi.AddPadding(pmt)},
+		} {
+			noMeta.Write(p.Bytes(nil))
+		}
+		err = writePSIWithMeta(&withMeta, t)
+		if err != nil {
+			t.Fatalf("could not write PSI: %v", err)
+		}
+		for _, b := range []*bytes.Buffer{&noMeta, &withMeta} {
+			err = writeFrame(b, make([]byte, 300), uint64(i*3600))
+			if err != nil {
+				t.Fatalf("could not write frame: %v", err)
+			}
+		}
+	}
+
+	add := map[string]string{"src": "new", "by": "test"}
+	tests := []struct {
+		name string
+		clip []byte
+		want map[string]string
+	}{
+		{name: "no meta", clip: noMeta.Bytes(), want: map[string]string{"src": "new", "by": "test"}},
+		{name: "with meta", clip: withMeta.Bytes(), want: map[string]string{"loc": "here", "src": "new", "by": "test"}},
+	}
+	for _, test := range tests {
+		got, err := SetMeta(test.clip, add)
+		if err != nil {
+			t.Fatalf("did not expect error for test %q: %v", test.name, err)
+		}
+		if len(got) != len(test.clip) {
+			t.Fatalf("did not get expected length for test %q.\nGot: %d\nWant: %d\n", test.name, len(got), len(test.clip))
+		}
+
+		for i := 0; i < len(got); i += PacketSize {
+			pid, _ := PID(got[i:])
+			switch pid {
+			case PmtPid:
+				m, err := ExtractMeta(got[i : i+PacketSize])
+				if err != nil {
+					t.Fatalf("could not extract meta for test %q at packet %d: %v", test.name, i/PacketSize, err)
+				}
+				if !reflect.DeepEqual(m, test.want) {
+					t.Errorf("did not get expected meta for test %q at packet %d.\nGot: %v\nWant: %v\n", test.name, i/PacketSize, m, test.want)
+				}
+				if got[i+3] != test.clip[i+3] {
+					t.Errorf("header changed for test %q at packet %d", test.name, i/PacketSize)
+				}
+			default:
+				if !bytes.Equal(got[i:i+PacketSize], test.clip[i:i+PacketSize]) {
+					t.Errorf("non-PMT packet changed for test %q at packet %d", test.name, i/PacketSize)
+				}
+			}
+		}
+
+		// The PSI should still be valid, including the CRC.
+		_, streams, m, err := FindPSI(got)
+		if err != nil {
+			t.Fatalf("did not expect error finding PSI for test %q: %v", test.name, err)
+		}
+		if len(streams) != 1 {
+			t.Errorf("did not get expected streams for test %q: %v", test.name, streams)
+		}
+		if !reflect.DeepEqual(m, test.want) {
+			t.Errorf("did not get expected meta from FindPSI for test %q.\nGot: %v\nWant: %v\n", test.name, m, test.want)
+		}
+	}
+
+	_, err = SetMeta(noMeta.Bytes(), map[string]string{"big": string(bytes.Repeat([]byte{'x'}, 200))})
+	if err == nil {
+		t.Errorf("expected error for oversized meta")
+	}
+	_, err = SetMeta(noMeta.Bytes()[1:], add)
+	if err != ErrInvalidLen {
+		t.Errorf("did not get expected error for invalid length.\nGot: %v\nWant: %v\n", err, ErrInvalidLen)
+	}
+}
+
+// TestMetaPMTPID checks that MetaTimeline, SegmentForMeta and TrimToMetaRange
+// find PMTs on the PMT PID given by the PAT, rather than only on PmtPid.
+func TestMetaPMTPID(t *testing.T) {
+	const pmtPID = 0x20
+	prog := psi.Program{
+		Number:  1,
+		PMTPID:  pmtPID,
+		PCRPID:  PIDVideo,
+		Streams: []psi.Stream{{Type: pes.H264SID, PID: PIDVideo}},
+	}
+	pat, err := psi.BuildPAT(prog)
+	if err != nil {
+		t.Fatalf("could not build PAT: %v", err)
+	}
+	pmt, err := psi.BuildPMT(prog)
+	if err != nil {
+		t.Fatalf("could not build PMT: %v", err)
+	}
+
+	// Each part of the clip is a PAT, a PMT with the given metadata and a
+	// frame.
+	var (
+		clip  []byte
+		pmtAt []int // Index of the PMT of each part in the clip.
+	)
+	for i, v := range []string{"a", "a", "b", "a"} {
+		var b bytes.Buffer
+		for _, p := range []Packet{
+			{PUSI: true, PID: PatPid, CC: byte(i), AFC: HasPayload, Payload: psi.AddPadding(pat)},
+			{PUSI: true, PID: pmtPID, CC: byte(i), AFC: HasPayload, Payload: psi.AddPadding(pmt)},
+		} {
+			b.Write(p.Bytes(nil))
+		}
+		err = writeFrame(&b, make([]byte, 300), uint64(i*3600))
+		if err != nil {
+			t.Fatalf("could not write frame: %v", err)
+		}
+		part, err := SetMeta(b.Bytes(), map[string]string{"loc": v})
+		if err != nil {
+			t.Fatalf("could not set meta of part %d: %v", i, err)
+		}
+		pmtAt = append(pmtAt, len(clip)+PacketSize)
+		clip = append(clip, part...)
+	}
+
+	timeline, err := MetaTimeline(clip)
+	if err != nil {
+		t.Fatalf("did not expect error from MetaTimeline: %v", err)
+	}
+	wantTimeline := []MetaState{
+		{Packet: pmtAt[0] / PacketSize, Meta: map[string]string{"loc": "a"}},
+		{Packet: pmtAt[2] / PacketSize, Meta: map[string]string{"loc": "b"}},
+		{Packet: pmtAt[3] / PacketSize, Meta: map[string]string{"loc": "a"}},
+	}
+	if !reflect.DeepEqual(timeline, wantTimeline) {
+		t.Errorf("did not get expected timeline.\nGot: %v\nWant: %v\n", timeline, wantTimeline)
+	}
+
+	segs, err := SegmentForMeta(clip, "loc", "a")
+	if err != nil {
+		t.Fatalf("did not expect error from SegmentForMeta: %v", err)
+	}
+	wantSegs := [][]byte{clip[pmtAt[0]:pmtAt[2]], clip[pmtAt[3]:]}
+	if !reflect.DeepEqual(segs, wantSegs) {
+		t.Errorf("did not get expected segments.\nGot: %d segments\nWant: %d segments\n", len(segs), len(wantSegs))
+	}
+
+	trimmed, err := TrimToMetaRange(clip, "loc", "a", "b")
+	if err != nil {
+		t.Fatalf("did not expect error from TrimToMetaRange: %v", err)
+	}
+	if want := clip[pmtAt[0] : pmtAt[2]+PacketSize]; !bytes.Equal(trimmed, want) {
+		t.Errorf("did not get expected trimmed clip.\nGot: %d bytes\nWant: %d bytes\n", len(trimmed), len(want))
+	}
+}
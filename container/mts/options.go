@@ -28,6 +28,8 @@ import (
 var (
 	ErrUnsupportedMedia = errors.New("unsupported media type")
 	ErrInvalidRate      = errors.New("invalid access unit rate")
+	ErrInvalidTSC       = errors.New("invalid transport scrambling control")
+	ErrInvalidMuxRate   = errors.New("invalid mux rate")
 )
 
 // PacketBasedPSI is an option that can be passed to NewEncoder to select
@@ -108,3 +110,48 @@ func Rate(r float64) func(*Encoder) error {
 		return nil
 	}
 }
+
+// ScramblingControl is an option that can be passed to NewEncoder to set the
+// transport scrambling control of media packets, for interoperability with
+// equipment expecting scrambled streams. The payload itself is not scrambled.
+// PSI packets are never marked as scrambled.
+func ScramblingControl(tsc byte) func(*Encoder) error {
+	return func(e *Encoder) error {
+		if tsc > TSCOddKey {
+			return ErrInvalidTSC
+		}
+		e.tsc = tsc
+		e.log.Debug("configured transport scrambling control", "TSC", tsc)
+		return nil
+	}
+}
+
+// MuxRate is an option that can be passed to NewEncoder to produce a constant
+// bitrate stream. After each access unit, null packets are written so that the
+// output reaches the mux rate, given in bits per second, relative to the PCR.
+// This is needed by some hardware decoders and modulators. The mux rate must
+// be large enough to carry the encoded media and PSI.
+func MuxRate(bps uint) func(*Encoder) error {
+	return func(e *Encoder) error {
+		if bps == 0 {
+			return ErrInvalidMuxRate
+		}
+		e.muxRate = bps
+		e.log.Debug("configured for constant bitrate output", "mux rate", bps)
+		return nil
+	}
+}
+
+// SEI is an option that can be passed to NewEncoder to insert an H.264
+// user_data_unregistered SEI NAL unit, e.g. carrying a timestamp and site ID
+// for provenance, before the first slice of each access unit containing an
+// IDR slice. The SEI holds the given UUID and the data returned by data at
+// the time of writing. Only H.264 media is supported.
+func SEI(uuid [16]byte, data func() []byte) func(*Encoder) error {
+	return func(e *Encoder) error {
+		e.seiUUID = uuid
+		e.seiData = data
+		e.log.Debug("configured for SEI insertion")
+		return nil
+	}
+}
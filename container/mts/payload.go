@@ -36,7 +36,7 @@ func Extract(p []byte) (*Clip, error) {
 	l := len(p)
 	// Check that clip is divisible by 188, i.e. contains a series of full MPEG-TS clips.
 	if l%PacketSize != 0 {
-		return nil, errors.New("MTS clip is not of valid size")
+		return nil, ErrInvalidLen
 	}
 
 	var (
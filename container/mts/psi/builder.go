@@ -0,0 +1,176 @@
+/*
+NAME
+  builder.go
+
+DESCRIPTION
+  builder.go provides construction of PAT and PMT tables with arbitrary
+  program numbers, PIDs and elementary streams.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package psi
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by BuildPAT and BuildPMT.
+var (
+	ErrNoPrograms    = errors.New("no programs given")
+	ErrNoStreams     = errors.New("no elementary streams given")
+	ErrTableTooLong  = errors.New("table does not fit in a single packet")
+	ErrDescTooLong   = errors.New("descriptor data too long")
+	ErrPIDOutOfRange = errors.New("PID out of range")
+)
+
+// maxPID is the largest valid 13 bit PID.
+const maxPID = 0x1fff
+
+// Stream describes an elementary stream in a program.
+type Stream struct {
+	Type        byte         // Stream type, e.g. 0x1b for H.264.
+	PID         uint16       // Elementary PID.
+	Descriptors []Descriptor // Elementary stream descriptors.
+}
+
+// Program describes a program for the purpose of building a PAT and PMT.
+type Program struct {
+	Number      uint16       // Program number.
+	PMTPID      uint16       // PID of packets carrying the program's PMT.
+	PCRPID      uint16       // PID of packets carrying the program's PCR.
+	Version     byte         // Table version number (5 bits).
+	Descriptors []Descriptor // Program descriptors.
+	Streams     []Stream     // Elementary streams of the program.
+}
+
+// BuildPAT returns a PAT, including pointer field and CRC, mapping each of the
+// given programs to its PMT PID.
+func BuildPAT(programs ...Program) (PSIBytes, error) {
+	if len(programs) == 0 {
+		return nil, ErrNoPrograms
+	}
+	for _, p := range programs {
+		if p.PMTPID > maxPID {
+			return nil, fmt.Errorf("PMT PID %#x of program %d: %w", p.PMTPID, p.Number, ErrPIDOutOfRange)
+		}
+	}
+	return build(patID, 0x01, 0, patPrograms(programs))
+}
+
+// BuildPMT returns a PMT, including pointer field and CRC, for the program p.
+// Descriptor lengths are taken from the length of the descriptor data.
+func BuildPMT(p Program) (PSIBytes, error) {
+	if len(p.Streams) == 0 {
+		return nil, ErrNoStreams
+	}
+	if p.PCRPID > maxPID {
+		return nil, fmt.Errorf("PCR PID %#x: %w", p.PCRPID, ErrPIDOutOfRange)
+	}
+	err := checkDescriptors(p.Descriptors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid program descriptor: %w", err)
+	}
+	for _, s := range p.Streams {
+		if s.PID > maxPID {
+			return nil, fmt.Errorf("elementary PID %#x: %w", s.PID, ErrPIDOutOfRange)
+		}
+		err = checkDescriptors(s.Descriptors)
+		if err != nil {
+			return nil, fmt.Errorf("invalid descriptor for PID %d: %w", s.PID, err)
+		}
+	}
+	return build(pmtID, p.Number, p.Version, (*programMap)(&p))
+}
+
+// build forms a complete table from the given table ID, table ID extension,
+// version and table data, calculating the section length and CRC.
+func build(tableID byte, ext uint16, version byte, data SpecificData) (PSIBytes, error) {
+	p := &PSI{
+		TableID:         tableID,
+		SyntaxIndicator: true,
+		SectionLen:      uint16(TSSDefLen + len(data.Bytes()) + crcSize),
+		SyntaxSection: &SyntaxSection{
+			TableIDExt:   ext,
+			Version:      version,
+			CurrentNext:  true,
+			SpecificData: data,
+		},
+	}
+
+	// The pointer field and table header precede the section.
+	if 1+PSIDefLen+int(p.SectionLen) > PacketSize {
+		return nil, ErrTableTooLong
+	}
+	return PSIBytes(p.Bytes()), nil
+}
+
+// checkDescriptors checks that each descriptor's data can be represented by
+// the 8 bit descriptor length.
+func checkDescriptors(descs []Descriptor) error {
+	for _, d := range descs {
+		if len(d.Data) > 0xff {
+			return fmt.Errorf("tag %d: %w", d.Tag, ErrDescTooLong)
+		}
+	}
+	return nil
+}
+
+// patPrograms implements SpecificData for a PAT with any number of programs.
+type patPrograms []Program
+
+// Bytes outputs a byte slice representation of the program loop of a PAT.
+func (ps patPrograms) Bytes() []byte {
+	out := make([]byte, 0, PATLen*len(ps))
+	for _, p := range ps {
+		out = append(out, (&PAT{Program: p.Number, ProgramMapPID: p.PMTPID}).Bytes()...)
+	}
+	return out
+}
+
+// programMap implements SpecificData for a PMT with any number of elementary
+// streams.
+type programMap Program
+
+// Bytes outputs a byte slice representation of the PMT data, calculating the
+// program info and elementary stream info lengths.
+func (p *programMap) Bytes() []byte {
+	descs := descriptorBytes(p.Descriptors)
+	out := []byte{
+		0xe0 | (0x1f & byte(p.PCRPID>>8)),
+		byte(p.PCRPID),
+		0xf0 | (0x03 & byte(len(descs)>>8)),
+		byte(len(descs)),
+	}
+	out = append(out, descs...)
+	for _, s := range p.Streams {
+		descs = descriptorBytes(s.Descriptors)
+		out = append(out,
+			s.Type,
+			0xe0|(0x1f&byte(s.PID>>8)),
+			byte(s.PID),
+			0xf0|(0x03&byte(len(descs)>>8)),
+			byte(len(descs)),
+		)
+		out = append(out, descs...)
+	}
+	return out
+}
+
+// descriptorBytes returns the byte representation of descs with each
+// descriptor length set from its data.
+func descriptorBytes(descs []Descriptor) []byte {
+	var out []byte
+	for _, d := range descs {
+		d.Len = byte(len(d.Data))
+		out = append(out, d.Bytes()...)
+	}
+	return out
+}
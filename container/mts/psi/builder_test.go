@@ -0,0 +1,159 @@
+/*
+NAME
+  builder_test.go
+
+DESCRIPTION
+  builder_test.go provides testing for the PAT and PMT builders in builder.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package psi
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gotspsi "github.com/Comcast/gots/v2/psi"
+)
+
+// TestBuildStandard checks that building the standard program gives the
+// standard PAT and PMT.
+func TestBuildStandard(t *testing.T) {
+	p := Program{
+		Number:  1,
+		PMTPID:  0x1000,
+		PCRPID:  0x0100,
+		Streams: []Stream{{Type: 0x1b, PID: 0x0100}},
+	}
+
+	pat, err := BuildPAT(p)
+	if err != nil {
+		t.Fatalf("did not expect error building PAT: %v", err)
+	}
+	if want := AddCRC(StandardPatBytes); !bytes.Equal(pat, want) {
+		t.Errorf("did not get expected PAT.\nGot: %v\nWant: %v\n", pat, want)
+	}
+
+	pmt, err := BuildPMT(p)
+	if err != nil {
+		t.Fatalf("did not expect error building PMT: %v", err)
+	}
+	if want := AddCRC(StandardPmtBytes); !bytes.Equal(pmt, want) {
+		t.Errorf("did not get expected PMT.\nGot: %v\nWant: %v\n", pmt, want)
+	}
+}
+
+// TestBuildParse checks that tables built with custom programs and PIDs are
+// parsed correctly by gots.
+func TestBuildParse(t *testing.T) {
+	programs := []Program{
+		{
+			Number:      3,
+			PMTPID:      0x0101,
+			PCRPID:      0x0200,
+			Version:     5,
+			Descriptors: []Descriptor{{Tag: MetadataTag, Data: []byte("key=val")}},
+			Streams: []Stream{
+				{Type: 0x24, PID: 0x0200},
+				{Type: 0x0f, PID: 0x0201, Descriptors: []Descriptor{{Tag: 0x0a, Data: []byte{'e', 'n', 'g', 0x00}}}},
+			},
+		},
+		{
+			Number:  7,
+			PMTPID:  0x0300,
+			PCRPID:  0x0301,
+			Streams: []Stream{{Type: 0x1b, PID: 0x0301}},
+		},
+	}
+
+	patBytes, err := BuildPAT(programs...)
+	if err != nil {
+		t.Fatalf("did not expect error building PAT: %v", err)
+	}
+	pat, err := gotspsi.NewPAT(patBytes)
+	if err != nil {
+		t.Fatalf("could not parse PAT: %v", err)
+	}
+	wantMap := map[int]int{3: 0x0101, 7: 0x0300}
+	if got := pat.ProgramMap(); !reflect.DeepEqual(got, wantMap) {
+		t.Errorf("did not get expected program map.\nGot: %v\nWant: %v\n", got, wantMap)
+	}
+
+	for _, p := range programs {
+		pmtBytes, err := BuildPMT(p)
+		if err != nil {
+			t.Fatalf("did not expect error building PMT for program %d: %v", p.Number, err)
+		}
+
+		// Check the CRC by recomputing it over a copy.
+		check := make([]byte, len(pmtBytes))
+		copy(check, pmtBytes)
+		UpdateCrc(check[1:])
+		if !bytes.Equal(check, pmtBytes) {
+			t.Errorf("PMT for program %d has bad CRC", p.Number)
+		}
+
+		pmt, err := gotspsi.NewPMT(pmtBytes)
+		if err != nil {
+			t.Fatalf("could not parse PMT for program %d: %v", p.Number, err)
+		}
+		if pmt.VersionNumber() != p.Version {
+			t.Errorf("did not get expected version for program %d.\nGot: %d\nWant: %d\n", p.Number, pmt.VersionNumber(), p.Version)
+		}
+		es := pmt.ElementaryStreams()
+		if len(es) != len(p.Streams) {
+			t.Fatalf("did not get expected number of streams for program %d.\nGot: %d\nWant: %d\n", p.Number, len(es), len(p.Streams))
+		}
+		for i, s := range p.Streams {
+			if es[i].StreamType() != s.Type || es[i].ElementaryPid() != int(s.PID) {
+				t.Errorf("did not get expected stream %d for program %d.\nGot: type=%#x pid=%#x\nWant: type=%#x pid=%#x\n",
+					i, p.Number, es[i].StreamType(), es[i].ElementaryPid(), s.Type, s.PID)
+			}
+			if len(es[i].Descriptors()) != len(s.Descriptors) {
+				t.Errorf("did not get expected number of descriptors for stream %d of program %d.\nGot: %d\nWant: %d\n",
+					i, p.Number, len(es[i].Descriptors()), len(s.Descriptors))
+			}
+		}
+	}
+
+	// The program descriptor should be found by HasDescriptor.
+	pmtBytes, _ := BuildPMT(programs[0])
+	_, desc := pmtBytes.HasDescriptor(MetadataTag)
+	if desc == nil || string(desc[2:]) != "key=val" {
+		t.Errorf("did not get expected program descriptor, got: %v", desc)
+	}
+}
+
+// TestBuildErrors checks that invalid programs are rejected.
+func TestBuildErrors(t *testing.T) {
+	_, err := BuildPAT()
+	if !errors.Is(err, ErrNoPrograms) {
+		t.Errorf("did not get expected error for no programs.\nGot: %v\nWant: %v\n", err, ErrNoPrograms)
+	}
+
+	tests := []struct {
+		p    Program
+		want error
+	}{
+		{p: Program{}, want: ErrNoStreams},
+		{p: Program{Streams: []Stream{{PID: 0x2000}}}, want: ErrPIDOutOfRange},
+		{p: Program{PCRPID: 0x2000, Streams: []Stream{{PID: 0x100}}}, want: ErrPIDOutOfRange},
+		{p: Program{Descriptors: []Descriptor{{Data: make([]byte, 256)}}, Streams: []Stream{{PID: 0x100}}}, want: ErrDescTooLong},
+		{p: Program{Descriptors: []Descriptor{{Data: make([]byte, 200)}}, Streams: []Stream{{PID: 0x100}}}, want: ErrTableTooLong},
+	}
+	for i, test := range tests {
+		_, err := BuildPMT(test.p)
+		if !errors.Is(err, test.want) {
+			t.Errorf("did not get expected error for test %d.\nGot: %v\nWant: %v\n", i, err, test.want)
+		}
+	}
+}
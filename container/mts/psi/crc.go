@@ -22,8 +22,17 @@ package psi
 
 import (
 	"encoding/binary"
+	"errors"
 	"hash/crc32"
 	"math/bits"
+
+	"github.com/Comcast/gots/v2/psi"
+)
+
+// Errors used by CheckCRC and FixCRC.
+var (
+	ErrInvalidCRC   = errors.New("PSI CRC32 does not match table")
+	ErrShortSection = errors.New("PSI shorter than section length")
 )
 
 // addCrc appends a crc table to a given psi table in bytes
@@ -40,6 +49,48 @@ func UpdateCrc(b []byte) {
 	binary.BigEndian.PutUint32(b[len(b)-4:], crc32)
 }
 
+// CheckCRC checks that the CRC32 at the end of the table section matches the
+// section contents. Any padding after the section is ignored.
+func (p *PSIBytes) CheckCRC() error {
+	sec, err := p.section()
+	if err != nil {
+		return err
+	}
+	crc := crc32_Update(0xffffffff, crc32_MakeTable(bits.Reverse32(crc32.IEEE)), sec[:len(sec)-crcSize])
+	if binary.BigEndian.Uint32(sec[len(sec)-crcSize:]) != crc {
+		return ErrInvalidCRC
+	}
+	return nil
+}
+
+// FixCRC recomputes the CRC32 of the table section and writes it into the
+// last four bytes of the section. Any padding after the section is untouched.
+func (p *PSIBytes) FixCRC() error {
+	sec, err := p.section()
+	if err != nil {
+		return err
+	}
+	UpdateCrc(sec)
+	return nil
+}
+
+// section returns the table section of the PSI, from the table ID to the end
+// of the CRC32, as given by the pointer field and section length.
+func (p *PSIBytes) section() ([]byte, error) {
+	if len(*p) < 1 {
+		return nil, ErrShortSection
+	}
+	start := 1 + int(psi.PointerField(*p))
+	if len(*p) < start+PSIDefLen {
+		return nil, ErrShortSection
+	}
+	end := start + PSIDefLen + int(psi.SectionLength(*p))
+	if end > len(*p) || end-start < PSIDefLen+crcSize {
+		return nil, ErrShortSection
+	}
+	return (*p)[start:end], nil
+}
+
 func crc32_MakeTable(poly uint32) *crc32.Table {
 	var t crc32.Table
 	for i := range t {
@@ -84,6 +84,12 @@ const (
 	ProgramInfoLenMask1 = 0x03
 )
 
+// Consts relating to version number.
+const (
+	VersionIdx  = 6
+	VersionMask = 0x3e
+)
+
 // DescriptorsIdx is the index that the descriptors start at.
 const DescriptorsIdx = ProgramInfoLenIdx2 + 1
 
@@ -401,6 +407,19 @@ func (p *PSIBytes) setSectionLen(l int) {
 	(*p)[SyntaxSecLenIdx2] = byte(l)
 }
 
+// Version returns the version number of a psi.
+func (p *PSIBytes) Version() byte {
+	return ((*p)[VersionIdx] & VersionMask) >> 1
+}
+
+// SetVersion sets the version number of a psi to v modulo 32, as the field is
+// 5 bits, and recomputes the CRC.
+func (p *PSIBytes) SetVersion(v byte) error {
+	(*p)[VersionIdx] &= 0xff ^ VersionMask
+	(*p)[VersionIdx] |= (v << 1) & VersionMask
+	return p.FixCRC()
+}
+
 // descriptors returns the descriptors in a psi if they exist, otherwise
 // a nil slice is returned.
 func (p *PSIBytes) descriptors() []byte {
@@ -354,3 +354,43 @@ func buildPmtWithMeta(tstStr string) []byte {
 	copy(dst[len(pmtWithMetaHead)+32:], pmtWithMetaTail)
 	return dst
 }
+
+// TestCheckFixCRC checks that a corrupted table fails CRC validation and that
+// FixCRC repairs it, with and without trailing padding.
+func TestCheckFixCRC(t *testing.T) {
+	for _, pad := range []bool{false, true} {
+		p := PSIBytes(AddCRC(StandardPmtBytes))
+		if pad {
+			p = AddPadding(p)
+		}
+
+		err := p.CheckCRC()
+		if err != nil {
+			t.Errorf("did not expect error for valid table (padded: %t): %v", pad, err)
+		}
+
+		// Change the stream type.
+		p[13] = 0x24
+		err = p.CheckCRC()
+		if err != ErrInvalidCRC {
+			t.Errorf("did not get expected error for corrupted table (padded: %t).\nGot: %v\nWant: %v\n", pad, err, ErrInvalidCRC)
+		}
+
+		err = p.FixCRC()
+		if err != nil {
+			t.Fatalf("did not expect error fixing CRC (padded: %t): %v", pad, err)
+		}
+		err = p.CheckCRC()
+		if err != nil {
+			t.Errorf("did not expect error for fixed table (padded: %t): %v", pad, err)
+		}
+		if pad && p[len(StandardPmtBytes)+crcSize] != 0xff {
+			t.Errorf("padding was modified by FixCRC")
+		}
+	}
+
+	short := PSIBytes(StandardPmtBytes[:8])
+	if err := short.CheckCRC(); err != ErrShortSection {
+		t.Errorf("did not get expected error for short table.\nGot: %v\nWant: %v\n", err, ErrShortSection)
+	}
+}
@@ -0,0 +1,261 @@
+/*
+NAME
+  rap.go
+
+DESCRIPTION
+  rap.go provides functionality for identifying random access points (RAPs),
+  i.e. keyframes, in MPEG-TS, and for segmenting MPEG-TS at them.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Comcast/gots/v2/packet"
+	gotspes "github.com/Comcast/gots/v2/pes"
+
+	"github.com/ausocean/av/codec/codecutil"
+	"github.com/ausocean/av/codec/h264/h264dec"
+	"github.com/ausocean/av/codec/h265"
+	"github.com/ausocean/av/container/mts/pes"
+)
+
+// StartsAtRAP returns true if the media of the given PID in the MPEG-TS clip
+// begins at a random access point. The first packet of the PID must start a
+// PES packet. For H.264 and H.265, this is decided by the first slice of the
+// PES packet, which must be IDR (IRAP for H.265); parameter sets alone do not
+// make a random access point. For other media the random access indicator of
+// the packet is used.
+func StartsAtRAP(clip []byte, pid uint16) (bool, error) {
+	if len(clip)%PacketSize != 0 {
+		return false, ErrInvalidLen
+	}
+	_, i, err := FindPid(clip, pid)
+	if err != nil {
+		return false, err
+	}
+
+	// If the clip starts part way through a PES packet it can't be decoded.
+	if clip[i+1]&0x40 == 0 {
+		return false, nil
+	}
+	return isRAP(clip, i, pid)
+}
+
+// isRAP returns true if the PES packet of the given PID beginning with the
+// packet at byte index i of the MPEG-TS clip is a random access point, as
+// described by StartsAtRAP.
+func isRAP(clip []byte, i int, pid uint16) (bool, error) {
+	data, sid, err := pesAt(clip, i, pid)
+	if err != nil {
+		return false, err
+	}
+
+	var vclRAP func([]byte) (rap, ok bool)
+	switch sid {
+	case pes.H264SID:
+		vclRAP = h264RAP
+	case pes.H265SID:
+		vclRAP = h265RAP
+	default:
+		return GetRAI(clip[i : i+PacketSize])
+	}
+
+	for _, n := range codecutil.SplitNALUnits(data) {
+		rap, ok := vclRAP(n)
+		if ok {
+			return rap, nil
+		}
+	}
+	return false, nil
+}
+
+// pesAt returns the data and stream ID of the PES packet of the given PID
+// beginning with the packet at byte index i of the MPEG-TS clip. The PES
+// packet continues up to the next packet of the PID with the PUSI set.
+func pesAt(clip []byte, i int, pid uint16) (data []byte, sid byte, err error) {
+	var (
+		pkt packet.Packet
+		buf []byte
+	)
+	for j := i; j < len(clip); j += PacketSize {
+		copy(pkt[:], clip[j:j+PacketSize])
+		if pkt.PID() != int(pid) {
+			continue
+		}
+		if j != i && pkt.PayloadUnitStartIndicator() {
+			break
+		}
+		payload, err := pkt.Payload()
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not get payload of packet at %d: %w", j, err)
+		}
+		buf = append(buf, payload...)
+	}
+	hdr, err := gotspes.NewPESHeader(buf)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not parse PES header: %w", err)
+	}
+	return hdr.Data(), hdr.StreamId(), nil
+}
+
+// raiMask is the mask for the random access indicator in the adaptation field.
+const raiMask = 0x40
+
+// GetRAI returns the random access indicator of the MPEG-TS packet pkt. This is
+// false if pkt has no adaptation field, or an adaptation field of zero length.
+func GetRAI(pkt []byte) (bool, error) {
+	if len(pkt) < PacketSize {
+		return false, ErrShortPacket
+	}
+	if pkt[AdaptationControlIdx]&0x20 == 0 || pkt[AdaptationIdx] == 0 {
+		return false, nil
+	}
+	return pkt[AdaptationFieldsIdx]&raiMask != 0, nil
+}
+
+// SegmentAtRAP splits the MPEG-TS clip into segments that each begin at a
+// PES packet of the given PID that is a random access point, as described by
+// StartsAtRAP, i.e. at the start of each GOP for video. Any PAT and PMT packets
+// immediately before the random access point begin the segment, so that each
+// segment can be played on its own. Each segment continues up to the start of
+// the next, so includes packets of all PIDs. Packets before the first random
+// access point are not included, as they cannot be decoded independently.
+func SegmentAtRAP(clip []byte, pid uint16) ([][]byte, error) {
+	if len(clip)%PacketSize != 0 {
+		return nil, ErrInvalidLen
+	}
+
+	var (
+		res   [][]byte // The resultant segments.
+		start = -1     // The start index of the current segment.
+	)
+	for i := 0; i < len(clip); i += PacketSize {
+		pkt := clip[i : i+PacketSize]
+		_pid, _ := PID(pkt)
+		if _pid != pid || pkt[1]&0x40 == 0 {
+			continue
+		}
+		rap, err := isRAP(clip, i, pid)
+		if err != nil || !rap {
+			continue
+		}
+
+		// Include the PSI before the random access point.
+		j := i
+		for j-PacketSize > start {
+			prev, _ := PID(clip[j-PacketSize : j])
+			if prev != PatPid && prev != PmtPid {
+				break
+			}
+			j -= PacketSize
+		}
+
+		if start != -1 {
+			res = append(res, clip[start:j])
+		}
+		start = j
+	}
+	if start != -1 {
+		res = append(res, clip[start:])
+	}
+	return res, nil
+}
+
+// ErrNoIDR is returned by FirstIDRIndex if the clip has no IDR access unit.
+var ErrNoIDR = errors.New("no IDR access unit")
+
+// FirstIDRIndex returns the byte index in the MPEG-TS clip of the packet that
+// begins the first PES packet of the given PID containing an H.264 IDR slice,
+// i.e. where the first IDR access unit begins. Packets of the PID before the
+// first PES start are ignored. ErrNoIDR is returned if there is no IDR.
+func FirstIDRIndex(clip []byte, pid uint16) (int, error) {
+	if len(clip)%PacketSize != 0 {
+		return -1, ErrInvalidLen
+	}
+
+	var (
+		start = -1   // Index of the packet beginning the current PES.
+		buf   []byte // Payload of the current PES.
+	)
+
+	// hasIDR returns true if the current PES contains an IDR slice.
+	hasIDR := func() bool {
+		if start == -1 {
+			return false
+		}
+		hdr, err := gotspes.NewPESHeader(buf)
+		if err != nil {
+			return false
+		}
+		return containsIDR(hdr.Data())
+	}
+
+	var pkt packet.Packet
+	for i := 0; i < len(clip); i += PacketSize {
+		copy(pkt[:], clip[i:i+PacketSize])
+		if pkt.PID() != int(pid) {
+			continue
+		}
+		if pkt.PayloadUnitStartIndicator() {
+			if hasIDR() {
+				return start, nil
+			}
+			start = i
+			buf = buf[:0]
+		}
+		if start == -1 {
+			continue
+		}
+		payload, err := pkt.Payload()
+		if err != nil {
+			return -1, fmt.Errorf("could not get payload of packet at %d: %w", i, err)
+		}
+		buf = append(buf, payload...)
+	}
+	if hasIDR() {
+		return start, nil
+	}
+	return -1, ErrNoIDR
+}
+
+// containsIDR returns true if the H.264 access unit au, in byte stream format,
+// contains an IDR slice.
+func containsIDR(au []byte) bool {
+	for _, n := range codecutil.SplitNALUnits(au) {
+		if h264dec.NALTypeOf(n) == h264dec.NALTypeIDR {
+			return true
+		}
+	}
+	return false
+}
+
+// h264RAP returns ok true if the H.264 NAL unit n is a slice, with rap true
+// if it is an IDR slice, and ok false if n does not determine a random access
+// point, e.g. if it is a parameter set.
+func h264RAP(n []byte) (rap, ok bool) {
+	t := h264dec.NALTypeOf(n)
+	if !h264dec.IsVCL(t) {
+		return false, false
+	}
+	return t == h264dec.NALTypeIDR, true
+}
+
+// h265RAP is the H.265 equivalent of h264RAP, with rap true for IRAP slices.
+func h265RAP(n []byte) (rap, ok bool) {
+	h, err := h265.ParseNALHeader(n)
+	if err != nil || !h.IsVCL() {
+		return false, false
+	}
+	return h.IsIRAP(), true
+}
@@ -0,0 +1,272 @@
+/*
+NAME
+  rap_test.go
+
+DESCRIPTION
+  rap_test.go provides testing for functionality in rap.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ausocean/av/container/mts/meta"
+	"github.com/ausocean/utils/logging"
+)
+
+// Access units used for testing random access point detection.
+var (
+	// H.264 IDR access unit: AUD, SPS, PPS, IDR slice.
+	h264IDR = []byte{
+		0x00, 0x00, 0x00, 0x01, 0x09, 0xf0,
+		0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0xc0, 0x1e, 0xd9, 0x00,
+		0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x3c, 0x80,
+		0x00, 0x00, 0x01, 0x65, 0x88, 0x84, 0x00, 0x33,
+	}
+
+	// H.264 non-IDR access unit: AUD, non-IDR slice.
+	h264NonIDR = []byte{
+		0x00, 0x00, 0x00, 0x01, 0x09, 0xf0,
+		0x00, 0x00, 0x01, 0x41, 0x9a, 0x24, 0x6c, 0x41,
+	}
+
+	// H.265 IDR access unit: VPS, SPS, PPS, IDR_W_RADL slice.
+	h265IDR = []byte{
+		0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c, 0x01, 0xff, 0xff,
+		0x00, 0x00, 0x00, 0x01, 0x42, 0x01, 0x01, 0x01, 0x60, 0x00,
+		0x00, 0x00, 0x00, 0x01, 0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62,
+		0x00, 0x00, 0x01, 0x26, 0x01, 0xaf, 0x06, 0xb8, 0x63,
+	}
+
+	// H.265 non-IRAP access unit: TRAIL_R slice.
+	h265Trail = []byte{0x00, 0x00, 0x01, 0x02, 0x01, 0xd0, 0x09, 0x7e, 0x10}
+
+	// H.264 non-IDR access unit repeating the parameter sets: SPS, PPS,
+	// non-IDR slice.
+	h264ParamsNonIDR = append(append([]byte{}, h264IDR[6:24]...), h264NonIDR[6:]...)
+
+	// H.265 non-IRAP access unit repeating the parameter sets: VPS, SPS, PPS,
+	// TRAIL_R slice.
+	h265ParamsTrail = append(append([]byte{}, h265IDR[:30]...), h265Trail...)
+)
+
+// encodeAUs returns MPEG-TS of the given access units encoded with the given
+// media type.
+func encodeAUs(t *testing.T, mediaType int, aus ...[]byte) []byte {
+	Meta = meta.New()
+	var buf bytes.Buffer
+	e, err := NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount), MediaType(mediaType))
+	if err != nil {
+		t.Fatalf("could not create encoder: %v", err)
+	}
+	for i, au := range aus {
+		_, err = e.Write(au)
+		if err != nil {
+			t.Fatalf("could not write access unit %d: %v", i, err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestStartsAtRAP(t *testing.T) {
+	tests := []struct {
+		name  string
+		media int
+		aus   [][]byte
+		want  bool
+	}{
+		{name: "h264 IDR", media: EncodeH264, aus: [][]byte{h264IDR, h264NonIDR}, want: true},
+		{name: "h264 non-IDR", media: EncodeH264, aus: [][]byte{h264NonIDR, h264IDR}, want: false},
+		{name: "h265 IDR", media: EncodeH265, aus: [][]byte{h265IDR, h265Trail}, want: true},
+		{name: "h265 non-IRAP", media: EncodeH265, aus: [][]byte{h265Trail, h265IDR}, want: false},
+		{name: "h264 parameter sets with non-IDR", media: EncodeH264, aus: [][]byte{h264ParamsNonIDR, h264IDR}, want: false},
+		{name: "h265 parameter sets with non-IRAP", media: EncodeH265, aus: [][]byte{h265ParamsTrail, h265IDR}, want: false},
+		{name: "jpeg", media: EncodeJPEG, aus: [][]byte{{0xff, 0xd8, 0xff, 0xd9}}, want: true}, // Uses the RAI flag.
+	}
+
+	for _, test := range tests {
+		clip := encodeAUs(t, test.media, test.aus...)
+		got, err := StartsAtRAP(clip, PIDVideo)
+		if err != nil {
+			t.Errorf("did not expect error for test %q: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("did not get expected result for test %q.\nGot: %t\nWant: %t\n", test.name, got, test.want)
+		}
+	}
+}
+
+// TestStartsAtRAPMidPES checks that a clip beginning part way through a PES
+// packet does not start at a random access point.
+func TestStartsAtRAPMidPES(t *testing.T) {
+	// A large access unit spans multiple packets.
+	au := append(append([]byte{}, h264IDR...), make([]byte, 4*PacketSize)...)
+	clip := encodeAUs(t, EncodeH264, au)
+
+	// Drop the PSI and the first video packet.
+	clip = clip[3*PacketSize:]
+	got, err := StartsAtRAP(clip, PIDVideo)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if got {
+		t.Errorf("did not expect clip to start at RAP")
+	}
+
+	_, err = StartsAtRAP(clip, PIDAudio)
+	if err == nil {
+		t.Errorf("expected error for absent PID")
+	}
+}
+
+func TestGetRAI(t *testing.T) {
+	withRAI := (&Packet{PID: PIDVideo, RAI: true, AFC: HasAdaptationField | HasPayload}).Bytes(nil)
+	withoutRAI := (&Packet{PID: PIDVideo, AFC: HasAdaptationField | HasPayload}).Bytes(nil)
+	noAF := (&Packet{PID: PIDVideo, AFC: HasPayload, Payload: bytes.Repeat([]byte{0xff}, PacketSize-HeadSize)}).Bytes(nil)
+
+	tests := []struct {
+		name string
+		pkt  []byte
+		want bool
+		err  error
+	}{
+		{name: "RAI set", pkt: withRAI, want: true},
+		{name: "RAI not set", pkt: withoutRAI},
+		{name: "no adaptation field", pkt: noAF},
+		{name: "short packet", pkt: withRAI[:HeadSize+2], err: ErrShortPacket},
+	}
+	for _, test := range tests {
+		got, err := GetRAI(test.pkt)
+		if err != test.err {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, test.err)
+		}
+		if got != test.want {
+			t.Errorf("did not get expected RAI for test %q.\nGot: %v\nWant: %v\n", test.name, got, test.want)
+		}
+	}
+}
+
+// TestSegmentAtRAP checks that encoder output of several GOPs is split into
+// segments that each begin with the PSI before an IDR access unit.
+func TestSegmentAtRAP(t *testing.T) {
+	const nGOPs = 3
+
+	// Start with a frame that is not a RAP, which should be excluded. Each GOP
+	// includes a non-IDR access unit that repeats the parameter sets, which
+	// must not start a segment.
+	aus := [][]byte{h264NonIDR}
+	for g := 0; g < nGOPs; g++ {
+		aus = append(aus, h264IDR, h264NonIDR, h264ParamsNonIDR, h264NonIDR)
+	}
+
+	Meta = meta.New()
+	var buf bytes.Buffer
+	e, err := NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), MediaType(EncodeH264))
+	if err != nil {
+		t.Fatalf("could not create encoder: %v", err)
+	}
+	var idrOffs []int // Offsets of the IDR access units.
+	for i, au := range aus {
+		if bytes.Equal(au, h264IDR) {
+			idrOffs = append(idrOffs, buf.Len())
+		}
+		_, err = e.Write(au)
+		if err != nil {
+			t.Fatalf("could not write access unit %d: %v", i, err)
+		}
+	}
+	clip := buf.Bytes()
+
+	segs, err := SegmentAtRAP(clip, PIDVideo)
+	if err != nil {
+		t.Fatalf("did not expect error segmenting clip: %v", err)
+	}
+	if len(segs) != nGOPs {
+		t.Fatalf("did not get expected number of segments.\nGot: %d\nWant: %d\n", len(segs), nGOPs)
+	}
+
+	for i, seg := range segs {
+		// The encoder writes PSI before each IDR access unit.
+		if pid, _ := PID(seg); pid != PatPid {
+			t.Errorf("segment %d does not begin with a PAT, got PID %d", i, pid)
+		}
+		if pid, _ := PID(seg[PacketSize:]); pid != PmtPid {
+			t.Errorf("segment %d does not have a PMT after the PAT, got PID %d", i, pid)
+		}
+		rap, err := StartsAtRAP(seg, PIDVideo)
+		if err != nil {
+			t.Fatalf("did not expect error checking RAP of segment %d: %v", i, err)
+		}
+		if !rap {
+			t.Errorf("segment %d does not begin at a RAP", i)
+		}
+
+		end := len(clip)
+		if i+1 < len(idrOffs) {
+			end = idrOffs[i+1]
+		}
+		if !bytes.Equal(seg, clip[idrOffs[i]:end]) {
+			t.Errorf("segment %d does not span its GOP", i)
+		}
+	}
+
+	_, err = SegmentAtRAP(clip[1:], PIDVideo)
+	if err != ErrInvalidLen {
+		t.Errorf("did not get expected error for invalid length.\nGot: %v\nWant: %v\n", err, ErrInvalidLen)
+	}
+}
+
+// TestFirstIDRIndex checks that the index of the packet beginning the first
+// IDR access unit is found when it is not the first frame.
+func TestFirstIDRIndex(t *testing.T) {
+	// A large non-IDR access unit spans multiple packets.
+	bigNonIDR := append(append([]byte{}, h264NonIDR...), make([]byte, 2*PacketSize)...)
+
+	tests := []struct {
+		name string
+		aus  [][]byte
+		want int // Index of the access unit expected to hold the first IDR.
+		err  error
+	}{
+		{name: "first", aus: [][]byte{h264IDR, h264NonIDR}, want: 0},
+		{name: "second", aus: [][]byte{h264NonIDR, h264IDR, h264NonIDR}, want: 1},
+		{name: "after large", aus: [][]byte{bigNonIDR, h264NonIDR, h264IDR, h264IDR}, want: 2},
+		{name: "last", aus: [][]byte{h264NonIDR, h264NonIDR, h264IDR}, want: 2},
+		{name: "none", aus: [][]byte{h264NonIDR, h264NonIDR}, err: ErrNoIDR},
+	}
+	for _, test := range tests {
+		clip := encodeAUs(t, EncodeH264, test.aus...)
+
+		// Find the index of each access unit by its PUSI packet.
+		var auIdx []int
+		for i := 0; i < len(clip); i += PacketSize {
+			pid, _ := PID(clip[i:])
+			if pid == PIDVideo && clip[i+1]&0x40 != 0 {
+				auIdx = append(auIdx, i)
+			}
+		}
+
+		got, err := FirstIDRIndex(clip, PIDVideo)
+		if err != test.err {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got != auIdx[test.want] {
+			t.Errorf("did not get expected index for test %q.\nGot: %d\nWant: %d\n", test.name, got, auIdx[test.want])
+		}
+	}
+}
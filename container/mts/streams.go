@@ -0,0 +1,116 @@
+/*
+NAME
+  streams.go
+
+DESCRIPTION
+  streams.go provides functionality for checking that the stream types
+  declared in the PMT are consistent with the media being carried.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Comcast/gots/v2/packet"
+	gotspes "github.com/Comcast/gots/v2/pes"
+
+	"github.com/ausocean/av/container/mts/pes"
+)
+
+// ErrStreamType is returned by CheckStreamTypes when the stream type of an
+// elementary stream does not match its media.
+var ErrStreamType = errors.New("stream type does not match media")
+
+// isAudio returns true if st is an audio stream type, and ok true if st is a
+// stream type known to this package.
+func isAudio(st byte) (audio, ok bool) {
+	switch st {
+	case pes.PCMSID, pes.ADPCMSID:
+		return true, true
+	case pes.H264SID, pes.H265SID, pes.MJPEGSID, pes.JPEGSID:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// CheckStreamTypes checks that the stream type of each elementary stream in
+// the first PMT of the MPEG-TS clip matches the media of that stream. The PES
+// stream ID of the first PES packet of each stream must equal the stream type,
+// as written by Encoder, and H.264, H.265 and JPEG media must begin with the
+// Annex-B start code or JPEG start of image marker respectively. Streams of
+// unknown type, and streams with no PES packet in the clip, are not checked.
+func CheckStreamTypes(clip []byte) error {
+	if len(clip)%PacketSize != 0 {
+		return ErrInvalidLen
+	}
+	_, streams, _, err := ScanPSI(clip)
+	if err != nil {
+		return fmt.Errorf("could not find PSI: %w", err)
+	}
+
+	for pid, st := range streams {
+		if _, ok := isAudio(st); !ok {
+			continue
+		}
+		data, sid, ok := firstPES(clip, pid)
+		if !ok {
+			continue
+		}
+		if sid != st {
+			return fmt.Errorf("%w: PID %d has stream type %d but PES stream ID %d", ErrStreamType, pid, st, sid)
+		}
+
+		var prefix [][]byte
+		switch st {
+		case pes.H264SID, pes.H265SID:
+			prefix = [][]byte{{0x00, 0x00, 0x01}, {0x00, 0x00, 0x00, 0x01}}
+		case pes.MJPEGSID, pes.JPEGSID:
+			prefix = [][]byte{{0xff, 0xd8}}
+		default:
+			continue
+		}
+		var match bool
+		for _, p := range prefix {
+			match = match || bytes.HasPrefix(data, p)
+		}
+		if !match {
+			return fmt.Errorf("%w: PID %d has stream type %d but media does not match", ErrStreamType, pid, st)
+		}
+	}
+	return nil
+}
+
+// firstPES returns the data in the first packet of the first PES packet of
+// the given PID in clip, and its stream ID. ok is false if no PES packet
+// could be found.
+func firstPES(clip []byte, pid uint16) (data []byte, sid byte, ok bool) {
+	var pkt packet.Packet
+	for i := 0; i+PacketSize <= len(clip); i += PacketSize {
+		copy(pkt[:], clip[i:i+PacketSize])
+		if pkt.PID() != int(pid) || !pkt.PayloadUnitStartIndicator() {
+			continue
+		}
+		payload, err := pkt.Payload()
+		if err != nil {
+			return nil, 0, false
+		}
+		hdr, err := gotspes.NewPESHeader(payload)
+		if err != nil {
+			return nil, 0, false
+		}
+		return hdr.Data(), hdr.StreamId(), true
+	}
+	return nil, 0, false
+}
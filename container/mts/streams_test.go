@@ -0,0 +1,107 @@
+/*
+NAME
+  streams_test.go
+
+DESCRIPTION
+  streams_test.go provides testing for functionality in streams.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package mts
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ausocean/av/container/mts/meta"
+	"github.com/ausocean/av/container/mts/pes"
+	"github.com/ausocean/utils/logging"
+)
+
+// TestCheckStreamTypesSID checks that CheckStreamTypes reports a stream
+// whose PES stream ID does not match the stream type given in the PMT.
+func TestCheckStreamTypesSID(t *testing.T) {
+	Meta = meta.New()
+	var buf bytes.Buffer
+	e, err := NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount), MediaType(EncodeH264))
+	if err != nil {
+		t.Fatalf("did not expect error creating encoder: %v", err)
+	}
+	_, err = e.Write([]byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0})
+	if err != nil {
+		t.Fatalf("did not expect error writing: %v", err)
+	}
+	clip := buf.Bytes()
+
+	err = CheckStreamTypes(clip)
+	if err != nil {
+		t.Fatalf("did not expect error before changing stream ID: %v", err)
+	}
+
+	// Change the stream ID of the first PES packet, which follows the packet
+	// start code prefix, to that of H.265.
+	_, i, err := FindPid(clip, PIDVideo)
+	if err != nil {
+		t.Fatalf("could not find video packet: %v", err)
+	}
+	pkt := clip[i : i+PacketSize]
+	off := 4
+	if (pkt[3]>>4)&HasAdaptationField != 0 {
+		off += 1 + int(pkt[4])
+	}
+	if !bytes.HasPrefix(pkt[off:], []byte{0x00, 0x00, 0x01, pes.H264SID}) {
+		t.Fatalf("did not find PES header with H.264 stream ID: %x", pkt[off:off+4])
+	}
+	pkt[off+3] = pes.H265SID
+
+	err = CheckStreamTypes(clip)
+	if !errors.Is(err, ErrStreamType) {
+		t.Errorf("did not get expected error.\nGot: %v\nWant: %v\n", err, ErrStreamType)
+	}
+}
+
+func TestCheckStreamTypes(t *testing.T) {
+	h264 := []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0}
+	jpeg := []byte{0xff, 0xd8, 0xff, 0xe0}
+	audio := []byte{0x01, 0x02, 0x03, 0x04}
+
+	tests := []struct {
+		name  string
+		media int
+		data  []byte
+		want  error
+	}{
+		{name: "h264", media: EncodeH264, data: h264},
+		{name: "jpeg", media: EncodeJPEG, data: jpeg},
+		{name: "pcm", media: EncodePCM, data: audio},
+		{name: "audio as h264", media: EncodeH264, data: audio, want: ErrStreamType},
+		{name: "h264 as mjpeg", media: EncodeMJPEG, data: h264, want: ErrStreamType},
+	}
+	for _, test := range tests {
+		Meta = meta.New()
+		var buf bytes.Buffer
+		e, err := NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), PacketBasedPSI(psiSendCount), MediaType(test.media))
+		if err != nil {
+			t.Fatalf("did not expect error creating encoder for test %q: %v", test.name, err)
+		}
+		for i := 0; i < 3; i++ {
+			_, err = e.Write(test.data)
+			if err != nil {
+				t.Fatalf("did not expect error writing for test %q: %v", test.name, err)
+			}
+		}
+
+		err = CheckStreamTypes(buf.Bytes())
+		if !errors.Is(err, test.want) {
+			t.Errorf("did not get expected error for test %q.\nGot: %v\nWant: %v\n", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+/*
+NAME
+  audio-convert/main.go
+
+DESCRIPTION
+  audio-convert is a command-line program for converting audio between raw
+  PCM, ADPCM and WAV formats, optionally downmixing to mono and resampling.
+
+  The input and output formats are given by the from and to flags, or
+  otherwise taken from the file extensions (.pcm, .adpcm or .wav). As raw PCM
+  and ADPCM carry no format information, the rate, ch and bits flags describe
+  the input audio for these formats; for WAV input they are read from the
+  file. ADPCM is always 16 bit mono. Raw PCM input is little endian unless
+  the be flag is given.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ausocean/av/codec/adpcm"
+	"github.com/ausocean/av/codec/pcm"
+	"github.com/ausocean/av/codec/wav"
+)
+
+// Supported audio formats.
+const (
+	fmtPCM   = "pcm"
+	fmtADPCM = "adpcm"
+	fmtWAV   = "wav"
+)
+
+// Errors returned by convert.
+var (
+	errUnknownFormat = errors.New("unknown audio format")
+	errNotMono16     = errors.New("ADPCM requires 16 bit mono audio")
+	errBitDepth      = errors.New("unsupported bit depth")
+)
+
+// options holds the conversion settings.
+type options struct {
+	from, to string           // Input and output formats.
+	in       pcm.BufferFormat // Format of raw input audio.
+	outRate  uint             // Output sample rate, or 0 to keep the input rate.
+	mono     bool             // Downmix to mono using the left channel.
+}
+
+func main() {
+	var (
+		inPath   = flag.String("in", "", "file path of input audio")
+		outPath  = flag.String("out", "", "file path of output audio")
+		from     = flag.String("from", "", "input format (pcm, adpcm or wav); defaults to input file extension")
+		to       = flag.String("to", "", "output format (pcm, adpcm or wav); defaults to output file extension")
+		rate     = flag.Uint("rate", 48000, "sample rate of raw PCM or ADPCM input")
+		channels = flag.Uint("ch", 1, "number of channels of raw PCM input")
+		bits     = flag.Uint("bits", 16, "bit depth of raw PCM input (8, 16 or 32)")
+		be       = flag.Bool("be", false, "raw PCM input is big endian")
+		outRate  = flag.Uint("out-rate", 0, "sample rate of output; 0 keeps the input rate")
+		mono     = flag.Bool("mono", false, "downmix the output to mono using the left channel")
+	)
+	flag.Parse()
+
+	sf, err := sampleFormat(*bits)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *be {
+		sf = bigEndian(sf)
+	}
+	opts := options{
+		from:    formatOf(*from, *inPath),
+		to:      formatOf(*to, *outPath),
+		in:      pcm.BufferFormat{SFormat: sf, Rate: *rate, Channels: *channels},
+		outRate: *outRate,
+		mono:    *mono,
+	}
+
+	src, err := os.ReadFile(*inPath)
+	if err != nil {
+		log.Fatalf("could not read input file: %v", err)
+	}
+
+	dst, err := convert(src, opts)
+	if err != nil {
+		log.Fatalf("could not convert audio: %v", err)
+	}
+
+	err = os.WriteFile(*outPath, dst, 0644)
+	if err != nil {
+		log.Fatalf("could not write output file: %v", err)
+	}
+	fmt.Println("Converted", len(src), "bytes of", opts.from, "to", len(dst), "bytes of", opts.to)
+}
+
+// formatOf returns the format f if given, otherwise the format given by the
+// extension of path.
+func formatOf(f, path string) string {
+	if f != "" {
+		return strings.ToLower(f)
+	}
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// sampleFormat returns the sample format for the given bit depth. As in WAV,
+// 8 bit samples are unsigned.
+func sampleFormat(bits uint) (pcm.SampleFormat, error) {
+	switch bits {
+	case 16:
+		return pcm.S16_LE, nil
+	case 32:
+		return pcm.S32_LE, nil
+	case 8:
+		return pcm.U8, nil
+	default:
+		return pcm.Unknown, fmt.Errorf("%w: %d", errBitDepth, bits)
+	}
+}
+
+// bigEndian returns the big endian variant of the sample format sf. 8 bit
+// formats have no byte order and are returned unchanged.
+func bigEndian(sf pcm.SampleFormat) pcm.SampleFormat {
+	switch sf {
+	case pcm.S16_LE:
+		return pcm.S16_BE
+	case pcm.S32_LE:
+		return pcm.S32_BE
+	default:
+		return sf
+	}
+}
+
+// bitDepth returns the bit depth of the given sample format.
+func bitDepth(sf pcm.SampleFormat) (int, error) {
+	switch sf {
+	case pcm.S16_LE:
+		return 16, nil
+	case pcm.S32_LE:
+		return 32, nil
+	case pcm.U8:
+		return 8, nil
+	default:
+		return 0, fmt.Errorf("%w: %v", errBitDepth, sf)
+	}
+}
+
+// convert converts the audio src according to opts.
+func convert(src []byte, opts options) ([]byte, error) {
+	buf, err := decode(src, opts.from, opts.in)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", opts.from, err)
+	}
+
+	if opts.mono {
+		buf, err = pcm.StereoToMono(buf)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert to mono: %w", err)
+		}
+	}
+
+	if opts.outRate != 0 {
+		buf, err = pcm.Resample(buf, opts.outRate)
+		if err != nil {
+			return nil, fmt.Errorf("could not resample: %w", err)
+		}
+	}
+
+	dst, err := encode(buf, opts.to)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode %s: %w", opts.to, err)
+	}
+	return dst, nil
+}
+
+// decode returns src, of format f, as PCM. in describes raw input audio.
+func decode(src []byte, f string, in pcm.BufferFormat) (pcm.Buffer, error) {
+	switch f {
+	case fmtPCM:
+		buf := pcm.Buffer{Format: in, Data: src}
+		if in.SFormat == pcm.S16_BE || in.SFormat == pcm.S32_BE {
+			return pcm.ByteSwap(buf)
+		}
+		return buf, nil
+	case fmtADPCM:
+		var out bytes.Buffer
+		_, err := adpcm.NewDecoder(&out).Write(src)
+		if err != nil {
+			return pcm.Buffer{}, err
+		}
+		return pcm.Buffer{
+			Format: pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: in.Rate, Channels: 1},
+			Data:   out.Bytes(),
+		}, nil
+	case fmtWAV:
+		md, audio, err := wav.Decode(src)
+		if err != nil {
+			return pcm.Buffer{}, err
+		}
+		if md.AudioFormat != wav.PCMFormat {
+			return pcm.Buffer{}, fmt.Errorf("%w: WAV audio format %d", errUnknownFormat, md.AudioFormat)
+		}
+		sf, err := sampleFormat(uint(md.BitDepth))
+		if err != nil {
+			return pcm.Buffer{}, err
+		}
+		return pcm.Buffer{
+			Format: pcm.BufferFormat{SFormat: sf, Rate: uint(md.SampleRate), Channels: uint(md.Channels)},
+			Data:   audio,
+		}, nil
+	default:
+		return pcm.Buffer{}, fmt.Errorf("%w: %q", errUnknownFormat, f)
+	}
+}
+
+// encode returns the PCM buf encoded in format f.
+func encode(buf pcm.Buffer, f string) ([]byte, error) {
+	switch f {
+	case fmtPCM:
+		return buf.Data, nil
+	case fmtADPCM:
+		if buf.Format.SFormat != pcm.S16_LE || buf.Format.Channels != 1 {
+			return nil, errNotMono16
+		}
+		out := bytes.NewBuffer(make([]byte, 0, adpcm.EncBytes(len(buf.Data))))
+		_, err := adpcm.NewEncoder(out).Write(buf.Data)
+		if err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	case fmtWAV:
+		bits, err := bitDepth(buf.Format.SFormat)
+		if err != nil {
+			return nil, err
+		}
+		w := &wav.WAV{Metadata: wav.Metadata{
+			AudioFormat: wav.PCMFormat,
+			Channels:    int(buf.Format.Channels),
+			SampleRate:  int(buf.Format.Rate),
+			BitDepth:    bits,
+		}}
+		_, err = w.Write(buf.Data)
+		if err != nil {
+			return nil, err
+		}
+		return w.Audio, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownFormat, f)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+NAME
+  audio-convert/main_test.go
+
+DESCRIPTION
+  main_test.go provides testing for the conversions performed by
+  audio-convert.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ausocean/av/codec/pcm"
+)
+
+// sine returns n 16 bit little endian samples of a sine wave of frequency f
+// sampled at rate, repeated for each channel.
+func sine(n int, f, rate float64, channels int) []byte {
+	b := make([]byte, 0, n*2*channels)
+	for i := 0; i < n; i++ {
+		s := int16(0.5 * math.MaxInt16 * math.Sin(2*math.Pi*f*float64(i)/rate))
+		for c := 0; c < channels; c++ {
+			b = binary.LittleEndian.AppendUint16(b, uint16(s))
+		}
+	}
+	return b
+}
+
+// TestPCMToADPCMAndBack checks that PCM converted to ADPCM and back is close
+// to the original.
+func TestPCMToADPCMAndBack(t *testing.T) {
+	const rate = 8000
+	in := pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: rate, Channels: 1}
+	src := sine(rate, 440, rate, 1)
+
+	enc, err := convert(src, options{from: fmtPCM, to: fmtADPCM, in: in})
+	if err != nil {
+		t.Fatalf("did not expect error converting to ADPCM: %v", err)
+	}
+	if len(enc) >= len(src)/2 {
+		t.Errorf("ADPCM not compressed: got %d bytes from %d", len(enc), len(src))
+	}
+
+	dec, err := convert(enc, options{from: fmtADPCM, to: fmtPCM, in: in})
+	if err != nil {
+		t.Fatalf("did not expect error converting from ADPCM: %v", err)
+	}
+	if len(dec) != len(src) {
+		t.Fatalf("did not get expected length.\nGot: %d\nWant: %d\n", len(dec), len(src))
+	}
+
+	// ADPCM is lossy so check the signal to noise ratio.
+	var sig, noise float64
+	for i := 0; i < len(src); i += 2 {
+		want := float64(int16(binary.LittleEndian.Uint16(src[i:])))
+		got := float64(int16(binary.LittleEndian.Uint16(dec[i:])))
+		sig += want * want
+		noise += (want - got) * (want - got)
+	}
+	const minSNR = 20 // dB.
+	if snr := 10 * math.Log10(sig/noise); snr < minSNR {
+		t.Errorf("SNR too low: got %.1fdB, want at least %ddB", snr, minSNR)
+	}
+}
+
+// TestPCMToWAVAndBack checks that PCM converted to WAV and back is unchanged.
+func TestPCMToWAVAndBack(t *testing.T) {
+	in := pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: 44100, Channels: 2}
+	src := sine(1000, 1000, 44100, 2)
+
+	w, err := convert(src, options{from: fmtPCM, to: fmtWAV, in: in})
+	if err != nil {
+		t.Fatalf("did not expect error converting to WAV: %v", err)
+	}
+	got, err := convert(w, options{from: fmtWAV, to: fmtPCM})
+	if err != nil {
+		t.Fatalf("did not expect error converting from WAV: %v", err)
+	}
+	if !bytes.Equal(got, src) {
+		t.Errorf("PCM changed by conversion to and from WAV")
+	}
+}
+
+// TestWAVToADPCM checks that stereo WAV can be downmixed and resampled for
+// conversion to ADPCM, and that ADPCM conversion requires mono.
+func TestWAVToADPCM(t *testing.T) {
+	const n = 4800
+	in := pcm.BufferFormat{SFormat: pcm.S16_LE, Rate: 48000, Channels: 2}
+	w, err := convert(sine(n, 440, 48000, 2), options{from: fmtPCM, to: fmtWAV, in: in})
+	if err != nil {
+		t.Fatalf("did not expect error converting to WAV: %v", err)
+	}
+
+	_, err = convert(w, options{from: fmtWAV, to: fmtADPCM})
+	if !errors.Is(err, errNotMono16) {
+		t.Errorf("did not get expected error for stereo ADPCM.\nGot: %v\nWant: %v\n", err, errNotMono16)
+	}
+
+	enc, err := convert(w, options{from: fmtWAV, to: fmtADPCM, mono: true, outRate: 8000})
+	if err != nil {
+		t.Fatalf("did not expect error converting to ADPCM: %v", err)
+	}
+	dec, err := convert(enc, options{from: fmtADPCM, to: fmtPCM, in: pcm.BufferFormat{Rate: 8000}})
+	if err != nil {
+		t.Fatalf("did not expect error converting from ADPCM: %v", err)
+	}
+	if want := n / 6 * 2; len(dec) != want {
+		t.Errorf("did not get expected length.\nGot: %d\nWant: %d\n", len(dec), want)
+	}
+}
+
+// TestBigEndianPCM checks that big endian PCM input is converted to little
+// endian.
+func TestBigEndianPCM(t *testing.T) {
+	want := sine(100, 440, 8000, 1)
+	src := make([]byte, len(want))
+	for i := 0; i < len(want); i += 2 {
+		src[i], src[i+1] = want[i+1], want[i]
+	}
+
+	in := pcm.BufferFormat{SFormat: bigEndian(pcm.S16_LE), Rate: 8000, Channels: 1}
+	got, err := convert(src, options{from: fmtPCM, to: fmtPCM, in: in})
+	if err != nil {
+		t.Fatalf("did not expect error converting big endian PCM: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("did not get expected little endian PCM")
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct{ flag, path, want string }{
+		{"", "audio.WAV", fmtWAV},
+		{"", "/tmp/audio.adpcm", fmtADPCM},
+		{"PCM", "audio.wav", fmtPCM},
+	}
+	for _, test := range tests {
+		if got := formatOf(test.flag, test.path); got != test.want {
+			t.Errorf("did not get expected format for %q, %q.\nGot: %s\nWant: %s\n", test.flag, test.path, got, test.want)
+		}
+	}
+}
@@ -3,11 +3,14 @@ NAME
 	ts-repair/main.go
 
 DESCRIPTION
-  This program attempts to repair mpegts discontinuities using one of two methods
+  This program attempts to repair mpegts discontinuities using one of three methods
 	as selected by the mode flag. Setting the mode flag to 0 will result in repair
 	by shifting all CCs such that they are continuous. Setting the mode flag to 1
 	will result in repair through setting the discontinuity indicator to true at
-	packets where a discontinuity exists.
+	packets where a discontinuity exists. Setting the mode flag to 2 will result
+	in repair by dropping packets that are garbage or that break continuity by
+	more than the threshold flag, and then shifting CCs such that they are
+	continuous.
 
 	Specify the input file with the in flag, and the output file with out flag.
 
@@ -61,19 +64,22 @@ const (
 	errBadMode           = "Bad fix mode"
 	errAdaptationPresent = "Adaptation field is already present in packet"
 	errNoAdaptationField = "No adaptation field in this packet"
+	errBadThreshold      = "Bad drop threshold"
 )
 
 // Consts describing flag usage.
 const (
-	inUsage   = "The path to the file to be repaired"
-	outUsage  = "Output file path"
-	modeUsage = "Fix mode: 0 = cc-shift, 1 = di-update"
+	inUsage        = "The path to the file to be repaired"
+	outUsage       = "Output file path"
+	modeUsage      = "Fix mode: 0 = cc-shift, 1 = di-update, 2 = drop"
+	thresholdUsage = "Drop mode: largest CC jump, in packets, that is not dropped (0-14)"
 )
 
 // Repair modes.
 const (
 	ccShift = iota
 	diUpdate
+	drop
 )
 
 var ccMap = map[int]byte{
@@ -173,6 +179,7 @@ func main() {
 	inPtr := flag.String("in", "", inUsage)
 	outPtr := flag.String("out", "out.ts", outUsage)
 	modePtr := flag.Int("mode", diUpdate, modeUsage)
+	thresholdPtr := flag.Int("threshold", 0, thresholdUsage)
 	flag.Parse()
 
 	// Try and open the given input file, otherwise panic - we can't do anything
@@ -189,6 +196,15 @@ func main() {
 		panic(errCantCreateOut)
 	}
 
+	if *modePtr == drop {
+		n, err := dropRepair(inFile, outFile, *thresholdPtr)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Dropped %v packets\n", n)
+		return
+	}
+
 	// Read each packet from the input file reader
 	var p Packet
 	for {
@@ -252,3 +268,91 @@ func expectedCC(pid int) (byte, bool) {
 func updateCCMap(pid int, cc byte) {
 	ccMap[pid] = (cc + 1) & 0xf
 }
+
+// dropRepair reads MPEG-TS from r and writes it to w, dropping packets that are
+// garbage or break continuity. A packet is garbage if it does not start with
+// the sync byte, or has the transport error indicator set. Packets of every
+// PID are kept, and null packets and packets without a payload are passed
+// through unchanged. A packet breaks continuity if its CC jumps ahead of the
+// previous CC of its PID by more than threshold packets. A packet that does
+// not continue from the last packet kept, but does continue from the last
+// packet dropped, is kept so that the stream resyncs after a real gap. The CCs
+// of the packets that are kept are renumbered so that they are continuous.
+// The number of packets dropped is returned.
+func dropRepair(r io.Reader, w io.Writer, threshold int) (int, error) {
+	if threshold < 0 || threshold > 14 {
+		return 0, errors.New(errBadThreshold)
+	}
+
+	var (
+		p           Packet
+		dropped     int
+		n           int
+		lastCC      = make(map[int]byte)
+		lastDropped = make(map[int]byte)
+		dr          = mts.NewDiscontinuityRepairer()
+	)
+	for {
+		_, err := io.ReadFull(r, p[:])
+		if err == io.EOF {
+			return dropped, nil
+		}
+		if err != nil {
+			return dropped, fmt.Errorf("%s: %w", errReadFail, err)
+		}
+		n++
+
+		if p[0] != packet.SyncByte || p[1]&0x80 != 0 {
+			fmt.Printf("***** Dropping garbage (packetNo: %v)\n", n)
+			dropped++
+			continue
+		}
+
+		// Null packets carry no data and their CC is undefined, and packets
+		// without a payload, such as PCR-only packets, do not advance the CC.
+		pid := int(packet.Pid((*packet.Packet)(&p)))
+		if pid == mts.NullPid || (p[3]>>4)&mts.HasPayload == 0 {
+			_, err = w.Write(p[:])
+			if err != nil {
+				return dropped, fmt.Errorf("%s: %w", errWriteFail, err)
+			}
+			continue
+		}
+
+		// Check continuity against the last packet kept, or failing that, the
+		// last packet dropped, so that we can resync after a real gap.
+		cc := p.CC()
+		last, seen := lastCC[pid]
+		if seen && !within(last, cc, threshold) {
+			prev, ok := lastDropped[pid]
+			lastDropped[pid] = cc
+			if !ok || !within(prev, cc, threshold) {
+				fmt.Printf("***** Dropping discontinuous packet (packetNo: %v pid: %v, cc: %v, last: %v)\n", n, pid, cc, last)
+				dropped++
+				continue
+			}
+		}
+		lastCC[pid] = cc
+		delete(lastDropped, pid)
+
+		// Renumber the CC so that the output is continuous.
+		expect, ok := dr.ExpectedCC(pid)
+		if ok {
+			p[3] = p[3]&0xf0 | byte(expect)
+		} else {
+			dr.SetExpectedCC(pid, int(cc))
+		}
+		dr.IncExpectedCC(pid)
+
+		_, err = w.Write(p[:])
+		if err != nil {
+			return dropped, fmt.Errorf("%s: %w", errWriteFail, err)
+		}
+	}
+}
+
+// within returns true if cc jumps ahead of last by no more than threshold
+// packets.
+func within(last, cc byte, threshold int) bool {
+	return int((cc-last-1)&0xf) <= threshold
+}
@@ -0,0 +1,177 @@
+/*
+NAME
+  ts-repair/main_test.go
+
+DESCRIPTION
+  main_test.go provides testing for the drop repair mode of ts-repair.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/Comcast/gots/v2/packet"
+	"github.com/ausocean/av/container/mts"
+)
+
+// TestDropRepair checks that drop mode removes an injected burst of garbage
+// and a discontinuous packet, that the output is continuous, and that audio,
+// null and PCR-only packets survive unchanged.
+func TestDropRepair(t *testing.T) {
+	const (
+		nPackets  = 60
+		burstAt   = 20
+		burstLen  = 5
+		skipAt    = 40
+		threshold = 2
+	)
+
+	// Generate a continuous stream of PSI, video, audio and null packets, and
+	// PCR-only video packets, which do not advance the CC.
+	var (
+		pkts  [][]byte
+		other [][]byte // Audio, null and PCR-only packets.
+	)
+	cc := map[uint16]byte{}
+	for i := 0; i < nPackets; i++ {
+		pid := uint16(mts.PIDVideo)
+		switch i % 10 {
+		case 0:
+			pid = mts.PatPid
+		case 1:
+			pid = mts.PmtPid
+		case 2, 6:
+			pid = mts.PIDAudio
+		case 8:
+			pid = mts.NullPid
+		}
+		if i%10 == 4 {
+			p := mts.Packet{PID: pid, CC: (cc[pid] - 1) & 0xf, AFC: mts.HasAdaptationField, PCRF: true, PCR: uint64(i)}
+			pkts = append(pkts, p.Bytes(nil))
+			other = append(other, pkts[len(pkts)-1])
+			continue
+		}
+		p := mts.Packet{PID: pid, CC: cc[pid], AFC: mts.HasPayload, Payload: []byte{byte(i)}}
+		if pid != mts.NullPid {
+			cc[pid] = (cc[pid] + 1) & 0xf
+		}
+		pkts = append(pkts, p.Bytes(nil))
+		if pid == mts.PIDAudio || pid == mts.NullPid {
+			other = append(other, pkts[len(pkts)-1])
+		}
+	}
+
+	// Make a video packet jump well beyond the threshold.
+	pkts[skipAt+5][3] = pkts[skipAt+5][3]&0xf0 | (pkts[skipAt+5][3]+8)&0xf
+
+	// Inject a burst of garbage.
+	rng := rand.New(rand.NewSource(1))
+	var burst [][]byte
+	for i := 0; i < burstLen; i++ {
+		g := make([]byte, mts.PacketSize)
+		rng.Read(g)
+		g[0] = 0x00
+		burst = append(burst, g)
+	}
+	// Garbage that happens to have a sync byte, but the transport error
+	// indicator set.
+	g := make([]byte, mts.PacketSize)
+	rng.Read(g)
+	g[0], g[1], g[2] = packet.SyncByte, 0x9f, 0xfe
+	burst = append(burst, g)
+	pkts = append(pkts[:burstAt:burstAt], append(burst, pkts[burstAt:]...)...)
+
+	var in bytes.Buffer
+	for _, p := range pkts {
+		in.Write(p)
+	}
+	inLen := in.Len()
+
+	var out bytes.Buffer
+	dropped, err := dropRepair(&in, &out, threshold)
+	if err != nil {
+		t.Fatalf("did not expect error from dropRepair: %v", err)
+	}
+
+	wantDropped := len(burst) + 1
+	if dropped != wantDropped {
+		t.Errorf("did not get expected number of dropped packets.\nGot: %v\nWant: %v\n", dropped, wantDropped)
+	}
+	if out.Len() != inLen-wantDropped*mts.PacketSize {
+		t.Errorf("did not get expected output length.\nGot: %v\nWant: %v\n", out.Len(), inLen-wantDropped*mts.PacketSize)
+	}
+
+	// Check the output is continuous, and audio, null and PCR-only packets
+	// unchanged.
+	next := map[uint16]byte{}
+	b := out.Bytes()
+	var gotOther [][]byte
+	for i := 0; i < len(b); i += mts.PacketSize {
+		var p packet.Packet
+		copy(p[:], b[i:i+mts.PacketSize])
+		pid := p.PID()
+		payload := p.HasPayload()
+		if pid == mts.PIDAudio || pid == mts.NullPid || !payload {
+			gotOther = append(gotOther, b[i:i+mts.PacketSize])
+		}
+		if pid == mts.NullPid || !payload {
+			continue
+		}
+		got := byte(p.ContinuityCounter())
+		if want, ok := next[uint16(pid)]; ok && got != want {
+			t.Errorf("discontinuity in output at packet %d (pid %d).\nGot: %v\nWant: %v\n", i/mts.PacketSize, pid, got, want)
+		}
+		next[uint16(pid)] = (got + 1) & 0xf
+	}
+	if !bytes.Equal(bytes.Join(gotOther, nil), bytes.Join(other, nil)) {
+		t.Errorf("did not get expected audio, null and PCR-only packets.\nGot: %d packets\nWant: %d packets\n", len(gotOther), len(other))
+	}
+}
+
+// TestDropRepairThreshold checks that jumps within the threshold are kept, and
+// that invalid thresholds are rejected.
+func TestDropRepairThreshold(t *testing.T) {
+	var in bytes.Buffer
+	for _, cc := range []byte{0, 1, 3, 4, 7} {
+		p := mts.Packet{PID: mts.PIDVideo, CC: cc, AFC: mts.HasPayload, Payload: []byte{cc}}
+		in.Write(p.Bytes(nil))
+	}
+	data := in.Bytes()
+
+	tests := []struct {
+		threshold int
+		dropped   int
+	}{
+		{threshold: 0, dropped: 2},
+		{threshold: 1, dropped: 1},
+		{threshold: 2, dropped: 0},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		dropped, err := dropRepair(bytes.NewReader(data), &out, test.threshold)
+		if err != nil {
+			t.Fatalf("did not expect error for threshold %d: %v", test.threshold, err)
+		}
+		if dropped != test.dropped {
+			t.Errorf("did not get expected number dropped for threshold %d.\nGot: %v\nWant: %v\n", test.threshold, dropped, test.dropped)
+		}
+	}
+
+	for _, threshold := range []int{-1, 15} {
+		_, err := dropRepair(bytes.NewReader(data), &bytes.Buffer{}, threshold)
+		if err == nil {
+			t.Errorf("expected error for threshold %d", threshold)
+		}
+	}
+}
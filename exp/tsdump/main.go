@@ -0,0 +1,180 @@
+/*
+NAME
+  tsdump/main.go
+
+DESCRIPTION
+  tsdump prints a summary of the structure of an MPEG-TS file, including the
+  packet count, the PIDs present, the contents of the first PSI, the PTS range
+  of the media, any metadata and any continuity counter discontinuities.
+
+  Specify the input file with the in flag. Setting the json flag will print the
+  summary as JSON rather than as human readable text.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/ausocean/av/container/mts"
+)
+
+// summary holds the results of inspecting an MPEG-TS clip.
+type summary struct {
+	Packets         int                  `json:"packets"`         // Number of packets in the clip.
+	PIDs            map[uint16]int       `json:"pids"`            // Packet counts keyed by PID.
+	PSIIndex        int                  `json:"psiIndex"`        // Byte index of the first PAT.
+	Streams         map[uint16]uint8     `json:"streams"`         // Stream types keyed by elementary PID.
+	PTSRange        map[uint16][2]uint64 `json:"ptsRange"`        // First and last PTS keyed by elementary PID.
+	Meta            map[string]string    `json:"meta"`            // Metadata from the first PMT.
+	Discontinuities []discontinuity      `json:"discontinuities"` // Continuity counter discontinuities.
+}
+
+// discontinuity describes an unexpected continuity counter in a packet.
+type discontinuity struct {
+	Packet int    `json:"packet"` // Packet number (from 0) in the clip.
+	PID    uint16 `json:"pid"`
+	Got    byte   `json:"got"`
+	Want   byte   `json:"want"`
+}
+
+func main() {
+	inPath := flag.String("in", "", "The path to the MPEG-TS file to inspect")
+	asJSON := flag.Bool("json", false, "Print the summary as JSON")
+	flag.Parse()
+
+	clip, err := os.ReadFile(*inPath)
+	if err != nil {
+		log.Fatalf("could not read input file: %v", err)
+	}
+
+	s, err := inspect(clip)
+	if err != nil {
+		log.Fatalf("could not inspect clip: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(s)
+		if err != nil {
+			log.Fatalf("could not encode summary: %v", err)
+		}
+		return
+	}
+	s.print(os.Stdout)
+}
+
+// inspect produces a summary of the MPEG-TS clip. The clip must contain only
+// complete packets.
+func inspect(clip []byte) (*summary, error) {
+	if len(clip)%mts.PacketSize != 0 {
+		return nil, mts.ErrInvalidLen
+	}
+
+	pids, err := mts.PIDCounts(clip)
+	if err != nil {
+		return nil, fmt.Errorf("could not count PIDs: %w", err)
+	}
+
+	s := &summary{
+		Packets:  len(clip) / mts.PacketSize,
+		PIDs:     pids,
+		PTSRange: make(map[uint16][2]uint64),
+	}
+
+	// Check continuity. Null packets have no defined CC, and packets without a
+	// payload don't advance it, so they are skipped.
+	expect := make(map[uint16]byte)
+	for i := 0; i < len(clip); i += mts.PacketSize {
+		pkt := clip[i : i+mts.PacketSize]
+		pid, err := mts.PID(pkt)
+		if err != nil {
+			return nil, fmt.Errorf("could not get PID of packet %d: %w", i/mts.PacketSize, err)
+		}
+		if pid == mts.NullPid || (pkt[3]>>4)&mts.HasPayload == 0 {
+			continue
+		}
+
+		cc := pkt[3] & 0x0f
+		if want, ok := expect[pid]; ok && cc != want {
+			s.Discontinuities = append(s.Discontinuities, discontinuity{Packet: i / mts.PacketSize, PID: pid, Got: cc, Want: want})
+		}
+		expect[pid] = (cc + 1) & 0x0f
+	}
+
+	s.PSIIndex, s.Streams, s.Meta, err = mts.FindPSI(clip)
+	if err != nil {
+		return s, fmt.Errorf("could not find PSI: %w", err)
+	}
+
+	for pid := range s.Streams {
+		pts, err := mts.GetPTSRange(clip, pid)
+		if err != nil {
+			continue
+		}
+		s.PTSRange[pid] = pts
+	}
+
+	return s, nil
+}
+
+// print writes a human readable form of the summary to w.
+func (s *summary) print(w io.Writer) {
+	fmt.Fprintf(w, "packets: %d\n", s.Packets)
+
+	fmt.Fprintln(w, "pids:")
+	for _, pid := range sortedPIDs(s.PIDs) {
+		fmt.Fprintf(w, "  %d: %d packets\n", pid, s.PIDs[pid])
+	}
+
+	fmt.Fprintf(w, "psi index: %d\n", s.PSIIndex)
+
+	fmt.Fprintln(w, "streams:")
+	for _, pid := range sortedPIDs(s.Streams) {
+		fmt.Fprintf(w, "  pid: %d, type: %#x", pid, s.Streams[pid])
+		if pts, ok := s.PTSRange[pid]; ok {
+			fmt.Fprintf(w, ", pts: %d-%d", pts[0], pts[1])
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "meta:")
+	keys := make([]string, 0, len(s.Meta))
+	for k := range s.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s: %s\n", k, s.Meta[k])
+	}
+
+	fmt.Fprintf(w, "discontinuities: %d\n", len(s.Discontinuities))
+	for _, d := range s.Discontinuities {
+		fmt.Fprintf(w, "  packet: %d, pid: %d, cc: %d, expected: %d\n", d.Packet, d.PID, d.Got, d.Want)
+	}
+}
+
+// sortedPIDs returns the keys of m in ascending order.
+func sortedPIDs[V any](m map[uint16]V) []uint16 {
+	pids := make([]uint16, 0, len(m))
+	for pid := range m {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	return pids
+}
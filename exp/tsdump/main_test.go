@@ -0,0 +1,156 @@
+/*
+NAME
+  tsdump/main_test.go
+
+DESCRIPTION
+  main_test.go provides testing for the clip inspection performed by tsdump.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ausocean/av/container/mts"
+	"github.com/ausocean/av/container/mts/meta"
+	"github.com/ausocean/av/container/mts/pes"
+	"github.com/ausocean/utils/logging"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+// TestInspect checks that inspect correctly summarises a clip generated by the
+// MPEG-TS encoder.
+func TestInspect(t *testing.T) {
+	const (
+		nFrames   = 10
+		frameSize = 500
+		rate      = 25
+		key, val  = "site", "test"
+	)
+
+	oldMeta := mts.Meta
+	t.Cleanup(func() { mts.Meta = oldMeta })
+	mts.Meta = meta.NewWith([][2]string{{key, val}})
+
+	var buf bytes.Buffer
+	e, err := mts.NewEncoder(nopCloser{&buf}, (*logging.TestLogger)(t), mts.PacketBasedPSI(7), mts.Rate(rate), mts.MediaType(mts.EncodeH264))
+	if err != nil {
+		t.Fatalf("could not create encoder: %v", err)
+	}
+	for i := 0; i < nFrames; i++ {
+		_, err = e.Write(make([]byte, frameSize))
+		if err != nil {
+			t.Fatalf("could not write frame %d: %v", i, err)
+		}
+	}
+
+	s, err := inspect(buf.Bytes())
+	if err != nil {
+		t.Fatalf("did not expect error from inspect: %v", err)
+	}
+
+	if s.Packets != buf.Len()/mts.PacketSize {
+		t.Errorf("unexpected packet count: got %d, want %d", s.Packets, buf.Len()/mts.PacketSize)
+	}
+
+	var total int
+	for _, n := range s.PIDs {
+		total += n
+	}
+	if total != s.Packets {
+		t.Errorf("PID counts do not sum to packet count: got %d, want %d", total, s.Packets)
+	}
+	if s.PIDs[mts.PatPid] == 0 || s.PIDs[mts.PmtPid] == 0 || s.PIDs[mts.PIDVideo] == 0 {
+		t.Errorf("expected PAT, PMT and video PIDs to be present: %v", s.PIDs)
+	}
+
+	if s.PSIIndex != 0 {
+		t.Errorf("unexpected PSI index: got %d, want 0", s.PSIIndex)
+	}
+	if typ, ok := s.Streams[mts.PIDVideo]; !ok || typ != pes.H264SID {
+		t.Errorf("unexpected streams: got %v, want video stream of type %d", s.Streams, pes.H264SID)
+	}
+
+	const (
+		firstPTS = 63000 // 700ms PTS offset at 90kHz.
+		period   = mts.PTSFrequency / rate
+	)
+	// The encoder derives PTS from floating point seconds, so allow for a tick
+	// of rounding.
+	want := [2]uint64{firstPTS, firstPTS + (nFrames-1)*period}
+	got := s.PTSRange[mts.PIDVideo]
+	for i := range want {
+		if got[i]+1 < want[i] || got[i] > want[i]+1 {
+			t.Errorf("unexpected PTS range: got %v, want %v", got, want)
+			break
+		}
+	}
+
+	if s.Meta[key] != val {
+		t.Errorf("unexpected meta: got %v, want %s=%s", s.Meta, key, val)
+	}
+
+	if len(s.Discontinuities) != 0 {
+		t.Errorf("did not expect discontinuities, got: %v", s.Discontinuities)
+	}
+}
+
+// TestInspectContinuity checks that null packets and packets without a
+// payload are not reported as discontinuities, but real jumps are.
+func TestInspectContinuity(t *testing.T) {
+	var clip []byte
+	add := func(p mts.Packet) { clip = append(clip, p.Bytes(nil)...) }
+	add(mts.Packet{PID: mts.PIDVideo, CC: 0, AFC: mts.HasPayload, Payload: []byte{1}})
+	add(mts.Packet{PID: mts.NullPid, AFC: mts.HasPayload})
+	add(mts.Packet{PID: mts.PIDVideo, CC: 0, AFC: mts.HasAdaptationField})
+	add(mts.Packet{PID: mts.NullPid, AFC: mts.HasPayload})
+	add(mts.Packet{PID: mts.PIDVideo, CC: 1, AFC: mts.HasPayload, Payload: []byte{2}})
+	add(mts.Packet{PID: mts.PIDVideo, CC: 5, AFC: mts.HasPayload, Payload: []byte{3}})
+
+	s, err := inspect(clip)
+	if s == nil {
+		t.Fatalf("did not expect nil summary: %v", err)
+	}
+	want := []discontinuity{{Packet: 5, PID: mts.PIDVideo, Got: 5, Want: 2}}
+	if !reflect.DeepEqual(s.Discontinuities, want) {
+		t.Errorf("did not get expected discontinuities.\nGot: %v\nWant: %v\n", s.Discontinuities, want)
+	}
+}
+
+// TestPrintOrder checks that print lists streams in PID order.
+func TestPrintOrder(t *testing.T) {
+	s := &summary{
+		PIDs:    map[uint16]int{300: 1, 100: 1, 200: 1},
+		Streams: map[uint16]uint8{300: pes.H264SID, 100: pes.H265SID, 200: pes.PCMSID},
+	}
+	var first string
+	for i := 0; i < 10; i++ {
+		var b bytes.Buffer
+		s.print(&b)
+		if i == 0 {
+			first = b.String()
+			continue
+		}
+		if b.String() != first {
+			t.Fatalf("print output changed between calls.\nGot: %s\nWant: %s\n", b.String(), first)
+		}
+	}
+	i100, i200, i300 := strings.Index(first, "pid: 100"), strings.Index(first, "pid: 200"), strings.Index(first, "pid: 300")
+	if i100 < 0 || !(i100 < i200 && i200 < i300) {
+		t.Errorf("streams not printed in PID order:\n%s", first)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+NAME
+  wav-split/main.go
+
+DESCRIPTION
+  wav-split is a command-line program for splitting a stereo WAV file into
+  two mono WAV files, one holding the left channel and the other the right.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ausocean/av/codec/wav"
+)
+
+func main() {
+	var (
+		inPath    = flag.String("in", "stereo.wav", "file path of input stereo WAV")
+		leftPath  = flag.String("left", "left.wav", "file path of output left channel WAV")
+		rightPath = flag.String("right", "right.wav", "file path of output right channel WAV")
+	)
+	flag.Parse()
+
+	in, err := os.ReadFile(*inPath)
+	if err != nil {
+		log.Fatalf("could not read input file: %v", err)
+	}
+
+	left, right, err := wav.SplitStereo(in)
+	if err != nil {
+		log.Fatalf("could not split WAV: %v", err)
+	}
+
+	err = os.WriteFile(*leftPath, left, 0644)
+	if err != nil {
+		log.Fatalf("could not write left channel: %v", err)
+	}
+	err = os.WriteFile(*rightPath, right, 0644)
+	if err != nil {
+		log.Fatalf("could not write right channel: %v", err)
+	}
+	fmt.Println("Split", len(in), "bytes into", *leftPath, "and", *rightPath)
+}
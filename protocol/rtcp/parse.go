@@ -51,3 +51,32 @@ func ParseTimestamp(buf []byte) (Timestamp, error) {
 		Fraction: binary.BigEndian.Uint32(buf[12:]),
 	}, nil
 }
+
+// ParseSenderReport parses the sender information of an RTCP sender report.
+// Any report blocks are ignored.
+func ParseSenderReport(buf []byte) (SenderReport, error) {
+	if len(buf) < senderReportSize {
+		return SenderReport{}, errors.New("bad RTCP packet, not of sufficient length")
+	}
+	if (buf[0]&0xc0)>>6 != rtcpVer {
+		return SenderReport{}, errors.New("incompatible RTCP version")
+	}
+	if buf[1] != typeSenderReport {
+		return SenderReport{}, errors.New("RTCP packet is not of sender report type")
+	}
+
+	return SenderReport{
+		Header: Header{
+			Version:     buf[0] >> 6,
+			Padding:     buf[0]&0x20 != 0,
+			ReportCount: buf[0] & 0x1f,
+			Type:        buf[1],
+		},
+		SSRC:         binary.BigEndian.Uint32(buf[4:]),
+		TimestampMSW: binary.BigEndian.Uint32(buf[8:]),
+		TimestampLSW: binary.BigEndian.Uint32(buf[12:]),
+		RTPTimestamp: binary.BigEndian.Uint32(buf[16:]),
+		PacketCount:  binary.BigEndian.Uint32(buf[20:]),
+		OctetCount:   binary.BigEndian.Uint32(buf[24:]),
+	}, nil
+}
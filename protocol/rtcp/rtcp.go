@@ -156,7 +156,7 @@ type SenderReport struct {
 // Bytes returns a []byte of the SenderReport.
 func (r *SenderReport) Bytes() []byte {
 	buf := make([]byte, senderReportSize)
-	r.writeHeader(buf, senderReportSize-1)
+	r.writeHeader(buf, senderReportSize/4-1)
 	for i, w := range []uint32{
 		r.SSRC,
 		r.TimestampMSW,
@@ -165,7 +165,7 @@ func (r *SenderReport) Bytes() []byte {
 		r.PacketCount,
 		r.OctetCount,
 	} {
-		binary.BigEndian.PutUint32(buf[i+4:], w)
+		binary.BigEndian.PutUint32(buf[4+4*i:], w)
 	}
 	return buf
 }
@@ -0,0 +1,164 @@
+/*
+NAME
+  sender.go
+
+DESCRIPTION
+  sender.go provides a Sender that periodically sends RTCP sender reports for
+  an RTP session.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package rtcp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ausocean/utils/logging"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+// the Unix epoch (1970).
+const ntpEpochOffset = 2208988800
+
+// rtpClockRate is the clock rate of the RTP timestamps of the session, which is
+// 90kHz for MPEG-TS.
+const rtpClockRate = 90000 // Hz
+
+// Source provides the sender information of an RTP session for sender
+// reports, including the wall-clock time at which the packet with the given
+// timestamp was sent. It is implemented by *rtp.Encoder.
+type Source interface {
+	SenderInfo() (ssrc, timestamp, packets, octets uint32, sent time.Time)
+}
+
+// Sender periodically sends RTCP sender reports describing an RTP session to
+// a receiver. By convention the receiver's RTCP port is one more than its RTP
+// port.
+type Sender struct {
+	src      Source           // Source of sender information.
+	conn     *net.UDPConn     // The UDP connection used for sending reports.
+	interval time.Duration    // Interval between sender reports.
+	now      func() time.Time // Used to get the time for reports; replaceable for testing.
+	wg       sync.WaitGroup   // Used to wait for the send routine to stop.
+	quit     chan struct{}    // Used to communicate a quit signal to the send routine.
+	log      Log              // Used to log any messages.
+	err      chan error       // Sender will send any errors through this chan. Can be accessed by Err().
+}
+
+// NewSender returns a pointer to a new Sender that will send sender reports
+// for the RTP session described by src to the given address.
+func NewSender(address string, src Source, l Log) (*Sender, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("can't resolve receiver address: %w", err)
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't dial: %w", err)
+	}
+	return &Sender{
+		src:      src,
+		conn:     conn,
+		interval: defaultSendInterval,
+		now:      time.Now,
+		quit:     make(chan struct{}),
+		log:      l,
+		err:      make(chan error),
+	}, nil
+}
+
+// SetSendInterval sets a custom sender report send interval (default is 2 seconds.)
+func (s *Sender) SetSendInterval(d time.Duration) {
+	s.interval = d
+}
+
+// Start starts the routine sending sender reports.
+func (s *Sender) Start() {
+	s.log(logging.Debug, pkg+"Sender is starting")
+	s.wg.Add(1)
+	go s.send()
+}
+
+// Stop sends a quit signal to the send routine and closes the UDP connection.
+// It will wait until the routine has returned.
+func (s *Sender) Stop() {
+	s.log(logging.Debug, pkg+"Sender is stopping")
+	close(s.quit)
+	s.wg.Wait()
+	s.conn.Close()
+	close(s.err)
+}
+
+// Err provides read access to the Sender err channel. This must be checked
+// otherwise the Sender will block if an error is encountered.
+func (s *Sender) Err() <-chan error {
+	return s.err
+}
+
+// Report returns a sender report for the current state of the RTP session.
+// The RTP timestamp of the last packet sent is advanced by the time since it
+// was sent, so that it corresponds to the same instant as the NTP timestamp.
+func (s *Sender) Report() SenderReport {
+	ssrc, ts, packets, octets, sent := s.src.SenderInfo()
+	now := s.now()
+	if d := now.Sub(sent); !sent.IsZero() && d > 0 {
+		ts += uint32(d.Seconds() * rtpClockRate)
+	}
+	msw, lsw := ntpTime(now)
+	return SenderReport{
+		Header: Header{
+			Version:     rtcpVer,
+			Padding:     false,
+			ReportCount: 0,
+			Type:        typeSenderReport,
+		},
+		SSRC:         ssrc,
+		TimestampMSW: msw,
+		TimestampLSW: lsw,
+		RTPTimestamp: ts,
+		PacketCount:  packets,
+		OctetCount:   octets,
+	}
+}
+
+// send writes sender reports to the receiver every interval.
+func (s *Sender) send() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+			r := s.Report()
+			s.log(logging.Debug, pkg+"sending sender report")
+			_, err := s.conn.Write(r.Bytes())
+			if err != nil {
+				select {
+				case s.err <- err:
+				case <-s.quit:
+					return
+				}
+			}
+		}
+	}
+}
+
+// ntpTime returns the most and least significant words of the NTP timestamp
+// for t, see https://tools.ietf.org/html/rfc1305
+func ntpTime(t time.Time) (msw, lsw uint32) {
+	msw = uint32(t.Unix() + ntpEpochOffset)
+	lsw = uint32((uint64(t.Nanosecond()) << 32) / uint64(time.Second))
+	return msw, lsw
+}
@@ -0,0 +1,169 @@
+/*
+NAME
+  sender_test.go
+
+DESCRIPTION
+  sender_test.go provides testing for functionality in sender.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package rtcp
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ausocean/av/protocol/rtp"
+)
+
+// TestSenderReportBytes checks that a SenderReport round trips through Bytes
+// and ParseSenderReport.
+func TestSenderReportBytes(t *testing.T) {
+	want := SenderReport{
+		Header:       Header{Version: rtcpVer, Type: typeSenderReport},
+		SSRC:         1873625286,
+		TimestampMSW: 2209003992,
+		TimestampLSW: 1956821460,
+		RTPTimestamp: 1260149413,
+		PacketCount:  102,
+		OctetCount:   115397,
+	}
+	b := want.Bytes()
+	if len(b) != senderReportSize {
+		t.Fatalf("did not get expected length.\nGot: %v\nWant: %v\n", len(b), senderReportSize)
+	}
+	if b[2] != 0x00 || b[3] != 0x06 {
+		t.Errorf("did not get expected length field.\nGot: %v\nWant: %v\n", b[2:4], []byte{0x00, 0x06})
+	}
+
+	got, err := ParseSenderReport(b)
+	if err != nil {
+		t.Fatalf("did not expect error parsing sender report: %v", err)
+	}
+	if got != want {
+		t.Errorf("did not get expected sender report.\nGot: %+v\nWant: %+v\n", got, want)
+	}
+
+	ts, err := ParseTimestamp(b)
+	if err != nil {
+		t.Fatalf("did not expect error parsing timestamp: %v", err)
+	}
+	if ts.Seconds != want.TimestampMSW || ts.Fraction != want.TimestampLSW {
+		t.Errorf("did not get expected timestamp.\nGot: %+v\n", ts)
+	}
+}
+
+// TestSender checks that a Sender sends sender reports with counts matching
+// what has been sent by an RTP encoder.
+func TestSender(t *testing.T) {
+	const (
+		nPackets    = 10
+		payloadSize = 7 * 188
+	)
+
+	recv, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer recv.Close()
+
+	enc := rtp.NewEncoder(io.Discard, 25)
+	for i := 0; i < nPackets; i++ {
+		err = enc.Encode(make([]byte, payloadSize))
+		if err != nil {
+			t.Fatalf("did not expect error encoding: %v", err)
+		}
+	}
+	ssrc, ts, _, _, sent := enc.SenderInfo()
+
+	s, err := NewSender(recv.LocalAddr().String(), enc, (*dummyLogger)(t).log)
+	if err != nil {
+		t.Fatalf("did not expect error creating sender: %v", err)
+	}
+	now := sent.Add(time.Second / 4)
+	s.now = func() time.Time { return now }
+	s.SetSendInterval(10 * time.Millisecond)
+	s.Start()
+	defer s.Stop()
+
+	buf := make([]byte, 1500)
+	recv.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := recv.Read(buf)
+	if err != nil {
+		t.Fatalf("did not receive sender report: %v", err)
+	}
+
+	got, err := ParseSenderReport(buf[:n])
+	if err != nil {
+		t.Fatalf("did not expect error parsing sender report: %v", err)
+	}
+	msw, lsw := ntpTime(now)
+	want := SenderReport{
+		Header:       Header{Version: rtcpVer, Type: typeSenderReport},
+		SSRC:         ssrc,
+		TimestampMSW: msw,
+		TimestampLSW: lsw,
+		RTPTimestamp: ts + rtpClockRate/4,
+		PacketCount:  nPackets,
+		OctetCount:   nPackets * payloadSize,
+	}
+	if got != want {
+		t.Errorf("did not get expected sender report.\nGot: %+v\nWant: %+v\n", got, want)
+	}
+}
+
+// clockSource is a Source reporting a fixed RTP timestamp and send time.
+type clockSource struct {
+	ts   uint32
+	sent time.Time
+}
+
+func (c clockSource) SenderInfo() (ssrc, timestamp, packets, octets uint32, sent time.Time) {
+	return 1, c.ts, 1, 1, c.sent
+}
+
+// TestSenderReportTimestamps checks that the RTP and NTP timestamps of sender
+// reports refer to the same instant, however long after the last packet was
+// sent the report is made.
+func TestSenderReportTimestamps(t *testing.T) {
+	sent := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := clockSource{ts: 0xffff0000, sent: sent}
+	s := &Sender{src: src}
+
+	tests := []struct {
+		after time.Duration
+		want  uint32
+	}{
+		{after: 0, want: 0xffff0000},
+		{after: 100 * time.Millisecond, want: 0xffff0000 + 9000},
+		{after: 2 * time.Second, want: 180000 - 0x10000}, // Wraps.
+		{after: -time.Second, want: 0xffff0000},          // Clock stepped back.
+	}
+	for _, test := range tests {
+		now := sent.Add(test.after)
+		s.now = func() time.Time { return now }
+		r := s.Report()
+		if r.RTPTimestamp != test.want {
+			t.Errorf("did not get expected RTP timestamp %v after send.\nGot: %d\nWant: %d\n", test.after, r.RTPTimestamp, test.want)
+		}
+		msw, lsw := ntpTime(now)
+		if r.TimestampMSW != msw || r.TimestampLSW != lsw {
+			t.Errorf("did not get expected NTP timestamp %v after send.\nGot: %d.%d\nWant: %d.%d\n", test.after, r.TimestampMSW, r.TimestampLSW, msw, lsw)
+		}
+	}
+
+	// Before any packets are sent there is no send time to advance from.
+	s.src = clockSource{ts: 1000}
+	if r := s.Report(); r.RTPTimestamp != 1000 {
+		t.Errorf("did not get expected RTP timestamp with no packets sent.\nGot: %d\nWant: 1000\n", r.RTPTimestamp)
+	}
+}
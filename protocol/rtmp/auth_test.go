@@ -0,0 +1,290 @@
+/*
+NAME
+  auth_test.go
+
+DESCRIPTION
+  auth_test.go provides testing for the authentication functionality in
+  auth.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package rtmp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ausocean/av/protocol/rtmp/amf"
+)
+
+// Captured connect rejection descriptions.
+const (
+	descNeedAuth      = "[ AccessManager.Reject ] : [ code=403 need auth; authmod=adobe ] : "
+	descAdobeChal     = "[ AccessManager.Reject ] : [ authmod=adobe ] : ?reason=needauth&user=ausocean&salt=lFqB9A==&challenge=AaBbCc==&opaque=vgoAAA=="
+	descAdobeNoOpaque = "[ AccessManager.Reject ] : [ authmod=adobe ] : ?reason=needauth&user=ausocean&salt=lFqB9A==&challenge=AaBbCc=="
+	descAuthFailed    = "[ AccessManager.Reject ] : [ authmod=adobe ] : ?reason=authfailed&opaque=vgoAAA=="
+	descLlnwNeedAuth  = "[ AccessManager.Reject ] : [ code=403 need auth; authmod=llnw ] : "
+	descLlnwChal      = "[ AccessManager.Reject ] : [ authmod=llnw ] : ?reason=needauth&user=ausocean&nonce=bJJ3gNI="
+)
+
+// Test credentials and client nonce.
+const (
+	testUser     = "ausocean"
+	testPassword = "secret"
+	testNonce    = "0a1b2c3d"
+)
+
+// setTestNonce replaces authNonce with a function returning testNonce for the
+// duration of the test.
+func setTestNonce(t *testing.T) {
+	orig := authNonce
+	authNonce = func() string { return testNonce }
+	t.Cleanup(func() { authNonce = orig })
+}
+
+func TestHandleConnectError(t *testing.T) {
+	setTestNonce(t)
+
+	type step struct {
+		desc      string
+		wantErr   error
+		wantQuery string
+	}
+	tests := []struct {
+		name  string
+		user  string
+		steps []step
+	}{
+		{
+			name: "adobe",
+			user: testUser,
+			steps: []step{
+				{desc: descNeedAuth, wantErr: errAuthRetry, wantQuery: "?authmod=adobe&user=ausocean"},
+				{desc: descAdobeChal, wantErr: errAuthRetry, wantQuery: "?authmod=adobe&user=ausocean&challenge=0a1b2c3d&response=aNhSQqq+m701jrP4dK5Hqw==&opaque=vgoAAA=="},
+				{desc: descAuthFailed, wantErr: errAuthFailed},
+			},
+		},
+		{
+			name: "adobe without opaque",
+			user: testUser,
+			steps: []step{
+				{desc: descNeedAuth, wantErr: errAuthRetry, wantQuery: "?authmod=adobe&user=ausocean"},
+				{desc: descAdobeNoOpaque, wantErr: errAuthRetry, wantQuery: "?authmod=adobe&user=ausocean&challenge=0a1b2c3d&response=rX7YV1EQK7/kTx61ApXSzQ=="},
+			},
+		},
+		{
+			name: "llnw",
+			user: testUser,
+			steps: []step{
+				{desc: descLlnwNeedAuth, wantErr: errAuthRetry, wantQuery: "?authmod=llnw&user=ausocean"},
+				{desc: descLlnwChal, wantErr: errAuthRetry, wantQuery: "?authmod=llnw&user=ausocean&nonce=bJJ3gNI=&cnonce=0a1b2c3d&nc=00000001&response=9c973f205edde73008e4cbcddcb51451"},
+			},
+		},
+		{
+			name: "escaped user",
+			user: "ocean&sea",
+			steps: []step{
+				{desc: descNeedAuth, wantErr: errAuthRetry, wantQuery: "?authmod=adobe&user=ocean%26sea"},
+				{desc: descAdobeChal, wantErr: errAuthRetry, wantQuery: "?authmod=adobe&user=ocean%26sea&challenge=0a1b2c3d&response=xsPewKERw+Aa4TLR7aS/6A==&opaque=vgoAAA=="},
+			},
+		},
+		{
+			name: "repeated challenge",
+			user: testUser,
+			steps: []step{
+				{desc: descNeedAuth, wantErr: errAuthRetry, wantQuery: "?authmod=adobe&user=ausocean"},
+				{desc: descAdobeChal, wantErr: errAuthRetry},
+				{desc: descAdobeChal, wantErr: errAuthFailed},
+			},
+		},
+		{
+			name:  "no credentials",
+			steps: []step{{desc: descNeedAuth, wantErr: errConnectRejected}},
+		},
+		{
+			name:  "unknown mode",
+			user:  testUser,
+			steps: []step{{desc: "[ AccessManager.Reject ] : [ code=403 need auth; authmod=other ] : ", wantErr: errUnknownAuthMode}},
+		},
+	}
+
+	for _, test := range tests {
+		c := &Conn{link: link{app: testApp, user: test.user, password: testPassword}}
+		for i, s := range test.steps {
+			err := handleConnectError(c, s.desc)
+			if !errors.Is(err, s.wantErr) {
+				t.Errorf("did not get expected error for test %q step %d.\nGot: %v\nWant: %v\n", test.name, i, err, s.wantErr)
+			}
+			if s.wantQuery != "" && c.link.authQuery != s.wantQuery {
+				t.Errorf("did not get expected query for test %q step %d.\nGot: %s\nWant: %s\n", test.name, i, c.link.authQuery, s.wantQuery)
+			}
+		}
+	}
+}
+
+// TestDialAuth checks that Dial reconnects with the authentication query
+// after each connect rejection, using a mock server that rejects the first two
+// connect commands with Adobe challenges and the third with an authentication
+// failure.
+func TestDialAuth(t *testing.T) {
+	setTestNonce(t)
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer ln.Close()
+
+	type result struct {
+		apps []string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var res result
+		for _, desc := range []string{descNeedAuth, descAdobeChal, descAuthFailed} {
+			var app string
+			app, res.err = rejectConnect(ln, desc)
+			if res.err != nil {
+				break
+			}
+			res.apps = append(res.apps, app)
+		}
+		done <- res
+	}()
+
+	url := fmt.Sprintf("rtmp://%s/%s/key", ln.Addr(), testApp)
+	_, err = Dial(url, func(int8, string, ...interface{}) {}, Credentials(testUser, testPassword), LinkTimeout(5))
+	if !errors.Is(err, errAuthFailed) {
+		t.Errorf("did not get expected error from Dial.\nGot: %v\nWant: %v\n", err, errAuthFailed)
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("unexpected mock server error: %v", res.err)
+	}
+	want := []string{
+		testApp,
+		testApp + "?authmod=adobe&user=ausocean",
+		testApp + "?authmod=adobe&user=ausocean&challenge=0a1b2c3d&response=aNhSQqq+m701jrP4dK5Hqw==&opaque=vgoAAA==",
+	}
+	if fmt.Sprint(res.apps) != fmt.Sprint(want) {
+		t.Errorf("did not get expected connect apps.\nGot: %q\nWant: %q\n", res.apps, want)
+	}
+}
+
+// rejectConnect accepts a connection from ln, performs the RTMP handshake,
+// reads the connect command and rejects it with an error with the given
+// description. The app of the connect command is returned.
+func rejectConnect(ln net.Listener, desc string) (string, error) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	err = serverHandshake(conn)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := readChunkedBody(conn)
+	if err != nil {
+		return "", fmt.Errorf("could not read connect command: %w", err)
+	}
+	var obj amf.Object
+	_, err = amf.Decode(&obj, body, false)
+	if err != nil {
+		return "", fmt.Errorf("could not decode connect command: %w", err)
+	}
+	info, err := obj.ObjectProperty("", 2)
+	if err != nil {
+		return "", fmt.Errorf("could not get connect info: %w", err)
+	}
+	app, err := info.StringProperty(avApp, -1)
+	if err != nil {
+		return "", fmt.Errorf("could not get app: %w", err)
+	}
+
+	var buf [1024]byte
+	enc, err := amf.EncodeString(buf[:], av_error)
+	if err != nil {
+		return "", err
+	}
+	enc, err = amf.EncodeNumber(enc, 1)
+	if err != nil {
+		return "", err
+	}
+	enc[0] = amf.TypeNull
+	enc = enc[1:]
+	enc, err = amf.Encode(&amf.Object{Properties: []amf.Property{
+		{Type: amf.TypeString, Name: avLevel, String: "error"},
+		{Type: amf.TypeString, Name: avCode, String: "NetConnection.Connect.Rejected"},
+		{Type: amf.TypeString, Name: avDescription, String: desc},
+	}}, enc)
+	if err != nil {
+		return "", err
+	}
+	_, err = conn.Write(chunk(buf[:len(buf)-len(enc)]))
+	if err != nil {
+		return "", fmt.Errorf("could not write error: %w", err)
+	}
+	return app, nil
+}
+
+// readChunkedBody reads the body of a single invoke message sent with a type
+// 0 chunk header on the control channel, using the default chunk size.
+func readChunkedBody(r io.Reader) ([]byte, error) {
+	hdr := make([]byte, fullHeaderSize)
+	_, err := io.ReadFull(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	if hdr[0] != chanControl {
+		return nil, fmt.Errorf("unexpected basic header: %#x", hdr[0])
+	}
+	size := int(amf.DecodeInt24(hdr[4:7]))
+	body := make([]byte, 0, size)
+	for len(body) < size {
+		if len(body) != 0 {
+			// Skip the type 3 header of the continuation chunk.
+			_, err = io.ReadFull(r, hdr[:1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		n := min(size-len(body), 128)
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, b...)
+	}
+	return body, nil
+}
+
+// chunk returns the invoke message body as chunks on the control channel,
+// using the default chunk size.
+func chunk(body []byte) []byte {
+	b := []byte{chanControl, 0, 0, 0, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body)), packetTypeInvoke, 0, 0, 0, 0}
+	for i := 0; i < len(body); i += 128 {
+		if i != 0 {
+			b = append(b, 0xc0|chanControl)
+		}
+		b = append(b, body[i:min(i+128, len(body))]...)
+	}
+	return b
+}
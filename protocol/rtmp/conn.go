@@ -25,13 +25,14 @@ LICENSE
 package rtmp
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
-	"strconv"
 	"time"
 
-	"github.com/ausocean/av/protocol/rtmp/amf"
+	"github.com/ausocean/av/container/flv"
 )
 
 // Log levels used by Log.
@@ -50,6 +51,10 @@ const (
 	defaultServerBandwidth = 2500000
 )
 
+// pollTimeout is how long Write waits for a message from the server before
+// writing, when checking for messages sent by the server while streaming.
+const pollTimeout = time.Millisecond
+
 // Conn represents an RTMP connection.
 type Conn struct {
 	inChunkSize          uint32
@@ -71,6 +76,14 @@ type Conn struct {
 	deferred             []byte
 	link                 link
 	log                  Log
+
+	// onBandwidth, if not nil, is called when the server changes the
+	// bandwidth of the connection. See BandwidthHandler.
+	onBandwidth func(serverBW, clientBW uint32)
+
+	// unread holds a byte read from the connection while checking for
+	// messages from the server, which is returned by the next read.
+	unread []byte
 }
 
 // link represents RTMP URL and connection information.
@@ -85,6 +98,19 @@ type link struct {
 	timeout  uint
 	port     uint16
 	conn     net.Conn
+
+	// tlsConfig is the TLS configuration used for RTMPS, or nil to use the
+	// default configuration.
+	tlsConfig *tls.Config
+
+	// user and password are the credentials used to answer Adobe and
+	// Limelight authentication challenges. authQuery is appended to the app
+	// and tcUrl of the connect command, and authTried records whether a
+	// challenge response has been sent. See auth.go.
+	user      string
+	password  string
+	authQuery string
+	authTried bool
 }
 
 // method represents an RTMP method.
@@ -128,10 +154,23 @@ func Dial(url string, log Log, options ...func(*Conn) error) (*Conn, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not parse url: %w", err)
 	}
-	c.link.url = rtmpProtocolStrings[c.link.protocol] + "://" + c.link.host + ":" + strconv.Itoa(int(c.link.port)) + "/" + c.link.app
+	c.link.url = tcURL(c.link.protocol, c.link.host, c.link.port, c.link.app)
 	c.link.protocol |= featureWrite
 
-	err = connect(&c)
+	// Servers requiring authentication reject the connect command and close
+	// the connection, so we reconnect with the authentication query until
+	// authentication succeeds or fails.
+	base := c
+	for {
+		err = connect(&c)
+		if !errors.Is(err, errAuthRetry) {
+			break
+		}
+		c.log(DebugLevel, pkg+"retrying connect with authentication")
+		query, tried := c.link.authQuery, c.link.authTried
+		c = base
+		c.link.authQuery, c.link.authTried = query, tried
+	}
 	if err != nil {
 		return nil, fmt.Errorf("could not connect: %w", err)
 	}
@@ -176,26 +215,115 @@ func (c *Conn) Write(data []byte) (int, error) {
 	if data[0] == packetTypeInfo || (data[0] == 'F' && data[1] == 'L' && data[2] == 'V') {
 		return 0, errUnimplemented
 	}
+	typ, size, timestamp, err := flv.ParseTagHeader(data)
+	if err != nil || len(data) < flvTagheaderSize+int(size) {
+		return 0, ErrInvalidFlvTag
+	}
+
+	err = c.readServerMessages()
+	if err != nil {
+		return 0, fmt.Errorf("could not read server messages: %w", err)
+	}
 
 	pkt := packet{
-		packetType: data[0],
-		bodySize:   amf.DecodeInt24(data[1:4]),
-		timestamp:  amf.DecodeInt24(data[4:7]) | uint32(data[7])<<24,
+		packetType: typ,
+		bodySize:   size,
+		timestamp:  timestamp,
 		channel:    chanSource,
 		streamID:   c.streamID,
 	}
 
 	pkt.resize(pkt.bodySize, headerSizeAuto)
 	copy(pkt.body, data[flvTagheaderSize:flvTagheaderSize+pkt.bodySize])
-	err := pkt.writeTo(c, false)
+	err = pkt.writeTo(c, false)
 	if err != nil {
 		return 0, fmt.Errorf("could not write packet to connection: %w", err)
 	}
 	return len(data), nil
 }
 
+// WriteAudio writes audio data as an FLV audio tag to the rtmp connection.
+// The timestamp is in milliseconds and must be on the same timeline as the
+// video tags written using Write, so that audio and video are interleaved by
+// timestamp. See flv.NewAudioTag for the meaning of the remaining parameters.
+func (c *Conn) WriteAudio(timestamp uint32, format, rate uint8, size16, stereo bool, data []byte) (int, error) {
+	tag := flv.NewAudioTag(timestamp, format, rate, size16, stereo, data)
+	_, err := c.Write(tag.Bytes())
+	if err != nil {
+		return 0, fmt.Errorf("could not write audio tag: %w", err)
+	}
+	return len(data), nil
+}
+
+// Bandwidth returns the current server bandwidth (window acknowledgement size)
+// and client (peer) bandwidth of the connection in bytes, as last set by the
+// server or by options.
+func (c *Conn) Bandwidth() (serverBW, clientBW uint32) {
+	return c.serverBW, c.clientBW
+}
+
+// readServerMessages reads and handles the messages the server has sent since
+// the connection was established, such as changes to the bandwidth, returning
+// when no more have arrived within pollTimeout. Only control messages are
+// handled; other messages are logged and ignored.
+func (c *Conn) readServerMessages() error {
+	var buf [256]byte
+	for {
+		ok, err := c.pending()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		pkt := packet{buf: buf[:]}
+		err = pkt.readFrom(c)
+		if err != nil {
+			return fmt.Errorf("could not read from packet: %w", err)
+		}
+		if !pkt.isReady() || pkt.bodySize == 0 {
+			continue
+		}
+
+		switch pkt.packetType {
+		case packetTypeChunkSize, packetTypeServerBW, packetTypeClientBW:
+			err = handlePacket(c, &pkt)
+			if err != nil {
+				return fmt.Errorf("could not handle packet: %w", err)
+			}
+		default:
+			c.log(DebugLevel, pkg+"ignoring server message while streaming", "type", pkt.packetType)
+		}
+	}
+}
+
 // I/O functions
 
+// pending returns true if the server has sent data that has not yet been read,
+// waiting up to pollTimeout for data to arrive. The first byte of any data is
+// kept in c.unread to be returned by the next read.
+func (c *Conn) pending() (bool, error) {
+	if len(c.unread) != 0 {
+		return true, nil
+	}
+	err := c.link.conn.SetReadDeadline(time.Now().Add(pollTimeout))
+	if err != nil {
+		return false, fmt.Errorf("could not set read deadline: %w", err)
+	}
+	var b [1]byte
+	n, err := c.link.conn.Read(b[:])
+	if n == 1 {
+		c.unread = append(c.unread[:0], b[0])
+		return true, nil
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return false, nil
+	}
+	return false, fmt.Errorf("could not read conn: %w", err)
+}
+
 // read from an RTMP connection. Sends a bytes received message if the
 // number of bytes received (nBytesIn) is greater than the number sent
 // (nBytesInSent) by 10% of the bandwidth.
@@ -204,7 +332,10 @@ func (c *Conn) read(buf []byte) (int, error) {
 	if err != nil {
 		return 0, fmt.Errorf("could not set read deadline: %w", err)
 	}
-	n, err := io.ReadFull(c.link.conn, buf)
+	n := copy(buf, c.unread)
+	c.unread = c.unread[n:]
+	m, err := io.ReadFull(c.link.conn, buf[n:])
+	n += m
 	if err != nil {
 		c.log(DebugLevel, pkg+"read failed", "error", err.Error())
 		return 0, fmt.Errorf("could not read conn: %w", err)
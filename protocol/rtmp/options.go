@@ -18,13 +18,17 @@ LICENSE
 
 package rtmp
 
-import "errors"
+import (
+	"crypto/tls"
+	"errors"
+)
 
 // Option parameter errors.
 var (
 	ErrClientBandwidth = errors.New("bad client bandwidth")
 	ErrServerBandwidth = errors.New("bad server bandwidth")
 	ErrLinkTimeout     = errors.New("bad link timeout")
+	ErrCredentials     = errors.New("bad credentials")
 )
 
 // ClientBandwidth changes the Conn's clientBW parameter to the given value.
@@ -62,3 +66,39 @@ func LinkTimeout(t uint) func(*Conn) error {
 		return nil
 	}
 }
+
+// TLSConfig sets the TLS configuration used to connect to RTMPS servers. If
+// cfg does not give a server name, the host of the URL is used for certificate
+// verification. Setting InsecureSkipVerify in cfg skips verification, which
+// should only be used for testing.
+func TLSConfig(cfg *tls.Config) func(*Conn) error {
+	return func(c *Conn) error {
+		c.link.tlsConfig = cfg
+		return nil
+	}
+}
+
+// Credentials sets the user and password used to authenticate with servers
+// that require Adobe or Limelight challenge-response authentication.
+func Credentials(user, password string) func(*Conn) error {
+	return func(c *Conn) error {
+		if user == "" {
+			return ErrCredentials
+		}
+		c.link.user = user
+		c.link.password = password
+		return nil
+	}
+}
+
+// BandwidthHandler sets a function to be called with the new server and
+// client bandwidths, in bytes, whenever the server changes either of them.
+// Applications may use this to adapt the bitrate of the stream to the
+// bandwidth available. The function is called by Dial while connecting, and
+// by Write when the server changes the bandwidth while streaming.
+func BandwidthHandler(fn func(serverBW, clientBW uint32)) func(*Conn) error {
+	return func(c *Conn) error {
+		c.onBandwidth = fn
+		return nil
+	}
+}
@@ -24,6 +24,7 @@ package rtmp
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"path"
 	"strconv"
@@ -36,6 +37,49 @@ var (
 	errInvalidElements = errors.New("invalid url elements")
 )
 
+// defaultInstance is the name of the default application instance used by
+// Wowza and Limelight servers.
+const defaultInstance = "_definst_"
+
+// Link holds the components of an RTMP URL.
+type Link struct {
+	Scheme   string // The protocol scheme, e.g. rtmp or rtmps.
+	Host     string
+	Port     uint16 // The port, or the default port of the scheme if not given.
+	App      string // The application, including any instance.
+	Playpath string // The stream name or key, including any query.
+	TcURL    string // The target URL sent in the connect command.
+}
+
+// ParseURL parses the RTMP URL rawurl of the form
+//
+//	scheme://host[:port]/app[/instance]/playpath[?query]
+//
+// The scheme must be one of rtmp, rtmpt, rtmpe, rtmpte, rtmps, rtmpts or
+// rtmfp. The instance is only recognised if it is the default instance,
+// _definst_, as otherwise the remainder of the path is ambiguous; everything
+// else after the app is taken to be the playpath. Any query is kept as part
+// of the playpath, as is common for stream keys that carry tokens.
+func ParseURL(rawurl string) (*Link, error) {
+	protocol, host, port, app, playpath, err := parseURL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return &Link{
+		Scheme:   rtmpProtocolStrings[protocol],
+		Host:     host,
+		Port:     port,
+		App:      app,
+		Playpath: playpath,
+		TcURL:    tcURL(protocol, host, port, app),
+	}, nil
+}
+
+// tcURL returns the target URL for the connect command.
+func tcURL(protocol int32, host string, port uint16, app string) string {
+	return rtmpProtocolStrings[protocol] + "://" + net.JoinHostPort(host, strconv.Itoa(int(port))) + "/" + app
+}
+
 // parseURL parses an RTMP URL (ok, technically it is lexing).
 func parseURL(addr string) (protocol int32, host string, port uint16, app, playpath string, err error) {
 	u, err := url.Parse(addr)
@@ -62,7 +106,7 @@ func parseURL(addr string) (protocol int32, host string, port uint16, app, playp
 		return protocol, host, port, app, playpath, fmt.Errorf("unknown scheme: %s", u.Scheme)
 	}
 
-	host = u.Host
+	host = u.Hostname()
 	if p := u.Port(); p != "" {
 		pi, err := strconv.Atoi(p)
 		if err != nil {
@@ -80,6 +124,10 @@ func parseURL(addr string) (protocol int32, host string, port uint16, app, playp
 	}
 	app = elems[0]
 	playpath = path.Join(elems[1:]...)
+	if len(elems) == 3 && elems[1] == defaultInstance && strings.Trim(elems[2], "/") != "" {
+		app = path.Join(elems[:2]...)
+		playpath = path.Join(elems[2:]...)
+	}
 
 	switch ext := path.Ext(playpath); ext {
 	case ".f4v", ".mp4":
@@ -99,10 +147,15 @@ func parseURL(addr string) (protocol int32, host string, port uint16, app, playp
 		playpath += "?" + u.RawQuery
 	}
 
+	// Of the protocols using SSL, only RTMPS is implemented.
+	if protocol&featureSSL != 0 && protocol != protoRTMPS {
+		return protocol, host, port, app, playpath, errors.New("ssl not implemented for " + u.Scheme)
+	}
+
 	switch {
 	case port != 0:
 	case (protocol & featureSSL) != 0:
-		return protocol, host, port, app, playpath, errors.New("ssl not implemented")
+		port = 443
 	case (protocol & featureHTTP) != 0:
 		port = 80
 	default:
@@ -153,6 +153,43 @@ var parseURLTests = []struct {
 		wantApp:      "appname",
 		wantPlaypath: "path/to/file?param1=value1&param2=value2",
 	},
+	{
+		url:          "rtmps://addr/appname/key",
+		wantProtocol: protoRTMPS,
+		wantHost:     "addr",
+		wantPort:     443,
+		wantApp:      "appname",
+		wantPlaypath: "key",
+	},
+	{
+		url:          "rtmps://addr:1936/appname/key",
+		wantProtocol: protoRTMPS,
+		wantHost:     "addr",
+		wantPort:     1936,
+		wantApp:      "appname",
+		wantPlaypath: "key",
+	},
+	{
+		url:          "rtmp://addr:1937/appname/key",
+		wantHost:     "addr",
+		wantPort:     1937,
+		wantApp:      "appname",
+		wantPlaypath: "key",
+	},
+	{
+		url:          "rtmp://addr/appname/_definst_/key",
+		wantHost:     "addr",
+		wantPort:     1935,
+		wantApp:      "appname/_definst_",
+		wantPlaypath: "key",
+	},
+	{
+		url:          "rtmp://addr/appname/_definst_/",
+		wantHost:     "addr",
+		wantPort:     1935,
+		wantApp:      "appname",
+		wantPlaypath: "_definst_",
+	},
 }
 
 func TestParseURL(t *testing.T) {
@@ -191,3 +228,96 @@ func TestParseURL(t *testing.T) {
 		}()
 	}
 }
+
+func TestParseURLLink(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    *Link
+		wantErr bool
+	}{
+		{
+			url: "rtmp://a.rtmp.youtube.com/live2/xxxx-xxxx-xxxx-xxxx",
+			want: &Link{
+				Scheme:   "rtmp",
+				Host:     "a.rtmp.youtube.com",
+				Port:     1935,
+				App:      "live2",
+				Playpath: "xxxx-xxxx-xxxx-xxxx",
+				TcURL:    "rtmp://a.rtmp.youtube.com:1935/live2",
+			},
+		},
+		{
+			url: "rtmps://a.rtmps.youtube.com/live2/key",
+			want: &Link{
+				Scheme:   "rtmps",
+				Host:     "a.rtmps.youtube.com",
+				Port:     443,
+				App:      "live2",
+				Playpath: "key",
+				TcURL:    "rtmps://a.rtmps.youtube.com:443/live2",
+			},
+		},
+		{
+			url: "rtmpt://10.0.0.2/app/key",
+			want: &Link{
+				Scheme:   "rtmpt",
+				Host:     "10.0.0.2",
+				Port:     80,
+				App:      "app",
+				Playpath: "key",
+				TcURL:    "rtmpt://10.0.0.2:80/app",
+			},
+		},
+		{
+			url: "rtmpe://host:1940/app/key",
+			want: &Link{
+				Scheme:   "rtmpe",
+				Host:     "host",
+				Port:     1940,
+				App:      "app",
+				Playpath: "key",
+				TcURL:    "rtmpe://host:1940/app",
+			},
+		},
+		{
+			url: "rtmp://host/live/_definst_/stream?token=abc&expires=123",
+			want: &Link{
+				Scheme:   "rtmp",
+				Host:     "host",
+				Port:     1935,
+				App:      "live/_definst_",
+				Playpath: "stream?token=abc&expires=123",
+				TcURL:    "rtmp://host:1935/live/_definst_",
+			},
+		},
+		{
+			url: "rtmp://[::1]:1936/app/key",
+			want: &Link{
+				Scheme:   "rtmp",
+				Host:     "::1",
+				Port:     1936,
+				App:      "app",
+				Playpath: "key",
+				TcURL:    "rtmp://[::1]:1936/app",
+			},
+		},
+		{url: "http://host/app/key", wantErr: true},
+		{url: "rtmpts://host/app/key", wantErr: true},
+		{url: "rtmp://host/app", wantErr: true},
+		{url: "rtmp://host:port/app/key", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseURL(test.url)
+		if (err != nil) != test.wantErr {
+			t.Errorf("unexpected error for %q: %v", test.url, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if *got != *test.want {
+			t.Errorf("did not get expected link for %q.\nGot: %+v\nWant: %+v\n", test.url, *got, *test.want)
+		}
+	}
+}
@@ -24,6 +24,7 @@ package rtmp
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -54,7 +55,7 @@ const (
 const (
 	featureHTTP   = 0x01 // not implemented
 	featureEncode = 0x02 // not implemented
-	featureSSL    = 0x04 // not implemented
+	featureSSL    = 0x04 // RTMPS only
 	featureMFP    = 0x08 // not implemented
 	featureWrite  = 0x10 // publish, not play
 	featureHTTP2  = 0x20 // server-side RTMPT - not implemented
@@ -75,6 +76,7 @@ const (
 // NB: Underscores are deliberately preserved in const names where they exist in the corresponding tokens.
 const (
 	av_checkbw                       = "_checkbw"
+	av_error                         = "_error"
 	av_onbwcheck                     = "_onbwcheck"
 	av_onbwdone                      = "_onbwdone"
 	av_result                        = "_result"
@@ -86,6 +88,7 @@ const (
 	avConnect                        = "connect"
 	avCreatestream                   = "createStream"
 	avDeletestream                   = "deleteStream"
+	avDescription                    = "description"
 	avFCPublish                      = "FCPublish"
 	avFCUnpublish                    = "FCUnpublish"
 	avFlashver                       = "flashVer"
@@ -171,6 +174,15 @@ func connect(c *Conn) error {
 		}
 	}()
 
+	if c.link.protocol&featureSSL != 0 {
+		err = tlsHandshake(c)
+		if err != nil {
+			c.log(WarnLevel, pkg+"TLS handshake failed", "error", err.Error())
+			return fmt.Errorf("could not complete TLS handshake: %w", err)
+		}
+		c.log(DebugLevel, pkg+"TLS handshaked")
+	}
+
 	err = handshake(c)
 	if err != nil {
 		c.log(WarnLevel, pkg+"handshake failed", "error", err.Error())
@@ -229,10 +241,15 @@ func handlePacket(c *Conn, pkt *packet) error {
 		c.log(DebugLevel, pkg+"received packetTypeBytesReadReport")
 
 	case packetTypeServerBW:
+		prev := c.serverBW
 		c.serverBW = amf.DecodeInt32(pkt.body[:4])
 		c.log(DebugLevel, pkg+"set serverBW", "size", int(c.serverBW))
+		if c.serverBW != prev && c.onBandwidth != nil {
+			c.onBandwidth(c.serverBW, c.clientBW)
+		}
 
 	case packetTypeClientBW:
+		prev := c.clientBW
 		c.clientBW = amf.DecodeInt32(pkt.body[:4])
 		c.log(DebugLevel, pkg+"set clientBW", "size", int(c.clientBW))
 		if pkt.bodySize > 4 {
@@ -241,6 +258,9 @@ func handlePacket(c *Conn, pkt *packet) error {
 		} else {
 			c.clientBW2 = 0xff
 		}
+		if c.clientBW != prev && c.onBandwidth != nil {
+			c.onBandwidth(c.serverBW, c.clientBW)
+		}
 
 	case packetTypeInvoke:
 		err := handleInvoke(c, pkt.body[:pkt.bodySize])
@@ -281,9 +301,9 @@ func sendConnectPacket(c *Conn) error {
 
 	// required link info
 	info := amf.Object{Properties: []amf.Property{
-		amf.Property{Type: amf.TypeString, Name: avApp, String: c.link.app},
+		amf.Property{Type: amf.TypeString, Name: avApp, String: c.link.app + c.link.authQuery},
 		amf.Property{Type: amf.TypeString, Name: avType, String: avNonprivate},
-		amf.Property{Type: amf.TypeString, Name: avTcUrl, String: c.link.url}},
+		amf.Property{Type: amf.TypeString, Name: avTcUrl, String: c.link.url + c.link.authQuery}},
 	}
 	enc, err = amf.Encode(&info, enc)
 	if err != nil {
@@ -669,6 +689,28 @@ func handleInvoke(c *Conn, body []byte) error {
 			c.log(FatalLevel, pkg+"unexpected method invoked"+methodInvoked)
 		}
 
+	case av_error:
+		var methodInvoked string
+		for i, m := range c.methodCalls {
+			if float64(m.num) == txn {
+				methodInvoked = m.name
+				c.methodCalls = eraseMethod(c.methodCalls, i)
+				break
+			}
+		}
+		obj2, err := obj.ObjectProperty("", 3)
+		if err != nil {
+			return fmt.Errorf("could not get object property value for obj2: %w", err)
+		}
+		code, _ := obj2.StringProperty(avCode, -1)
+		desc, _ := obj2.StringProperty(avDescription, -1)
+		c.log(DebugLevel, pkg+"received error for "+methodInvoked, "code", code, "description", desc)
+
+		if methodInvoked == avConnect {
+			return handleConnectError(c, desc)
+		}
+		return fmt.Errorf("error response to %s: %s: %s", methodInvoked, code, desc)
+
 	case avOnBWDone:
 		err := sendCheckBW(c)
 		if err != nil {
@@ -708,6 +750,31 @@ func handleInvoke(c *Conn, body []byte) error {
 	return nil
 }
 
+// tlsHandshake wraps the TCP connection of c in a TLS client connection and
+// performs the TLS handshake. Unless given by the TLS config of c, the server
+// name used for certificate verification is the host of the RTMP URL.
+func tlsHandshake(c *Conn) error {
+	cfg := &tls.Config{}
+	if c.link.tlsConfig != nil {
+		cfg = c.link.tlsConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = c.link.host
+	}
+
+	conn := tls.Client(c.link.conn, cfg)
+	err := conn.SetDeadline(time.Now().Add(time.Second * time.Duration(c.link.timeout)))
+	if err != nil {
+		return fmt.Errorf("could not set deadline: %w", err)
+	}
+	err = conn.Handshake()
+	if err != nil {
+		return err
+	}
+	c.link.conn = conn
+	return nil
+}
+
 func handshake(c *Conn) error {
 	var clientbuf [signatureSize + 1]byte
 	clientsig := clientbuf[1:]
@@ -24,8 +24,18 @@ package rtmp
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"os"
 	"runtime"
 	"testing"
@@ -46,9 +56,10 @@ const (
 
 // testVerbosity controls the amount of output.
 // NB: This is not the log level, which is DebugLevel.
-//   0: suppress logging completely
-//   1: log messages only
-//   2: log messages with errors, if any
+//
+//	0: suppress logging completely
+//	1: log messages only
+//	2: log messages with errors, if any
 var testVerbosity = 1
 
 // testKey is the YouTube RTMP key required for YouTube streaming (RTMP_TEST_KEY env var).
@@ -257,3 +268,249 @@ func TestFromFile(t *testing.T) {
 		t.Errorf("Conn.Close failed with error: %v", err)
 	}
 }
+
+// TestDialTLS checks that an rtmps URL results in the RTMP handshake and
+// connect command being sent over TLS, with verification of the server
+// certificate.
+func TestDialTLS(t *testing.T) {
+	cert, pool := testCert(t)
+
+	tests := []struct {
+		name    string
+		cfg     *tls.Config
+		wantTLS bool // Whether the RTMP handshake should be completed over TLS.
+	}{
+		{name: "trusted", cfg: &tls.Config{RootCAs: pool}, wantTLS: true},
+		{name: "skip verify", cfg: &tls.Config{InsecureSkipVerify: true}, wantTLS: true},
+		{name: "untrusted", cfg: nil, wantTLS: false},
+	}
+
+	for _, test := range tests {
+		ln, err := tls.Listen("tcp4", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+		if err != nil {
+			t.Fatalf("could not listen: %v", err)
+		}
+		done := make(chan error, 1)
+		go func() { done <- serveRTMPHandshake(ln) }()
+
+		url := fmt.Sprintf("rtmps://%s/%s/key", ln.Addr(), testApp)
+		_, err = Dial(url, func(int8, string, ...interface{}) {}, TLSConfig(test.cfg), LinkTimeout(5))
+		ln.Close()
+
+		// The mock server closes the connection after the connect command, so
+		// Dial should always fail.
+		if err == nil {
+			t.Errorf("expected error from Dial for test %q", test.name)
+		}
+		var verr *tls.CertificateVerificationError
+		if test.wantTLS == errors.As(err, &verr) {
+			t.Errorf("unexpected certificate verification result for test %q: %v", test.name, err)
+		}
+
+		srvErr := <-done
+		if test.wantTLS && srvErr != nil {
+			t.Errorf("server did not complete RTMP handshake for test %q: %v", test.name, srvErr)
+		}
+		if !test.wantTLS && srvErr == nil {
+			t.Errorf("server unexpectedly completed RTMP handshake for test %q", test.name)
+		}
+	}
+}
+
+// serveRTMPHandshake accepts a connection from ln, performs the server side of
+// the RTMP handshake, and checks that an RTMP connect command follows.
+func serveRTMPHandshake(ln net.Listener) error {
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	err = serverHandshake(conn)
+	if err != nil {
+		return err
+	}
+
+	// The client should then send the connect command.
+	buf := make([]byte, 128)
+	n, err := io.ReadAtLeast(conn, buf, len(avConnect))
+	if err != nil {
+		return fmt.Errorf("could not read connect command: %w", err)
+	}
+	if !bytes.Contains(buf[:n], []byte(avConnect)) {
+		return errors.New("did not get connect command")
+	}
+	return nil
+}
+
+// serverHandshake performs the server side of the RTMP handshake on conn.
+func serverHandshake(conn net.Conn) error {
+	// C0 and C1.
+	c01 := make([]byte, 1+signatureSize)
+	_, err := io.ReadFull(conn, c01)
+	if err != nil {
+		return fmt.Errorf("could not read C0 and C1: %w", err)
+	}
+
+	// S0, S1 and S2, where S2 echoes C1.
+	s1 := make([]byte, signatureSize)
+	copy(s1, "server signature")
+	_, err = conn.Write(append(append([]byte{chanControl}, s1...), c01[1:]...))
+	if err != nil {
+		return fmt.Errorf("could not write S0, S1 and S2: %w", err)
+	}
+
+	// C2 should echo S1.
+	c2 := make([]byte, signatureSize)
+	_, err = io.ReadFull(conn, c2)
+	if err != nil {
+		return fmt.Errorf("could not read C2: %w", err)
+	}
+	if !bytes.Equal(c2, s1) {
+		return errors.New("C2 does not match S1")
+	}
+	return nil
+}
+
+// testCert returns a self-signed certificate for 127.0.0.1 and a pool
+// containing it.
+func testCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"AusOcean"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(crand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// TestBandwidthHandler checks that a bandwidth drop signalled by the server
+// results in a call to the bandwidth handler with the new bandwidths, and that
+// an unchanged bandwidth does not.
+func TestBandwidthHandler(t *testing.T) {
+	type bw struct{ server, client uint32 }
+	var got []bw
+	c := &Conn{serverBW: defaultServerBandwidth, clientBW: defaultClientBandwidth, log: func(int8, string, ...interface{}) {}}
+	err := BandwidthHandler(func(s, c uint32) { got = append(got, bw{s, c}) })(c)
+	if err != nil {
+		t.Fatalf("unexpected error from option: %v", err)
+	}
+
+	pkts := []packet{
+		{packetType: packetTypeServerBW, bodySize: 4, body: []byte{0x00, 0x0f, 0x42, 0x40}},       // 1000000.
+		{packetType: packetTypeServerBW, bodySize: 4, body: []byte{0x00, 0x0f, 0x42, 0x40}},       // Unchanged.
+		{packetType: packetTypeClientBW, bodySize: 5, body: []byte{0x00, 0x07, 0xa1, 0x20, 0x02}}, // 500000, dynamic.
+	}
+	for i := range pkts {
+		err := handlePacket(c, &pkts[i])
+		if err != nil {
+			t.Fatalf("unexpected error handling packet %d: %v", i, err)
+		}
+	}
+
+	want := []bw{{1000000, defaultClientBandwidth}, {1000000, 500000}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("did not get expected bandwidth calls.\nGot: %v\nWant: %v\n", got, want)
+	}
+	if s, cl := c.Bandwidth(); s != 1000000 || cl != 500000 {
+		t.Errorf("did not get expected bandwidth.\nGot: %d, %d\nWant: 1000000, 500000\n", s, cl)
+	}
+}
+
+// TestBandwidthHandlerStreaming checks that a bandwidth drop signalled by the
+// server while streaming is read by Write and results in a call to the
+// bandwidth handler, and that the tags are still written to the server.
+func TestBandwidthHandlerStreaming(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer ln.Close()
+
+	cc, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer cc.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("could not accept: %v", err)
+	}
+	defer sc.Close()
+
+	// Discard what the client writes, counting the bytes.
+	received := make(chan int64, 1)
+	go func() {
+		n, _ := io.Copy(io.Discard, sc)
+		received <- n
+	}()
+
+	var got []uint32
+	c := &Conn{
+		inChunkSize:  128,
+		outChunkSize: 128,
+		streamID:     1,
+		serverBW:     defaultServerBandwidth,
+		clientBW:     defaultClientBandwidth,
+		link:         link{conn: cc, timeout: 5},
+		log:          func(int8, string, ...interface{}) {},
+	}
+	err = BandwidthHandler(func(s, _ uint32) { got = append(got, s) })(c)
+	if err != nil {
+		t.Fatalf("unexpected error from option: %v", err)
+	}
+
+	tag := flv.NewAudioTag(0, flv.AACAudioFormat, flv.SoundRate44kHz, true, true, make([]byte, 64)).Bytes()
+	_, err = c.Write(tag)
+	if err != nil {
+		t.Fatalf("unexpected error from first write: %v", err)
+	}
+
+	// Window acknowledgement size of 500000 on the protocol control channel.
+	_, err = sc.Write([]byte{0x02, 0, 0, 0, 0, 0, 4, packetTypeServerBW, 0, 0, 0, 0, 0x00, 0x07, 0xa1, 0x20})
+	if err != nil {
+		t.Fatalf("could not write server message: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		_, err = c.Write(tag)
+		if err != nil {
+			t.Fatalf("unexpected error from write %d after bandwidth change: %v", i, err)
+		}
+	}
+
+	want := []uint32{500000}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("did not get expected bandwidth calls.\nGot: %v\nWant: %v\n", got, want)
+	}
+	if s, _ := c.Bandwidth(); s != 500000 {
+		t.Errorf("did not get expected server bandwidth.\nGot: %d\nWant: 500000\n", s)
+	}
+
+	cc.Close()
+	if n := <-received; n == 0 {
+		t.Error("server did not receive any tags")
+	}
+}
@@ -23,6 +23,7 @@ package rtp
 import (
 	"io"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -40,24 +41,39 @@ type Encoder struct {
 	dst           io.Writer
 	ssrc          uint32
 	seqNo         uint16
+	tsOffset      uint32
 	clock         time.Duration
 	frameInterval time.Duration
 	lastTime      time.Time
 	fps           int
 	buffer        []byte
 	pktSpace      [defPktSize]byte
+	now           func() time.Time // Used to get the time packets are sent; replaceable for testing.
+
+	mu         sync.Mutex // Guards the below fields, which are used for RTCP sender reports.
+	timestamp  uint32     // Timestamp of the last packet sent.
+	sent       time.Time  // Wall-clock time the last packet was sent.
+	pktCount   uint32     // Number of packets sent.
+	octetCount uint32     // Number of payload octets sent.
 }
 
 // NewEncoder returns a new Encoder type given an io.Writer - the destination
-// after encoding and the desired fps
-func NewEncoder(dst io.Writer, fps int) *Encoder {
-	return &Encoder{
+// after encoding and the desired fps. Options may be given to set the SSRC,
+// initial sequence number and initial timestamp, which otherwise default to a
+// random SSRC and zero.
+func NewEncoder(dst io.Writer, fps int, options ...func(*Encoder)) *Encoder {
+	e := &Encoder{
 		dst:           dst,
 		ssrc:          rand.Uint32(),
 		frameInterval: time.Duration(float64(time.Second) / float64(fps)),
 		fps:           fps,
 		buffer:        make([]byte, 0),
+		now:           time.Now,
+	}
+	for _, option := range options {
+		option(e)
 	}
+	return e
 }
 
 // Write provides an interface between a prior encoder and this rtp encoder,
@@ -90,13 +106,14 @@ func min(a, b int) int {
 // Encode takes a nalu unit and encodes it into an rtp packet and
 // writes to the io.Writer given in NewEncoder
 func (e *Encoder) Encode(payload []byte) error {
+	ts := e.nxtTimestamp()
 	pkt := Packet{
-		Version:    rtpVer,           // version
-		CSRCCount:  0,                // CSRC count
-		PacketType: defaultPktType,   // 33 for mpegts
-		Sync:       e.nxtSeqNo(),     // sequence number
-		Timestamp:  e.nxtTimestamp(), // timestamp
-		SSRC:       e.ssrc,           // source identifier
+		Version:    rtpVer,         // version
+		CSRCCount:  0,              // CSRC count
+		PacketType: defaultPktType, // 33 for mpegts
+		Sync:       e.nxtSeqNo(),   // sequence number
+		Timestamp:  ts,             // timestamp
+		SSRC:       e.ssrc,         // source identifier
 		Payload:    payload,
 		Padding:    nil,
 	}
@@ -104,10 +121,28 @@ func (e *Encoder) Encode(payload []byte) error {
 	if err != nil {
 		return err
 	}
+	sent := e.now()
+	e.mu.Lock()
+	e.timestamp = ts
+	e.sent = sent
+	e.pktCount++
+	e.octetCount += uint32(len(payload))
+	e.mu.Unlock()
 	e.tick()
 	return nil
 }
 
+// SenderInfo returns the SSRC, the timestamp of the last packet sent, the
+// number of packets and payload octets sent, and the wall-clock time at which
+// the last packet was sent, as required for RTCP sender reports. The time is
+// zero if no packets have been sent. It is safe to call concurrently with Write
+// and Encode.
+func (e *Encoder) SenderInfo() (ssrc, timestamp, packets, octets uint32, sent time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ssrc, e.timestamp, e.pktCount, e.octetCount, e.sent
+}
+
 // tick advances the clock one frame interval.
 func (e *Encoder) tick() {
 	e.clock += e.frameInterval
@@ -115,7 +150,7 @@ func (e *Encoder) tick() {
 
 // nxtTimestamp gets the next timestamp
 func (e *Encoder) nxtTimestamp() uint32 {
-	return uint32(e.clock.Seconds() * timestampFreq)
+	return e.tsOffset + uint32(e.clock.Seconds()*timestampFreq)
 }
 
 // nxtSeqNo gets the next rtp packet sequence number
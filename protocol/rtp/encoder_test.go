@@ -0,0 +1,124 @@
+/*
+NAME
+  encoder_test.go
+
+DESCRIPTION
+  encoder_test.go provides testing for functionality in encoder.go and
+  options.go.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package rtp
+
+import (
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+// packetRecorder keeps a copy of each packet written to it.
+type packetRecorder struct {
+	pkts [][]byte
+}
+
+func (r *packetRecorder) Write(p []byte) (int, error) {
+	r.pkts = append(r.pkts, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// TestEncoderOptions checks that the first packets from an Encoder use the
+// configured SSRC, sequence number and timestamp.
+func TestEncoderOptions(t *testing.T) {
+	const (
+		fps  = 25
+		ssrc = 0x12345678
+		seq  = 0xfffe
+		ts   = 0xffffffff - 1000
+	)
+
+	var r packetRecorder
+	e := NewEncoder(&r, fps, WithSSRC(ssrc), InitialSequence(seq), InitialTimestamp(ts))
+	for i := 0; i < 3; i++ {
+		err := e.Encode([]byte{byte(i)})
+		if err != nil {
+			t.Fatalf("did not expect error encoding packet %d: %v", i, err)
+		}
+	}
+
+	const tsStep = timestampFreq / fps
+	for i, p := range r.pkts {
+		gotSSRC := binary.BigEndian.Uint32(p[8:12])
+		if gotSSRC != ssrc {
+			t.Errorf("did not get expected SSRC for packet %d.\nGot: %x\nWant: %x\n", i, gotSSRC, ssrc)
+		}
+		gotSeq := binary.BigEndian.Uint16(p[2:4])
+		wantSeq := uint16(seq + i)
+		if gotSeq != wantSeq {
+			t.Errorf("did not get expected sequence number for packet %d.\nGot: %v\nWant: %v\n", i, gotSeq, wantSeq)
+		}
+		gotTS := binary.BigEndian.Uint32(p[4:8])
+		wantTS := uint32(ts) + uint32(i*tsStep)
+		if gotTS != wantTS {
+			t.Errorf("did not get expected timestamp for packet %d.\nGot: %v\nWant: %v\n", i, gotTS, wantTS)
+		}
+	}
+}
+
+// TestEncoderDefaults checks that without options the sequence number and
+// timestamp start from 0.
+func TestEncoderDefaults(t *testing.T) {
+	var r packetRecorder
+	e := NewEncoder(&r, 25)
+	err := e.Encode([]byte{0x00})
+	if err != nil {
+		t.Fatalf("did not expect error encoding: %v", err)
+	}
+	p := r.pkts[0]
+	if seq := binary.BigEndian.Uint16(p[2:4]); seq != 0 {
+		t.Errorf("did not get expected sequence number.\nGot: %v\nWant: 0\n", seq)
+	}
+	if ts := binary.BigEndian.Uint32(p[4:8]); ts != 0 {
+		t.Errorf("did not get expected timestamp.\nGot: %v\nWant: 0\n", ts)
+	}
+	if ssrc, _, _, _, _ := e.SenderInfo(); binary.BigEndian.Uint32(p[8:12]) != ssrc {
+		t.Errorf("packet SSRC does not match encoder SSRC")
+	}
+}
+
+// TestEncoderSenderInfo checks that SenderInfo reports the timestamp, send
+// time and counts of the packets encoded.
+func TestEncoderSenderInfo(t *testing.T) {
+	e := NewEncoder(io.Discard, 25, InitialTimestamp(1000))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	if _, _, _, _, sent := e.SenderInfo(); !sent.IsZero() {
+		t.Errorf("did not get expected send time before encoding.\nGot: %v\nWant: zero time\n", sent)
+	}
+	for i := 0; i < 3; i++ {
+		now = now.Add(40 * time.Millisecond)
+		err := e.Encode(make([]byte, 10))
+		if err != nil {
+			t.Fatalf("did not expect error encoding: %v", err)
+		}
+	}
+
+	_, ts, packets, octets, sent := e.SenderInfo()
+	if ts != 1000+2*3600 {
+		t.Errorf("did not get expected timestamp.\nGot: %v\nWant: %v\n", ts, 1000+2*3600)
+	}
+	if !sent.Equal(now) {
+		t.Errorf("did not get expected send time.\nGot: %v\nWant: %v\n", sent, now)
+	}
+	if packets != 3 || octets != 30 {
+		t.Errorf("did not get expected counts.\nGot: %d packets, %d octets\nWant: 3 packets, 30 octets\n", packets, octets)
+	}
+}
@@ -0,0 +1,43 @@
+/*
+DESCRIPTION
+  options.go provides option functions that can be provided to the RTP
+  encoder's constructor NewEncoder for encoder configuration. These options
+  allow the SSRC, initial sequence number and initial timestamp to be set,
+  e.g. for deterministic testing or for resuming a session.
+
+LICENSE
+  Copyright (C) 2024 the Australian Ocean Lab (AusOcean). All Rights Reserved.
+
+  The Software and all intellectual property rights associated
+  therewith, including but not limited to copyrights, trademarks,
+  patents, and trade secrets, are and will remain the exclusive
+  property of the Australian Ocean Lab (AusOcean).
+*/
+
+package rtp
+
+// WithSSRC is an option that can be passed to NewEncoder to set the
+// synchronisation source identifier of the encoded packets, rather than
+// using a random value.
+func WithSSRC(ssrc uint32) func(*Encoder) {
+	return func(e *Encoder) {
+		e.ssrc = ssrc
+	}
+}
+
+// InitialSequence is an option that can be passed to NewEncoder to set the
+// sequence number of the first encoded packet. The default is 0.
+func InitialSequence(seq uint16) func(*Encoder) {
+	return func(e *Encoder) {
+		e.seqNo = seq
+	}
+}
+
+// InitialTimestamp is an option that can be passed to NewEncoder to set the
+// timestamp of the first encoded packet. Subsequent timestamps advance from
+// this value, wrapping as required. The default is 0.
+func InitialTimestamp(ts uint32) func(*Encoder) {
+	return func(e *Encoder) {
+		e.tsOffset = ts
+	}
+}